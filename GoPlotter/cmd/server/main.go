@@ -7,10 +7,10 @@ import (
 	"net/http"
 	"sfaf-plotter/config"
 	"sfaf-plotter/handlers"
+	"sfaf-plotter/models"
 	"sfaf-plotter/repositories"
 	"sfaf-plotter/services"
 	"sfaf-plotter/storage"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -44,14 +44,95 @@ func main() {
 	// CREATE MARKER SERVICE BEFORE USING IT
 	markerService := services.NewMarkerService(markerRepo, iracNotesRepo, serialService, coordService)
 
+	// Parse frequency and power of markers saved before those columns existed
+	if updated, err := markerService.BackfillParsedValues(); err != nil {
+		log.Fatal("Failed to migrate marker columns:", err)
+	} else if updated > 0 {
+		log.Printf("✅ Parsed frequency/power for %d existing markers", updated)
+	}
+	if indexed, err := markerService.IndexMarkers(); err != nil {
+		log.Fatal("Failed to index markers:", err)
+	} else {
+		log.Printf("✅ Indexed %d marker positions", indexed)
+	}
+
 	// Now other services can reference markerService
-	sfafService := services.NewSFAFService(storage, coordService)
 	geometryService := services.NewGeometryService(storage, markerService, serialService, coordService)
+	if indexed, err := geometryService.IndexGeometries(); err != nil {
+		log.Fatal("Failed to index geometries:", err)
+	} else {
+		log.Printf("✅ Indexed %d geometries", indexed)
+	}
+	// Replace the planar area estimates of geometries saved before areas were ellipsoidal
+	if updated, err := geometryService.RecomputeMeasurements(); err != nil {
+		log.Fatal("Failed to recompute geometry areas:", err)
+	} else if updated > 0 {
+		log.Printf("✅ Recomputed area and perimeter of %d geometries", updated)
+	}
+	// IRAC coordination areas are kept as geometries so markers can be tested against them
+	areasPath := config.GetEnv("COORDINATION_AREAS", "./web/static/references/coordination-areas.json")
+	coordinationAreas, err := services.LoadCoordinationAreas(areasPath)
+	if err != nil {
+		log.Fatal("Failed to load coordination areas:", err)
+	}
+	coordinationService := services.NewCoordinationService(storage, geometryService, coordinationAreas)
+	if stored, err := coordinationService.SyncAreas(); err != nil {
+		log.Fatal("Failed to store coordination areas:", err)
+	} else {
+		log.Printf("✅ Stored %d coordination area geometries for %d IRAC notes", stored, len(coordinationAreas.Areas))
+	}
+
+	// Field definitions come from the MCEB Pub 7 catalogue data file
+	cataloguePath := config.GetEnv("SFAF_FIELD_CATALOGUE", "./web/static/references/sfaf-field-catalogue.json")
+	fieldCatalogue, err := services.LoadSFAFFieldCatalogue(cataloguePath)
+	if err != nil {
+		log.Fatal("Failed to load SFAF field catalogue:", err)
+	}
+	log.Printf("✅ Loaded SFAF field catalogue %s (%d fields)", fieldCatalogue.Version, len(fieldCatalogue.Fields))
+
+	// IRAC notes come from the database; their geographical scope from the reference file
+	iracNotes, err := markerService.GetIRACNotes()
+	if err != nil {
+		log.Fatal("Failed to load IRAC notes:", err)
+	}
+	iracReferencePath := config.GetEnv("IRAC_NOTES_REFERENCE", "./web/static/references/irac-notes-reference.json")
+	iracCatalogue, err := services.NewIRACNoteCatalogue(iracNotes, iracReferencePath)
+	if err != nil {
+		log.Fatal("Failed to load IRAC note catalogue:", err)
+	}
+	if iracCatalogue.Len() == 0 {
+		log.Printf("⚠️ irac_notes table is empty; IRAC note checks are disabled")
+	} else {
+		log.Printf("✅ Loaded IRAC note catalogue (%d notes)", iracCatalogue.Len())
+	}
+
+	// Co-channel and adjacent-channel conflicts: guard band in kHz, distance in km
+	conflictSettings, err := services.ParseConflictSettings(
+		config.GetEnv("CONFLICT_GUARD_BAND_KHZ", "25"), config.GetEnv("CONFLICT_DISTANCE_KM", "50"), "km",
+		models.ConflictSettings{})
+	if err != nil {
+		log.Fatal("Failed to read conflict settings:", err)
+	}
+	conflictService := services.NewConflictService(storage, markerService, conflictSettings)
+
+	// Frequencies never nominated, from the restricted frequency data file
+	restrictedPath := config.GetEnv("RESTRICTED_FREQUENCIES", "./web/static/references/restricted-frequencies.json")
+	restricted, err := services.LoadRestrictedFrequencies(restrictedPath)
+	if err != nil {
+		log.Fatal("Failed to load restricted frequencies:", err)
+	}
+	log.Printf("✅ Loaded restricted frequencies %s (%d entries)", restricted.Version, len(restricted.Frequencies))
+	nominationService := services.NewNominationService(storage, markerService, coordService, conflictService, restricted)
+
+	sfafService := services.NewSFAFService(storage, coordService, markerService, geometryService, conflictService, coordinationService, fieldCatalogue, iracCatalogue)
 
 	// Initialize handlers with properly created services
-	markerHandler := handlers.NewMarkerHandler(markerService)
-	sfafHandler := handlers.NewSFAFHandler(sfafService, markerService) // ADD SFAF HANDLER
+	markerHandler := handlers.NewMarkerHandler(markerService, geometryService, coordinationService, coordService, conflictService)
+	sfafHandler := handlers.NewSFAFHandler(sfafService, markerService, conflictService) // ADD SFAF HANDLER
 	geometryHandler := handlers.NewGeometryHandler(geometryService)
+	geodesicHandler := handlers.NewGeodesicHandler(markerService, coordService)
+	conflictHandler := handlers.NewConflictHandler(conflictService)
+	nominationHandler := handlers.NewNominationHandler(nominationService)
 
 	// Setup Gin router
 	r := gin.Default()
@@ -96,19 +177,57 @@ func main() {
 	{
 
 		api.GET("/convert-coords", func(c *gin.Context) {
-			lat := c.Query("lat")
-			lng := c.Query("lng")
+			// position takes MGRS, UTM or a lat/lng pair; otherwise lat and
+			// lng may be in any form the coordinate parser reads
+			var latFloat, lngFloat float64
+			if position := c.Query("position"); position != "" {
+				coord, err := coordService.ParsePosition(position)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				latFloat, lngFloat = coord.Lat, coord.Lng
+			} else {
+				var err error
+				if latFloat, err = coordService.ParseLatitude(c.Query("lat")); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				if lngFloat, err = coordService.ParseLongitude(c.Query("lng")); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
 
-			latFloat, _ := strconv.ParseFloat(lat, 64)
-			lngFloat, _ := strconv.ParseFloat(lng, 64)
+			// precision=1 gives tenths of a second; whole seconds by default.
+			// mgrs_precision is 1 (10 km) to 5 (1 m) digits, 5 by default.
+			precision, err := coordService.ParseDMSPrecision(c.Query("precision"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			mgrsPrecision, err := coordService.ParseMGRSPrecision(c.Query("mgrs_precision"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 
 			// Use coordinate service for conversion
-			coordFormats := coordService.GetAllFormats(latFloat, lngFloat)
+			coordFormats := coordService.GetAllFormats(latFloat, lngFloat, models.CoordinateFormatOptions{
+				SecondsPrecision: precision,
+				MGRSPrecision:    mgrsPrecision,
+			})
 
 			c.JSON(http.StatusOK, gin.H{
-				"decimal": fmt.Sprintf("%.4f, %.4f", latFloat, lngFloat),
-				"dms":     coordFormats.DMS,
-				"compact": coordFormats.Compact,
+				"lat":            latFloat,
+				"lng":            lngFloat,
+				"decimal":        fmt.Sprintf("%.4f, %.4f", latFloat, lngFloat),
+				"dms":            coordFormats.DMS,
+				"compact":        coordFormats.Compact,
+				"precision":      coordFormats.Precision,
+				"utm":            coordFormats.UTM,
+				"mgrs":           coordFormats.MGRS,
+				"mgrs_precision": coordFormats.MGRSPrecision,
 			})
 		})
 
@@ -127,7 +246,13 @@ func main() {
 
 		// ✅ ADD SFAF ROUTES
 		api.GET("/sfaf/object-data/:markerId", sfafHandler.GetObjectData)
+		api.GET("/sfaf/field-definitions", sfafHandler.GetFieldDefinitions)
 		api.POST("/sfaf", sfafHandler.CreateSFAF)
+		api.POST("/sfaf/validate", sfafHandler.ValidateSFAF)
+		api.POST("/sfaf/import", sfafHandler.ImportSFAF)
+		api.POST("/sfaf/import/preview", sfafHandler.PreviewSFAFImport)
+		api.GET("/sfaf/export", sfafHandler.ExportSFAFs)
+		api.GET("/sfaf/export/:id", sfafHandler.ExportSFAF)
 		api.PUT("/sfaf/:id", sfafHandler.UpdateSFAF)
 		api.DELETE("/sfaf/:id", sfafHandler.DeleteSFAF)
 
@@ -136,7 +261,19 @@ func main() {
 		api.POST("/geometry/polygon", geometryHandler.CreatePolygon)
 		api.POST("/geometry/rectangle", geometryHandler.CreateRectangle)
 		api.GET("/geometry", geometryHandler.GetAllGeometries)
+		api.PUT("/geometry/:id", geometryHandler.UpdateGeometry)
 		api.DELETE("/geometry/:id", geometryHandler.DeleteGeometry)
+
+		// Separation between markers or positions on the WGS 84 ellipsoid
+		api.GET("/geodesic/distance", geodesicHandler.GetDistance)
+		api.GET("/geodesic/destination", geodesicHandler.GetDestination)
+
+		// Frequency conflicts of an assignment with nearby ones
+		api.GET("/conflicts/marker/:id", conflictHandler.CheckMarker)
+		api.GET("/conflicts/sfaf/:id", conflictHandler.CheckSFAF)
+
+		// Clear frequencies for a new assignment
+		api.POST("/nominate", nominationHandler.Nominate)
 	}
 
 	log.Println("🚀 SFAF Plotter server starting on :8080")
@@ -0,0 +1,149 @@
+// emission/emission.go
+package emission
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Designator is a decoded ITU emission designator (SFAF field114), e.g.
+// "2K70J3E": 2.7 kHz necessary bandwidth, single sideband suppressed
+// carrier, single analogue channel, telephony.
+type Designator struct {
+	Raw             string  `json:"raw"`
+	BandwidthHz     float64 `json:"bandwidth_hz"`
+	Modulation      string  `json:"modulation"`             // first symbol, e.g. "J"
+	ModulationName  string  `json:"modulation_name"`        // e.g. "Single sideband, suppressed carrier"
+	Signal          string  `json:"signal"`                 // second symbol, e.g. "3"
+	SignalName      string  `json:"signal_name"`            // e.g. "Single channel, analogue"
+	Information     string  `json:"information"`            // third symbol, e.g. "E"
+	InformationName string  `json:"information_name"`       // e.g. "Telephony"
+	Details         string  `json:"details,omitempty"`      // optional fourth symbol
+	Multiplexing    string  `json:"multiplexing,omitempty"` // optional fifth symbol
+}
+
+var bandwidthUnits = map[byte]float64{'H': 1, 'K': 1e3, 'M': 1e6, 'G': 1e9}
+
+// ITU-R RR Appendix 1, first symbol
+var modulationTypes = map[byte]string{
+	'N': "Unmodulated carrier",
+	'A': "Double sideband",
+	'H': "Single sideband, full carrier",
+	'R': "Single sideband, reduced or variable carrier",
+	'J': "Single sideband, suppressed carrier",
+	'B': "Independent sidebands",
+	'C': "Vestigial sideband",
+	'F': "Frequency modulation",
+	'G': "Phase modulation",
+	'D': "Amplitude and angle modulation",
+	'P': "Unmodulated pulses",
+	'K': "Pulses modulated in amplitude",
+	'L': "Pulses modulated in width/duration",
+	'M': "Pulses modulated in position/phase",
+	'Q': "Pulses with angle modulation during the pulse period",
+	'V': "Combination of pulse modulations",
+	'W': "Combination of amplitude, angle and pulse modulation",
+	'X': "Other",
+}
+
+// Second symbol
+var signalNatures = map[byte]string{
+	'0': "No modulating signal",
+	'1': "Single channel, digital, no modulating sub-carrier",
+	'2': "Single channel, digital, with modulating sub-carrier",
+	'3': "Single channel, analogue",
+	'7': "Two or more channels, digital",
+	'8': "Two or more channels, analogue",
+	'9': "Composite of analogue and digital channels",
+	'X': "Other",
+}
+
+// Third symbol
+var informationTypes = map[byte]string{
+	'N': "No information transmitted",
+	'A': "Telegraphy, aural reception",
+	'B': "Telegraphy, automatic reception",
+	'C': "Facsimile",
+	'D': "Data transmission, telemetry, telecommand",
+	'E': "Telephony",
+	'F': "Television (video)",
+	'W': "Combination of the above",
+	'X': "Other",
+}
+
+// Optional fourth and fifth symbols
+const (
+	detailSymbols       = "ABCDEFGHJKLMNWX"
+	multiplexingSymbols = "NCFTWX"
+)
+
+// Parse decodes a 7 character designator ("2K70J3E") or the 9 character
+// form with details and multiplexing symbols ("16K0F3EJN"). The bandwidth
+// is three digits with the unit letter (H, K, M or G) marking the decimal
+// point: "2K70" is 2.70 kHz, "400H" is 400 Hz and "H002" is 0.002 Hz.
+func Parse(value string) (Designator, error) {
+	raw := strings.TrimSpace(value)
+	text := strings.ToUpper(raw)
+	if len(text) != 7 && len(text) != 9 {
+		return Designator{}, fmt.Errorf("invalid emission designator %q (expected 7 or 9 characters, e.g. 2K70J3E)", raw)
+	}
+
+	bandwidth, err := parseBandwidth(text[:4])
+	if err != nil {
+		return Designator{}, fmt.Errorf("invalid emission designator %q: %w", raw, err)
+	}
+
+	d := Designator{Raw: raw, BandwidthHz: bandwidth}
+
+	var ok bool
+	d.Modulation = text[4:5]
+	if d.ModulationName, ok = modulationTypes[text[4]]; !ok {
+		return Designator{}, fmt.Errorf("invalid emission designator %q: unknown modulation type %q", raw, d.Modulation)
+	}
+	d.Signal = text[5:6]
+	if d.SignalName, ok = signalNatures[text[5]]; !ok {
+		return Designator{}, fmt.Errorf("invalid emission designator %q: unknown modulating signal %q", raw, d.Signal)
+	}
+	d.Information = text[6:7]
+	if d.InformationName, ok = informationTypes[text[6]]; !ok {
+		return Designator{}, fmt.Errorf("invalid emission designator %q: unknown information type %q", raw, d.Information)
+	}
+
+	if len(text) == 9 {
+		d.Details, d.Multiplexing = text[7:8], text[8:9]
+		if !strings.Contains(detailSymbols, d.Details) {
+			return Designator{}, fmt.Errorf("invalid emission designator %q: unknown signal detail %q", raw, d.Details)
+		}
+		if !strings.Contains(multiplexingSymbols, d.Multiplexing) {
+			return Designator{}, fmt.Errorf("invalid emission designator %q: unknown multiplexing %q", raw, d.Multiplexing)
+		}
+	}
+
+	return d, nil
+}
+
+func parseBandwidth(text string) (float64, error) {
+	unitAt := -1
+	for i := 0; i < len(text); i++ {
+		if _, isUnit := bandwidthUnits[text[i]]; isUnit {
+			if unitAt >= 0 {
+				return 0, fmt.Errorf("bandwidth %q has more than one unit letter", text)
+			}
+			unitAt = i
+		} else if text[i] < '0' || text[i] > '9' {
+			return 0, fmt.Errorf("bandwidth %q must be three digits and one of H, K, M, G", text)
+		}
+	}
+	if unitAt < 0 {
+		return 0, fmt.Errorf("bandwidth %q has no unit letter (H, K, M or G)", text)
+	}
+	if unitAt > 0 && text[0] == '0' {
+		return 0, fmt.Errorf("bandwidth %q must not start with zero", text)
+	}
+
+	number, _ := strconv.ParseFloat(text[:unitAt]+"."+text[unitAt+1:], 64)
+	// Round to the millihertz so "2K70" is exactly 2700 Hz
+	return math.Round(number*bandwidthUnits[text[unitAt]]*1e3) / 1e3, nil
+}
@@ -0,0 +1,65 @@
+// emission/emission_test.go
+package emission
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value                           string
+		bandwidthHz                     float64
+		modulation, signal, information string
+		details, multiplexing           string
+	}{
+		{"2K70J3E", 2700, "J", "3", "E", "", ""},
+		{"16K0F3E", 16000, "F", "3", "E", "", ""},
+		{"400HA1A", 400, "A", "1", "A", "", ""},
+		{"H002N0N", 0.002, "N", "0", "N", "", ""},
+		{"6M00C3F", 6e6, "C", "3", "F", "", ""},
+		{"1G20G7W", 1.2e9, "G", "7", "W", "", ""},
+		{"16K0F3EJN", 16000, "F", "3", "E", "J", "N"},
+		{" 25k0g1d ", 25000, "G", "1", "D", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if d.BandwidthHz != tt.bandwidthHz || d.Modulation != tt.modulation || d.Signal != tt.signal ||
+				d.Information != tt.information || d.Details != tt.details || d.Multiplexing != tt.multiplexing {
+				t.Errorf("Parse(%q) = %+v", tt.value, d)
+			}
+			if d.ModulationName == "" || d.SignalName == "" || d.InformationName == "" {
+				t.Errorf("Parse(%q) left a symbol name empty: %+v", tt.value, d)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{"empty", ""},
+		{"too short", "2K7J3E"},
+		{"eight characters", "16K0F3EJ"},
+		{"no unit letter", "2700J3E"},
+		{"two unit letters", "2KK0J3E"},
+		{"leading zero", "02K7J3E"},
+		{"letters in the bandwidth", "2KXXJ3E"},
+		{"unknown modulation", "2K70Z3E"},
+		{"unknown signal", "2K70J4E"},
+		{"unknown information", "2K70J3Z"},
+		{"unknown details", "16K0F3EIN"},
+		{"unknown multiplexing", "16K0F3EJA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d, err := Parse(tt.value); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", tt.value, d)
+			}
+		})
+	}
+}
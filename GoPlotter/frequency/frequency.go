@@ -0,0 +1,152 @@
+// frequency/frequency.go
+package frequency
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unit multipliers for the MCEB Pub 7 frequency prefixes
+const (
+	KHz float64 = 1e3
+	MHz float64 = 1e6
+	GHz float64 = 1e9
+	THz float64 = 1e12
+)
+
+var (
+	// "K4551.5", "M123.45(123.4)", "4551.5"
+	singlePattern = regexp.MustCompile(`^([KMGT])?(\d+(?:\.\d+)?)(?:\(([KMGT])?(\d+(?:\.\d+)?)\))?$`)
+	// one end of a band: "M30", "88"
+	edgePattern = regexp.MustCompile(`^([KMGT])?(\d+(?:\.\d+)?)$`)
+)
+
+// Frequency is a parsed SFAF frequency (field110 style). A single frequency
+// has MinHz == MaxHz == Hz; a band ("M30-M88") has Hz at its lower edge.
+type Frequency struct {
+	Raw         string  `json:"raw"`
+	Hz          float64 `json:"hz"`
+	MinHz       float64 `json:"min_hz"`
+	MaxHz       float64 `json:"max_hz"`
+	ReferenceHz float64 `json:"reference_hz,omitempty"` // the "(4550)" part, if any
+}
+
+// Parse reads an SFAF frequency: an optional K/M/G/T unit prefix and a
+// number, optionally followed by a parenthesized reference frequency in the
+// same unit ("K4551.5(4550)"), or a band written as two such values joined
+// by "-" ("M30-M88", "M30-88"). Values without a prefix are taken as MHz.
+func Parse(value string) (Frequency, error) {
+	raw := strings.TrimSpace(value)
+	text := strings.ToUpper(strings.ReplaceAll(raw, " ", ""))
+	if text == "" {
+		return Frequency{}, fmt.Errorf("frequency is empty")
+	}
+
+	if lower, upper, isBand := strings.Cut(text, "-"); isBand {
+		return parseBand(raw, lower, upper)
+	}
+
+	match := singlePattern.FindStringSubmatch(text)
+	if match == nil {
+		return Frequency{}, fmt.Errorf("invalid frequency %q (expected e.g. K4551.5, M123.45 or M30-M88)", raw)
+	}
+
+	hz, err := toHz(match[1], match[2], "")
+	if err != nil {
+		return Frequency{}, fmt.Errorf("invalid frequency %q: %w", raw, err)
+	}
+
+	f := Frequency{Raw: raw, Hz: hz, MinHz: hz, MaxHz: hz}
+	if match[4] != "" {
+		if f.ReferenceHz, err = toHz(match[3], match[4], match[1]); err != nil {
+			return Frequency{}, fmt.Errorf("invalid reference frequency in %q: %w", raw, err)
+		}
+	}
+	return f, nil
+}
+
+func parseBand(raw, lower, upper string) (Frequency, error) {
+	low := edgePattern.FindStringSubmatch(lower)
+	high := edgePattern.FindStringSubmatch(upper)
+	if low == nil || high == nil {
+		return Frequency{}, fmt.Errorf("invalid frequency band %q (expected e.g. M30-M88)", raw)
+	}
+
+	minHz, err := toHz(low[1], low[2], "")
+	if err != nil {
+		return Frequency{}, fmt.Errorf("invalid frequency band %q: %w", raw, err)
+	}
+	// The upper edge may omit its unit ("M30-88")
+	maxHz, err := toHz(high[1], high[2], low[1])
+	if err != nil {
+		return Frequency{}, fmt.Errorf("invalid frequency band %q: %w", raw, err)
+	}
+	if maxHz < minHz {
+		return Frequency{}, fmt.Errorf("invalid frequency band %q: upper edge is below lower edge", raw)
+	}
+
+	return Frequency{Raw: raw, Hz: minHz, MinHz: minHz, MaxHz: maxHz}, nil
+}
+
+func toHz(unit, number, defaultUnit string) (float64, error) {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("frequency must be greater than zero")
+	}
+	if unit == "" {
+		unit = defaultUnit
+	}
+
+	multiplier := MHz
+	switch unit {
+	case "K":
+		multiplier = KHz
+	case "G":
+		multiplier = GHz
+	case "T":
+		multiplier = THz
+	}
+
+	// Round to the millihertz so "K4551.5" is exactly 4551500 Hz
+	return math.Round(value*multiplier*1e3) / 1e3, nil
+}
+
+// IsBand reports whether f covers a range rather than a single frequency
+func (f Frequency) IsBand() bool {
+	return f.MaxHz > f.MinHz
+}
+
+// MHz returns the assigned frequency (or lower band edge) in megahertz
+func (f Frequency) MHz() float64 {
+	return f.Hz / MHz
+}
+
+// Overlaps reports whether f has any part within [minHz, maxHz]
+func (f Frequency) Overlaps(minHz, maxHz float64) bool {
+	return f.MaxHz >= minHz && f.MinHz <= maxHz
+}
+
+// Format writes hz the way field110 expects: kHz below 30 MHz, MHz up to
+// 100 GHz, GHz up to 3 THz and THz above, with trailing zeros dropped.
+func Format(hz float64) string {
+	switch {
+	case hz < 30*MHz:
+		return "K" + formatNumber(hz/KHz)
+	case hz <= 100*GHz:
+		return "M" + formatNumber(hz/MHz)
+	case hz <= 3*THz:
+		return "G" + formatNumber(hz/GHz)
+	default:
+		return "T" + formatNumber(hz/THz)
+	}
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e6)/1e6, 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+// frequency/frequency_test.go
+package frequency
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value                   string
+		hz, minHz, maxHz, refHz float64
+	}{
+		{"K4551.5", 4551500, 4551500, 4551500, 0},
+		{"M123.45", 123450000, 123450000, 123450000, 0},
+		{"G8.4", 8.4e9, 8.4e9, 8.4e9, 0},
+		{"T1.2", 1.2e12, 1.2e12, 1.2e12, 0},
+		{"225.5", 225500000, 225500000, 225500000, 0},
+		{" m30 ", 30e6, 30e6, 30e6, 0},
+		{"K4551.5(4550)", 4551500, 4551500, 4551500, 4550000},
+		{"M123.45(M123.4)", 123450000, 123450000, 123450000, 123400000},
+		{"M30-M88", 30e6, 30e6, 88e6, 0},
+		{"M30-88", 30e6, 30e6, 88e6, 0},
+		{"K2000-M30", 2e6, 2e6, 30e6, 0},
+		{"K0.0015", 1.5, 1.5, 1.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			f, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if f.Hz != tt.hz || f.MinHz != tt.minHz || f.MaxHz != tt.maxHz || f.ReferenceHz != tt.refHz {
+				t.Errorf("Parse(%q) = %+v, want Hz %v, MinHz %v, MaxHz %v, ReferenceHz %v",
+					tt.value, f, tt.hz, tt.minHz, tt.maxHz, tt.refHz)
+			}
+			if f.IsBand() != (tt.maxHz > tt.minHz) {
+				t.Errorf("Parse(%q).IsBand() = %v", tt.value, f.IsBand())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{"", "   ", "X123", "M", "M-5", "M0", "K0.0", "M1.2.3", "M88-M30", "M30-", "-M30", "M30-X88", "K4551.5(X)", "M30M"} {
+		t.Run(value, func(t *testing.T) {
+			if f, err := Parse(value); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", value, f)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		hz   float64
+		want string
+	}{
+		{4551500, "K4551.5"},
+		{29999000, "K29999"},
+		{30e6, "M30"},
+		{123450000, "M123.45"},
+		{8.4e9, "M8400"},
+		{100e9, "M100000"},
+		{120e9, "G120"},
+		{3.5e12, "T3.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Format(tt.hz); got != tt.want {
+				t.Errorf("Format(%v) = %q, want %q", tt.hz, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatParse checks that a formatted frequency parses back to the same
+// value
+func TestFormatParse(t *testing.T) {
+	for _, hz := range []float64{1.5, 9000, 4551500, 29999999, 30e6, 225.5e6, 1.2e9, 99.999e9, 150e9, 3.5e12} {
+		text := Format(hz)
+		f, err := Parse(text)
+		if err != nil {
+			t.Errorf("Parse(Format(%v) = %q): %v", hz, text, err)
+			continue
+		}
+		if f.Hz != hz {
+			t.Errorf("Parse(Format(%v) = %q).Hz = %v", hz, text, f.Hz)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	band, _ := Parse("M30-M88")
+	tests := []struct {
+		name       string
+		min, max   float64
+		overlapped bool
+	}{
+		{"inside", 40e6, 50e6, true},
+		{"covering", 10e6, 100e6, true},
+		{"touching the lower edge", 20e6, 30e6, true},
+		{"touching the upper edge", 88e6, 90e6, true},
+		{"below", 10e6, 29.9e6, false},
+		{"above", 88.1e6, 90e6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := band.Overlaps(tt.min, tt.max); got != tt.overlapped {
+				t.Errorf("Overlaps(%v, %v) = %v, want %v", tt.min, tt.max, got, tt.overlapped)
+			}
+		})
+	}
+}
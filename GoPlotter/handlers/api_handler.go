@@ -1,6 +1,13 @@
 // api_handler.go
 package handlers
 
+import (
+	"fmt"
+	"sfaf-plotter/models"
+	"strconv"
+	"strings"
+)
+
 // This file can contain shared handler utilities
 // For now, keeping it minimal
 
@@ -24,3 +31,39 @@ func NewErrorResponse(message string) APIResponse {
 		Error:   message,
 	}
 }
+
+// parseBoundingBox reads a "west,south,east,north" query value, the order
+// Leaflet's LatLngBounds.toBBoxString() produces.
+func parseBoundingBox(value string) (*models.BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be west,south,east,north")
+	}
+
+	var numbers [4]float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox value %q", part)
+		}
+		numbers[i] = number
+	}
+
+	box := &models.BoundingBox{West: numbers[0], South: numbers[1], East: numbers[2], North: numbers[3]}
+	if box.South > box.North || box.South < -90 || box.North > 90 {
+		return nil, fmt.Errorf("invalid bbox latitudes")
+	}
+	return box, nil
+}
+
+// parseOptionalFloat reads an optional numeric query value
+func parseOptionalFloat(name, value string) (*float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return &number, nil
+}
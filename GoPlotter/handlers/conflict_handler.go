@@ -0,0 +1,69 @@
+// handlers/conflict_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sfaf-plotter/models"
+	"sfaf-plotter/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConflictHandler struct {
+	conflictService *services.ConflictService
+}
+
+func NewConflictHandler(conflictService *services.ConflictService) *ConflictHandler {
+	return &ConflictHandler{conflictService: conflictService}
+}
+
+// CheckMarker lists the assignments that may interfere with a marker's.
+// Optional guard_khz and distance (in unit: km, nm, mi or m; km by
+// default) replace the configured guard band and distance.
+func (ch *ConflictHandler) CheckMarker(c *gin.Context) {
+	settings, err := ch.settings(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	report, err := ch.conflictService.CheckMarker(c.Param("id"), settings)
+	if err != nil {
+		c.JSON(conflictErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "report": report})
+}
+
+// CheckSFAF does the same for an SFAF record, using its field110 frequency
+// and field114 emission at its marker's position
+func (ch *ConflictHandler) CheckSFAF(c *gin.Context) {
+	settings, err := ch.settings(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	report, err := ch.conflictService.CheckSFAF(c.Param("id"), settings)
+	if err != nil {
+		c.JSON(conflictErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "report": report})
+}
+
+func (ch *ConflictHandler) settings(c *gin.Context) (models.ConflictSettings, error) {
+	return services.ParseConflictSettings(c.Query("guard_khz"), c.Query("distance"), c.Query("unit"), ch.conflictService.Settings())
+}
+
+// conflictErrorStatus maps conflict service errors to HTTP status codes
+func conflictErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAssignmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNoFrequency), errors.Is(err, services.ErrInvalidConflictSettings):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
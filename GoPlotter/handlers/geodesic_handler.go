@@ -0,0 +1,124 @@
+// handlers/geodesic_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GeodesicHandler struct {
+	markerService *services.MarkerService
+	coordService  *services.CoordinateService
+}
+
+func NewGeodesicHandler(markerService *services.MarkerService, coordService *services.CoordinateService) *GeodesicHandler {
+	return &GeodesicHandler{markerService: markerService, coordService: coordService}
+}
+
+// GetDistance returns the ellipsoidal distance and bearings between from
+// and to. Each is a marker ID or a position: MGRS, UTM, DMS or decimal
+// degrees.
+func (gh *GeodesicHandler) GetDistance(c *gin.Context) {
+	from, fromMarker, status, err := gh.resolveEndpoint("from", c.Query("from"))
+	if err != nil {
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	to, toMarker, status, err := gh.resolveEndpoint("to", c.Query("to"))
+	if err != nil {
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := gh.coordService.Geodesic(from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	response := gin.H{"success": true, "geodesic": result}
+	addEndpointMarker(response, "from_marker", fromMarker)
+	addEndpointMarker(response, "to_marker", toMarker)
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDestination returns the point distance (in unit: km, nm, mi or m;
+// km by default) from a marker or position along an initial bearing in
+// degrees true
+func (gh *GeodesicHandler) GetDestination(c *gin.Context) {
+	from, fromMarker, status, err := gh.resolveEndpoint("from", c.Query("from"))
+	if err != nil {
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	bearing, err := strconv.ParseFloat(strings.TrimSpace(c.Query("bearing")), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("invalid bearing %q", c.Query("bearing"))})
+		return
+	}
+	distance, err := strconv.ParseFloat(strings.TrimSpace(c.Query("distance")), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("invalid distance %q", c.Query("distance"))})
+		return
+	}
+	meters, err := services.DistanceToMeters(distance, c.Query("unit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := gh.coordService.Destination(from, bearing, meters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success":     true,
+		"destination": result,
+		"coordinates": gh.coordService.GetAllFormats(result.To.Lat, result.To.Lng, models.CoordinateFormatOptions{}),
+	}
+	addEndpointMarker(response, "from_marker", fromMarker)
+	c.JSON(http.StatusOK, response)
+}
+
+// resolveEndpoint reads a marker ID or a position. On failure it also
+// returns the status to answer with.
+func (gh *GeodesicHandler) resolveEndpoint(name, value string) (models.Coordinate, *models.Marker, int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return models.Coordinate{}, nil, http.StatusBadRequest, fmt.Errorf("%s is required: a marker ID or a position", name)
+	}
+
+	if _, err := uuid.Parse(value); err == nil {
+		response, err := gh.markerService.GetMarker(value)
+		if err != nil {
+			return models.Coordinate{}, nil, http.StatusNotFound, fmt.Errorf("%s: marker not found", name)
+		}
+		marker := response.Marker
+		return models.Coordinate{Lat: marker.Latitude, Lng: marker.Longitude}, marker, http.StatusOK, nil
+	}
+
+	coordinate, err := gh.coordService.ParsePosition(value)
+	if err != nil {
+		return models.Coordinate{}, nil, http.StatusBadRequest, fmt.Errorf("%s: %w", name, err)
+	}
+	return coordinate, nil, http.StatusOK, nil
+}
+
+// addEndpointMarker names the marker an endpoint came from, if any
+func addEndpointMarker(response gin.H, key string, marker *models.Marker) {
+	if marker == nil {
+		return
+	}
+	response[key] = gin.H{"id": marker.ID, "serial": marker.Serial}
+}
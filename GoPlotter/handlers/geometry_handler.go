@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"sfaf-plotter/models"
 	"sfaf-plotter/services"
@@ -25,14 +27,15 @@ func (gh *GeometryHandler) CreateCircle(c *gin.Context) {
 
 	geometry, err := gh.geometryService.CreateCircle(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(geometryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"success":  true,
-		"message":  "Circle created successfully",
-		"geometry": geometry,
+		"success":       true,
+		"message":       "Circle created successfully",
+		"geometry":      geometry,
+		"center_marker": gh.centerMarker(geometry),
 	})
 }
 
@@ -45,14 +48,15 @@ func (gh *GeometryHandler) CreatePolygon(c *gin.Context) {
 
 	geometry, err := gh.geometryService.CreatePolygon(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(geometryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"success":  true,
-		"message":  "Polygon created successfully",
-		"geometry": geometry,
+		"success":       true,
+		"message":       "Polygon created successfully",
+		"geometry":      geometry,
+		"center_marker": gh.centerMarker(geometry),
 	})
 }
 
@@ -65,33 +69,103 @@ func (gh *GeometryHandler) CreateRectangle(c *gin.Context) {
 
 	geometry, err := gh.geometryService.CreateRectangle(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(geometryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"success":  true,
-		"message":  "Rectangle created successfully",
-		"geometry": geometry,
+		"success":       true,
+		"message":       "Rectangle created successfully",
+		"geometry":      geometry,
+		"center_marker": gh.centerMarker(geometry),
 	})
 }
 
+// GetAllGeometries lists every stored geometry, including the field306
+// authorization areas generated from SFAF records. bbox=west,south,east,north
+// keeps those reaching into the box.
 func (gh *GeometryHandler) GetAllGeometries(c *gin.Context) {
-	// Implementation depends on storage.GetAllGeometries method
+	var geometries []*models.Geometry
+	var err error
+	if bbox := c.Query("bbox"); bbox != "" {
+		box, parseErr := parseBoundingBox(bbox)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": parseErr.Error()})
+			return
+		}
+		geometries, err = gh.geometryService.FindGeometries(*box)
+	} else {
+		geometries, err = gh.geometryService.GetAllGeometries()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success":    true,
-		"geometries": []interface{}{}, // Placeholder
+		"geometries": geometries,
 	})
 }
 
-func (gh *GeometryHandler) DeleteGeometry(c *gin.Context) {
-	id := c.Param("id")
+// UpdateGeometry edits the points, bounds or radius, color and notes of a
+// drawn geometry
+func (gh *GeometryHandler) UpdateGeometry(c *gin.Context) {
+	var req models.UpdateGeometryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Implementation depends on storage.DeleteGeometry method
-	_ = id // Placeholder
+	geometry, err := gh.geometryService.UpdateGeometry(c.Param("id"), req)
+	if err != nil {
+		c.JSON(geometryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Geometry deleted successfully",
+		"success":       true,
+		"message":       "Geometry updated successfully",
+		"geometry":      geometry,
+		"center_marker": gh.centerMarker(geometry),
 	})
 }
+
+// DeleteGeometry removes a drawn geometry together with its center marker
+func (gh *GeometryHandler) DeleteGeometry(c *gin.Context) {
+	geometry, err := gh.geometryService.DeleteGeometry(c.Param("id"))
+	if err != nil {
+		c.JSON(geometryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"message":          "Geometry deleted successfully",
+		"center_marker_id": geometry.MarkerID,
+	})
+}
+
+// centerMarker looks up a geometry's center marker for the response. The
+// geometry is already saved, so a failed lookup is only logged.
+func (gh *GeometryHandler) centerMarker(geometry *models.Geometry) *models.Marker {
+	marker, err := gh.geometryService.CenterMarker(geometry)
+	if err != nil {
+		log.Printf("❌ Center marker of geometry %s not loaded: %v", geometry.ID, err)
+		return nil
+	}
+	return marker
+}
+
+// geometryErrorStatus maps geometry service errors to HTTP status codes
+func geometryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrGeometryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrGeometryReadOnly):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidCoordinates), errors.Is(err, services.ErrInvalidGeometry):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -2,19 +2,74 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"sfaf-plotter/frequency"
 	"sfaf-plotter/models"
+	"sfaf-plotter/power"
 	"sfaf-plotter/services"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MarkerHandler struct {
-	markerService *services.MarkerService
+	markerService       *services.MarkerService
+	geometryService     *services.GeometryService
+	coordinationService *services.CoordinationService
+	coordService        *services.CoordinateService
+	conflictService     *services.ConflictService
 }
 
-func NewMarkerHandler(markerService *services.MarkerService) *MarkerHandler {
-	return &MarkerHandler{markerService: markerService}
+func NewMarkerHandler(markerService *services.MarkerService, geometryService *services.GeometryService, coordinationService *services.CoordinationService, coordService *services.CoordinateService, conflictService *services.ConflictService) *MarkerHandler {
+	return &MarkerHandler{markerService: markerService, geometryService: geometryService, coordinationService: coordinationService, coordService: coordService, conflictService: conflictService}
+}
+
+// addCoordination tests a created or moved marker against the coordination
+// areas. A failed check is logged rather than failing the marker request.
+func (mh *MarkerHandler) addCoordination(response *models.MarkerResponse) {
+	if response.Marker == nil {
+		return
+	}
+
+	matches, err := mh.coordinationService.CheckMarker(response.Marker)
+	if err != nil {
+		log.Printf("❌ Coordination areas not checked for marker %s: %v", response.Marker.ID, err)
+		return
+	}
+	response.Coordination = matches
+}
+
+// moveAuthorizationArea keeps a moved marker's field306 circle centered on
+// it. The marker is already saved, so a failure is only logged.
+func (mh *MarkerHandler) moveAuthorizationArea(response *models.MarkerResponse) {
+	if response.Marker == nil {
+		return
+	}
+
+	if err := mh.geometryService.MoveAuthorizationArea(*response.Marker); err != nil {
+		log.Printf("❌ Authorization area not moved for marker %s: %v", response.Marker.ID, err)
+	}
+}
+
+// addConflicts checks a created, moved or retuned marker for frequency
+// conflicts with nearby assignments. Markers without a frequency are left
+// alone and a failed check is logged.
+func (mh *MarkerHandler) addConflicts(response *models.MarkerResponse) {
+	if response.Marker == nil || response.Marker.Frequency == "" {
+		return
+	}
+
+	report, err := mh.conflictService.CheckMarker(response.Marker.ID.String(), mh.conflictService.Settings())
+	if err != nil {
+		log.Printf("❌ Frequency conflicts not checked for marker %s: %v", response.Marker.ID, err)
+		return
+	}
+	response.FrequencyConflicts = report.Conflicts
 }
 
 // Existing CRUD handlers
@@ -27,17 +82,42 @@ func (mh *MarkerHandler) CreateMarker(c *gin.Context) {
 
 	marker, err := mh.markerService.CreateMarker(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(markerErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	mh.addCoordination(marker)
+	mh.addConflicts(marker)
 
 	c.JSON(http.StatusCreated, marker)
 }
 
+// GetAllMarkers lists markers. Optional freq_min/freq_max (MHz) keep markers
+// whose frequency or band reaches into the range; power_class (low, medium,
+// high, very-high) or power_min/power_max (watts) filter on transmitter
+// power; sort=frequency orders by frequency instead of newest first.
+//
+// bbox=west,south,east,north keeps markers in the box; near=<position> with
+// radius (in radius_unit: km, nm, mi or m; km by default) keeps markers
+// within that geodesic distance; within=<geometry ID> keeps markers inside
+// a circle, rectangle or polygon. near and within add each marker's
+// distance (from the geometry center for within) and sort nearest first
+// unless sort=frequency. limit=n keeps n markers: the nearest for near and
+// within, where near with a limit and no radius finds the n nearest
+// anywhere.
 func (mh *MarkerHandler) GetAllMarkers(c *gin.Context) {
-	markers, err := mh.markerService.GetAllMarkers()
+	filter, err := parseMarkerFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := mh.parseSpatialFilter(c, &filter); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	markers, err := mh.markerService.FindMarkers(filter)
+	if err != nil {
+		c.JSON(markerErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -65,9 +145,16 @@ func (mh *MarkerHandler) UpdateMarker(c *gin.Context) {
 
 	marker, err := mh.markerService.UpdateMarker(id, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(markerErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	if req.Latitude != nil || req.Longitude != nil {
+		mh.moveAuthorizationArea(marker)
+		mh.addCoordination(marker)
+	}
+	if req.Latitude != nil || req.Longitude != nil || req.Frequency != nil {
+		mh.addConflicts(marker)
+	}
 
 	c.JSON(http.StatusOK, marker)
 }
@@ -80,6 +167,12 @@ func (mh *MarkerHandler) DeleteMarker(c *gin.Context) {
 		return
 	}
 
+	// A center marker takes its geometry with it
+	if err := mh.geometryService.DeleteMarkerGeometries(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Marker deleted successfully"})
 }
 
@@ -89,6 +182,10 @@ func (mh *MarkerHandler) DeleteAllMarkers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err := mh.geometryService.DeleteAllMarkerGeometries(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "All markers deleted successfully"})
 }
@@ -182,3 +279,111 @@ func (mh *MarkerHandler) RemoveIRACNoteFromMarker(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "IRAC note removed from marker successfully"})
 }
+
+// parseMarkerFilter reads the marker listing query parameters
+func parseMarkerFilter(c *gin.Context) (models.MarkerFilter, error) {
+	filter := models.MarkerFilter{SortBy: c.Query("sort")}
+	if filter.SortBy != "" && filter.SortBy != "frequency" && filter.SortBy != "distance" {
+		return filter, fmt.Errorf("invalid sort %q (supported: frequency, distance)", filter.SortBy)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+		filter.Limit = n
+	}
+
+	minMHz, err := parseOptionalFloat("freq_min", c.Query("freq_min"))
+	if err != nil {
+		return filter, err
+	}
+	maxMHz, err := parseOptionalFloat("freq_max", c.Query("freq_max"))
+	if err != nil {
+		return filter, err
+	}
+	if minMHz != nil {
+		hz := *minMHz * frequency.MHz
+		filter.MinFreqHz = &hz
+	}
+	if maxMHz != nil {
+		hz := *maxMHz * frequency.MHz
+		filter.MaxFreqHz = &hz
+	}
+
+	if class := c.Query("power_class"); class != "" {
+		minW, maxW, err := power.ClassRange(power.Class(class))
+		if err != nil {
+			return filter, err
+		}
+		filter.MinPowerW = &minW
+		if !math.IsInf(maxW, 1) {
+			filter.MaxPowerW = &maxW
+		}
+	}
+	if minW, err := parseOptionalFloat("power_min", c.Query("power_min")); err != nil {
+		return filter, err
+	} else if minW != nil {
+		filter.MinPowerW = minW
+	}
+	if maxW, err := parseOptionalFloat("power_max", c.Query("power_max")); err != nil {
+		return filter, err
+	} else if maxW != nil {
+		filter.MaxPowerW = maxW
+	}
+
+	return filter, nil
+}
+
+// parseSpatialFilter reads bbox, near/radius and within into filter. On
+// failure it also returns the status to answer with.
+func (mh *MarkerHandler) parseSpatialFilter(c *gin.Context, filter *models.MarkerFilter) (int, error) {
+	if bbox := c.Query("bbox"); bbox != "" {
+		box, err := parseBoundingBox(bbox)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		filter.Bounds = append(filter.Bounds, *box)
+	}
+
+	near := strings.TrimSpace(c.Query("near"))
+	radius, err := parseOptionalFloat("radius", c.Query("radius"))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	switch {
+	case near != "" && radius == nil && filter.Limit == 0:
+		return http.StatusBadRequest, fmt.Errorf("near needs a radius or a limit")
+	case near == "" && radius != nil:
+		return http.StatusBadRequest, fmt.Errorf("radius needs near")
+	case near != "":
+		position, err := mh.coordService.ParsePosition(near)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("near: %w", err)
+		}
+		if radius != nil {
+			if filter.RadiusM, err = services.DistanceToMeters(*radius, c.Query("radius_unit")); err != nil {
+				return http.StatusBadRequest, err
+			}
+		}
+		filter.Near = &position
+	}
+
+	if within := strings.TrimSpace(c.Query("within")); within != "" {
+		geometry, err := mh.geometryService.GetGeometry(within)
+		if err != nil {
+			return geometryErrorStatus(err), err
+		}
+		filter.Within = geometry
+	}
+	return http.StatusOK, nil
+}
+
+func markerErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidFrequency) || errors.Is(err, services.ErrInvalidPower) ||
+		errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidGeometry) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,144 @@
+// marker_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/services"
+	"sfaf-plotter/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestParseSpatialFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	geometry := &models.Geometry{ID: uuid.New(), Type: models.GeometryTypeCircle, Latitude: 30, Longitude: -86}
+	if err := store.SaveGeometry(geometry); err != nil {
+		t.Fatalf("SaveGeometry: %v", err)
+	}
+	coordService := services.NewCoordinateService()
+	geometryService := services.NewGeometryService(store, nil, nil, coordService)
+	mh := NewMarkerHandler(nil, geometryService, nil, coordService, nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		limit  int    // limit already read from the query
+		status int    // status of a rejected filter
+		errMsg string // substring of the error
+		check  func(t *testing.T, filter models.MarkerFilter)
+	}{
+		{
+			name: "no spatial filter",
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if len(filter.Bounds) != 0 || filter.Near != nil || filter.Within != nil {
+					t.Errorf("filter %+v", filter)
+				}
+			},
+		},
+		{
+			name:  "bbox",
+			query: "bbox=-83,24,-77,31.5",
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				want := models.BoundingBox{West: -83, South: 24, East: -77, North: 31.5}
+				if len(filter.Bounds) != 1 || filter.Bounds[0] != want {
+					t.Errorf("bounds %+v, want %+v", filter.Bounds, want)
+				}
+			},
+		},
+		{
+			name:  "bbox across the antimeridian",
+			query: "bbox=170,-10,-170,10",
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if len(filter.Bounds) != 1 || filter.Bounds[0].West != 170 || filter.Bounds[0].East != -170 {
+					t.Errorf("bounds %+v", filter.Bounds)
+				}
+			},
+		},
+		{name: "bbox of three values", query: "bbox=-83,24,-77", status: http.StatusBadRequest, errMsg: "west,south,east,north"},
+		{name: "bbox with text", query: "bbox=-83,south,-77,31.5", status: http.StatusBadRequest, errMsg: `invalid bbox value "south"`},
+		{name: "bbox upside down", query: "bbox=-83,31.5,-77,24", status: http.StatusBadRequest, errMsg: "invalid bbox latitudes"},
+		{
+			name:  "near with a radius",
+			query: "near=30.5,-86.5&radius=10",
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if filter.Near == nil || filter.Near.Lat != 30.5 || filter.Near.Lng != -86.5 || filter.RadiusM != 10e3 {
+					t.Errorf("near %+v, radius %.0f m", filter.Near, filter.RadiusM)
+				}
+			},
+		},
+		{
+			name:  "radius in nautical miles",
+			query: "near=30.5,-86.5&radius=10&radius_unit=nm",
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if filter.RadiusM != 18520 {
+					t.Errorf("radius %.0f m, want 18520", filter.RadiusM)
+				}
+			},
+		},
+		{
+			name:  "near with a limit",
+			query: "near=30.5,-86.5",
+			limit: 5,
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if filter.Near == nil || filter.RadiusM != 0 {
+					t.Errorf("near %+v, radius %.0f m", filter.Near, filter.RadiusM)
+				}
+			},
+		},
+		{name: "near alone", query: "near=30.5,-86.5", status: http.StatusBadRequest, errMsg: "near needs a radius or a limit"},
+		{name: "radius alone", query: "radius=10", status: http.StatusBadRequest, errMsg: "radius needs near"},
+		{name: "unreadable radius", query: "near=30.5,-86.5&radius=far", status: http.StatusBadRequest, errMsg: `invalid radius: "far"`},
+		{name: "unknown radius unit", query: "near=30.5,-86.5&radius=10&radius_unit=furlong", status: http.StatusBadRequest, errMsg: "unknown distance unit"},
+		{name: "unreadable near", query: "near=somewhere&radius=10", status: http.StatusBadRequest, errMsg: "near:"},
+		{
+			name:  "within a geometry",
+			query: "within=" + geometry.ID.String(),
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if filter.Within == nil || filter.Within.ID != geometry.ID {
+					t.Errorf("within %+v", filter.Within)
+				}
+			},
+		},
+		{name: "within an unknown geometry", query: "within=" + uuid.NewString(), status: http.StatusNotFound, errMsg: "geometry not found"},
+		{name: "within a malformed ID", query: "within=exercise-box", status: http.StatusNotFound, errMsg: "geometry not found"},
+		{
+			name:  "all filters",
+			query: "bbox=-90,20,-70,35&near=30.5,-86.5&radius=50&within=" + geometry.ID.String(),
+			check: func(t *testing.T, filter models.MarkerFilter) {
+				if len(filter.Bounds) != 1 || filter.Near == nil || filter.RadiusM != 50e3 || filter.Within == nil {
+					t.Errorf("filter %+v", filter)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/markers?"+tt.query, nil)
+			filter := models.MarkerFilter{Limit: tt.limit}
+
+			status, err := mh.parseSpatialFilter(c, &filter)
+			if tt.status != 0 {
+				if err == nil || status != tt.status || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("status %d, error %v; want %d, %q", status, err, tt.status, tt.errMsg)
+				}
+				return
+			}
+			if err != nil || status != http.StatusOK {
+				t.Fatalf("status %d, error %v", status, err)
+			}
+			tt.check(t, filter)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// handlers/nomination_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sfaf-plotter/models"
+	"sfaf-plotter/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NominationHandler struct {
+	nominationService *services.NominationService
+}
+
+func NewNominationHandler(nominationService *services.NominationService) *NominationHandler {
+	return &NominationHandler{nominationService: nominationService}
+}
+
+// Nominate proposes clear frequencies for a new assignment: the channels of
+// a band raster (or a channel list) at a location, away from restricted
+// frequencies and from the assignments within the radius that are active
+// in the optional from/to window, best separated first.
+func (nh *NominationHandler) Nominate(c *gin.Context) {
+	var req models.NominationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := nh.nominationService.Nominate(req)
+	if err != nil {
+		c.JSON(nominationErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "nomination": result})
+}
+
+// nominationErrorStatus maps nomination service errors to HTTP status codes
+func nominationErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidNomination) || errors.Is(err, services.ErrInvalidCoordinates) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
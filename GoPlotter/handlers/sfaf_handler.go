@@ -1,23 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"sfaf-plotter/models"
+	"sfaf-plotter/power"
 	"sfaf-plotter/services"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SFAFHandler struct {
-	sfafService   *services.SFAFService
-	markerService *services.MarkerService
+	sfafService     *services.SFAFService
+	markerService   *services.MarkerService
+	conflictService *services.ConflictService
 }
 
-func NewSFAFHandler(sfafService *services.SFAFService, markerService *services.MarkerService) *SFAFHandler {
+func NewSFAFHandler(sfafService *services.SFAFService, markerService *services.MarkerService, conflictService *services.ConflictService) *SFAFHandler {
 	return &SFAFHandler{
-		sfafService:   sfafService,
-		markerService: markerService,
+		sfafService:     sfafService,
+		markerService:   markerService,
+		conflictService: conflictService,
 	}
 }
 
@@ -33,27 +41,101 @@ func (sh *SFAFHandler) GetObjectData(c *gin.Context) {
 	sfaf, _ := sh.sfafService.GetSFAFByMarkerID(markerID)
 
 	var fields map[string]string
+	var entries []models.SFAFEntry
+	var emissions []models.SFAFEmission
+	var bandwidthHz float64
+	var transmitterPower *power.Power
 	if sfaf != nil {
 		fields = sfaf.Fields // This should now work correctly
+		entries = sfaf.Entries
+		emissions = sfaf.Emissions()
+		bandwidthHz = sfaf.NecessaryBandwidthHz()
+		if parsed, err := power.Parse(sfaf.Value("field115")); err == nil {
+			transmitterPower = &parsed
+		}
 	} else {
 		fields = sh.sfafService.AutoPopulateFromMarker(markerResp.Marker)
+		entries = models.SFAFEntriesFromFields(fields)
 	}
 
 	fieldDefs := sh.sfafService.GetFieldDefinitions()
-	coordFormats := sh.sfafService.GetCoordinateFormats(markerResp.Marker.Latitude, markerResp.Marker.Longitude)
+	formatOpts, err := sh.sfafService.CoordinateFormatOptions(sfaf, c.Query("precision"), c.Query("mgrs_precision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	coordFormats := sh.sfafService.GetCoordinateFormats(markerResp.Marker.Latitude, markerResp.Marker.Longitude, formatOpts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"marker":  markerResp.Marker,
 		"coordinates": map[string]interface{}{
-			"lat":     markerResp.Marker.Latitude,  // Always float64
-			"lng":     markerResp.Marker.Longitude, // Always float64
-			"decimal": fmt.Sprintf("%.6f, %.6f", markerResp.Marker.Latitude, markerResp.Marker.Longitude),
-			"dms":     coordFormats.DMS,     // Add DMS format
-			"compact": coordFormats.Compact, // Add compact military format
+			"lat":       markerResp.Marker.Latitude,  // Always float64
+			"lng":       markerResp.Marker.Longitude, // Always float64
+			"decimal":   fmt.Sprintf("%.6f, %.6f", markerResp.Marker.Latitude, markerResp.Marker.Longitude),
+			"dms":       coordFormats.DMS,       // Add DMS format
+			"compact":   coordFormats.Compact,   // Add compact military format
+			"precision": coordFormats.Precision, // decimal places on the DMS seconds
+			"utm":       coordFormats.UTM,       // empty beyond 80°S/84°N
+			"mgrs":      coordFormats.MGRS,
 		},
-		"sfaf_fields": fields,
-		"field_defs":  fieldDefs,
+		"sfaf_fields":  fields,
+		"sfaf_entries": entries,
+		"emissions":    emissions,        // decoded field114 occurrences
+		"bandwidth_hz": bandwidthHz,      // widest necessary bandwidth, 0 if unknown
+		"power":        transmitterPower, // field115 in W, dBW and dBm
+		"field_defs":   fieldDefs,
+	})
+}
+
+// GetFieldDefinitions returns the SFAF field catalogue used for validation
+// so the form can apply the same labels, lengths and occurrence limits.
+func (sh *SFAFHandler) GetFieldDefinitions(c *gin.Context) {
+	catalogue := sh.sfafService.GetFieldCatalogue()
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"version":     catalogue.Version,
+		"publication": catalogue.Publication,
+		"fields":      catalogue.Fields,
+		"field_defs":  sh.sfafService.GetFieldDefinitions(),
+	})
+}
+
+// ValidateSFAF checks a record without saving it: catalogue field formats,
+// then the named rule set (the full MCEB Pub 7 compliance set by default).
+// The form calls this, and imports run the same checks with the default
+// set, so browser and imports share one set of checks.
+func (sh *SFAFHandler) ValidateSFAF(c *gin.Context) {
+	var req models.ValidateSFAFRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	fields := req.Fields
+	if len(req.Entries) > 0 {
+		fields = models.SFAFFieldsFromEntries(req.Entries)
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "fields or entries are required"})
+		return
+	}
+
+	ruleSet := req.RuleSet
+	if ruleSet == "" {
+		ruleSet = services.DefaultSFAFRuleSet
+	}
+
+	validation, err := sh.sfafService.ValidateFieldsWithRuleSet(fields, ruleSet)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error(), "rule_sets": sh.sfafService.SFAFRuleSets()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"rule_set":   ruleSet,
+		"validation": validation,
 	})
 }
 
@@ -66,15 +148,22 @@ func (sh *SFAFHandler) CreateSFAF(c *gin.Context) {
 
 	sfaf, err := sh.sfafService.CreateSFAF(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(sfafErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"success": true,
 		"message": "SFAF created successfully",
 		"sfaf":    sfaf,
-	})
+	}
+	// A record without a frequency cannot conflict; other failures are logged
+	if report, err := sh.conflictService.CheckSFAF(sfaf.ID.String(), sh.conflictService.Settings()); err == nil {
+		response["frequency_conflicts"] = report.Conflicts
+	} else if !errors.Is(err, services.ErrNoFrequency) {
+		log.Printf("❌ Frequency conflicts not checked for SFAF %s: %v", sfaf.ID, err)
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 func (sh *SFAFHandler) UpdateSFAF(c *gin.Context) {
@@ -88,7 +177,7 @@ func (sh *SFAFHandler) UpdateSFAF(c *gin.Context) {
 
 	sfaf, err := sh.sfafService.UpdateSFAF(id, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(sfafErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -99,6 +188,13 @@ func (sh *SFAFHandler) UpdateSFAF(c *gin.Context) {
 	})
 }
 
+func sfafErrorStatus(err error) int {
+	if errors.Is(err, services.ErrSFAFValidation) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (sh *SFAFHandler) DeleteSFAF(c *gin.Context) {
 	id := c.Param("id")
 
@@ -113,3 +209,237 @@ func (sh *SFAFHandler) DeleteSFAF(c *gin.Context) {
 		"message": "SFAF deleted successfully",
 	})
 }
+
+// ExportSFAF downloads one SFAF record. format is "sfaf" (MCEB Pub 7 text,
+// the default), "json", "csv" or "xml".
+func (sh *SFAFHandler) ExportSFAF(c *gin.Context) {
+	id := c.Param("id")
+	format := models.SFAFExportFormat(c.DefaultQuery("format", string(models.SFAFExportText)))
+
+	data, err := sh.sfafService.ExportSFAF(id, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType, extension := services.SFAFExportContentType(format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=sfaf_%s.%s", id, extension))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ExportSFAFs downloads every SFAF record matching the query filters as one
+// file. Filters: agency (field200), state (field300), type (marker type),
+// freq_min/freq_max (MHz, field110), bbox (west,south,east,north) and
+// from/to (assignment date, YYYY-MM-DD or YYYYMMDD). format is "sfaf" (the
+// default), "csv", "json", "xml" or "zip"; zip holds one file per record in
+// record_format (default "sfaf").
+func (sh *SFAFHandler) ExportSFAFs(c *gin.Context) {
+	filter, err := parseSFAFExportFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	format := models.SFAFExportFormat(c.DefaultQuery("format", string(models.SFAFExportText)))
+	recordFormat := models.SFAFExportFormat(c.DefaultQuery("record_format", string(models.SFAFExportText)))
+	if !isSFAFExportFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("unsupported export format: %s", format)})
+		return
+	}
+	if format == models.SFAFExportZip && (recordFormat == models.SFAFExportZip || !isSFAFExportFormat(recordFormat)) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": fmt.Sprintf("unsupported record format: %s", recordFormat)})
+		return
+	}
+
+	records, err := sh.sfafService.FindSFAFs(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	log.Printf("📤 Exporting %d SFAF records as %s", len(records), format)
+
+	contentType, extension := services.SFAFExportContentType(format)
+	filename := fmt.Sprintf("sfaf_export_%s.%s", time.Now().Format("20060102_150405"), extension)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("X-SFAF-Record-Count", strconv.Itoa(len(records)))
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure here can only be logged
+	if err := sh.sfafService.WriteSFAFExport(c.Writer, records, format, recordFormat); err != nil {
+		log.Printf("❌ SFAF export failed: %v", err)
+	}
+}
+
+func isSFAFExportFormat(format models.SFAFExportFormat) bool {
+	switch format {
+	case models.SFAFExportText, models.SFAFExportCSV, models.SFAFExportJSON, models.SFAFExportXML, models.SFAFExportZip:
+		return true
+	}
+	return false
+}
+
+// parseSFAFExportFilter reads the bulk export query parameters
+func parseSFAFExportFilter(c *gin.Context) (models.SFAFExportFilter, error) {
+	filter := models.SFAFExportFilter{
+		Agency:     strings.TrimSpace(c.Query("agency")),
+		State:      strings.TrimSpace(c.Query("state")),
+		MarkerType: strings.TrimSpace(c.Query("type")),
+	}
+
+	var err error
+	if filter.MinFreqMHz, err = parseOptionalFloat("freq_min", c.Query("freq_min")); err != nil {
+		return filter, err
+	}
+	if filter.MaxFreqMHz, err = parseOptionalFloat("freq_max", c.Query("freq_max")); err != nil {
+		return filter, err
+	}
+	if filter.MinFreqMHz != nil && filter.MaxFreqMHz != nil && *filter.MinFreqMHz > *filter.MaxFreqMHz {
+		return filter, fmt.Errorf("freq_min is greater than freq_max")
+	}
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		if filter.Bounds, err = parseBoundingBox(bbox); err != nil {
+			return filter, err
+		}
+	}
+
+	if filter.From, err = parseExportDate("from", c.Query("from")); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseExportDate("to", c.Query("to")); err != nil {
+		return filter, err
+	}
+	if filter.To != nil {
+		// Inclusive: anything on the "to" day matches
+		end := filter.To.Add(24*time.Hour - time.Nanosecond)
+		filter.To = &end
+	}
+
+	return filter, nil
+}
+
+func parseExportDate(name, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s date %q (use YYYY-MM-DD)", name, value)
+}
+
+// ImportSFAF imports an uploaded SFAF text file. Markers and SFAF records are
+// saved as one unit: either every accepted record is stored or none is. The
+// optional "accept" form value lists the record numbers (from a preview) to
+// import; without it every record is imported, and an empty list imports
+// none. The response reports each record's line range, serial and outcome.
+func (sh *SFAFHandler) ImportSFAF(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	accept, present := c.GetPostForm("accept")
+	accepted, err := parseAcceptedRecords(accept, present)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	log.Printf("📁 Importing SFAF file: %s (%d bytes)", header.Filename, header.Size)
+
+	result, err := sh.sfafService.ImportSFAFFile(file, header.Filename, accepted)
+	if err != nil {
+		log.Printf("❌ Import of %s failed: %v", header.Filename, err)
+		response := gin.H{"success": false, "error": err.Error()}
+		if result != nil {
+			response["records"] = result.Records
+		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	log.Printf("✅ Imported %s: %d new, %d updated, %d deleted, %d skipped, %d conflicts",
+		header.Filename, result.Imported, result.Updated, result.Deleted, result.Skipped, result.Conflicts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Imported %d records, updated %d, deleted %d, skipped %d, %d conflicts",
+			result.Imported, result.Updated, result.Deleted, result.Skipped, result.Conflicts),
+		"filename":           header.Filename,
+		"imported_count":     result.Imported,
+		"updated_count":      result.Updated,
+		"deleted_count":      result.Deleted,
+		"skipped_count":      result.Skipped,
+		"conflict_count":     result.Conflicts,
+		"markers":            result.Markers,
+		"deleted_marker_ids": result.DeletedIDs,
+		"sfaf_records":       result.SFAFRecords,
+		"records":            result.Records,
+	})
+}
+
+// PreviewSFAFImport parses an uploaded SFAF file and reports, per record,
+// whether it is new, unchanged, modified or deleted compared with the
+// assignment that has the same serial (field102), or conflicts with its
+// transaction type (field010). Nothing is written.
+func (sh *SFAFHandler) PreviewSFAFImport(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	records, err := sh.sfafService.PreviewSFAFImport(file, header.Filename)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	counts := map[string]int{"new": 0, "unchanged": 0, "modified": 0, "delete": 0, "skipped": 0, "conflict": 0}
+	for _, record := range records {
+		if record.Status != "" {
+			counts[string(record.Status)]++
+		} else {
+			counts[string(record.Match)]++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"filename": header.Filename,
+		"counts":   counts,
+		"records":  records,
+	})
+}
+
+// parseAcceptedRecords reads a comma-separated list of 1-based record numbers.
+// A missing list (nil) accepts every record; a present but empty one
+// accepts none.
+func parseAcceptedRecords(value string, present bool) (map[int]bool, error) {
+	if !present {
+		return nil, nil
+	}
+
+	accepted := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		record, err := strconv.Atoi(part)
+		if err != nil || record < 1 {
+			return nil, fmt.Errorf("invalid record number in accept list: %q", part)
+		}
+		accepted[record] = true
+	}
+	return accepted, nil
+}
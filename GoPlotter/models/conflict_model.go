@@ -0,0 +1,54 @@
+// models/conflict_model.go
+package models
+
+import "github.com/google/uuid"
+
+// ConflictType says how the occupied bands of two assignments relate
+type ConflictType string
+
+const (
+	ConflictCoChannel       ConflictType = "co-channel"       // the bands overlap
+	ConflictAdjacentChannel ConflictType = "adjacent-channel" // the bands are within the guard band
+)
+
+// OccupiedBand is the spectrum an assignment uses: its field110 frequency
+// or band widened on each side by half the widest field114 necessary
+// bandwidth
+type OccupiedBand struct {
+	MinHz       float64 `json:"min_hz"`
+	MaxHz       float64 `json:"max_hz"`
+	CenterHz    float64 `json:"center_hz"`
+	BandwidthHz float64 `json:"bandwidth_hz"` // necessary bandwidth, 0 without an emission designator
+}
+
+// ConflictSettings limit a conflict check: assignments farther than
+// DistanceM, or with bands more than GuardBandHz apart, do not conflict
+type ConflictSettings struct {
+	GuardBandHz float64 `json:"guard_band_hz"`
+	DistanceM   float64 `json:"distance_m"`
+}
+
+// FrequencyConflict is an existing assignment that may interfere with the
+// one checked. Conflicts are ranked from 1, co-channel before
+// adjacent-channel, then nearest first, then closest in frequency.
+type FrequencyConflict struct {
+	Rank         int          `json:"rank"`
+	Type         ConflictType `json:"type"`
+	MarkerID     uuid.UUID    `json:"marker_id"`
+	SFAFID       *uuid.UUID   `json:"sfaf_id,omitempty"`
+	Serial       string       `json:"serial"`
+	Frequency    string       `json:"frequency"`
+	Band         OccupiedBand `json:"band"`
+	SeparationHz float64      `json:"separation_hz"` // between center frequencies
+	GapHz        float64      `json:"gap_hz"`        // between band edges, 0 when they overlap
+	OverlapHz    float64      `json:"overlap_hz"`    // spectrum both bands use
+	Distance     Distance     `json:"distance"`
+}
+
+// ConflictReport is the result of checking one assignment
+type ConflictReport struct {
+	MarkerID  uuid.UUID           `json:"marker_id"`
+	Band      OccupiedBand        `json:"band"`
+	Settings  ConflictSettings    `json:"settings"`
+	Conflicts []FrequencyConflict `json:"conflicts"`
+}
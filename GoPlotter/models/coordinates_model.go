@@ -14,7 +14,82 @@ type DMSCoordinate struct {
 }
 
 type CoordinateResponse struct {
-	Decimal string `json:"decimal"`
-	DMS     string `json:"dms"`
-	Compact string `json:"compact"`
+	Decimal   string `json:"decimal"`
+	DMS       string `json:"dms"`
+	Compact   string `json:"compact"`
+	Precision int    `json:"precision"` // decimal places on the DMS seconds
+	// UTM and MGRS are left out beyond 80°S and 84°N
+	UTM           string `json:"utm,omitempty"`
+	MGRS          string `json:"mgrs,omitempty"`
+	MGRSPrecision int    `json:"mgrs_precision,omitempty"` // digits each of easting and northing
+}
+
+// CoordinateFormatOptions selects the precision of formatted coordinates
+type CoordinateFormatOptions struct {
+	SecondsPrecision int // decimal places on DMS seconds: 0 or 1
+	MGRSPrecision    int // digits each of easting and northing: 1 (10 km) to 5 (1 m); 0 means 5
+}
+
+// UTMCoordinate is a position on the UTM grid in metres. Band is the 8°
+// latitude band letter; Hemisphere (N or S) decides the false northing.
+type UTMCoordinate struct {
+	Zone       int     `json:"zone"`
+	Band       string  `json:"band"`
+	Hemisphere string  `json:"hemisphere"`
+	Easting    float64 `json:"easting"`
+	Northing   float64 `json:"northing"`
+}
+
+// Distance is one length in every unit we report
+type Distance struct {
+	Meters       float64 `json:"m"`
+	Km           float64 `json:"km"`
+	NM           float64 `json:"nm"`
+	StatuteMiles float64 `json:"mi"`
+}
+
+// Area is one area in every unit we report
+type Area struct {
+	SquareKm    float64 `json:"km2"`
+	SquareNM    float64 `json:"nm2"`
+	SquareMiles float64 `json:"mi2"`
+}
+
+// GeodesicResult is the shortest path between two points on the WGS 84
+// ellipsoid. Bearings are degrees clockwise from true north. Ellipsoidal is
+// false when nearly antipodal points forced a spherical estimate.
+type GeodesicResult struct {
+	From           Coordinate `json:"from"`
+	To             Coordinate `json:"to"`
+	Distance       Distance   `json:"distance"`
+	InitialBearing float64    `json:"initial_bearing"`
+	FinalBearing   float64    `json:"final_bearing"`
+	Ellipsoidal    bool       `json:"ellipsoidal"`
+}
+
+// DestinationResult is the point reached from From along InitialBearing
+type DestinationResult struct {
+	From           Coordinate `json:"from"`
+	To             Coordinate `json:"to"`
+	Distance       Distance   `json:"distance"`
+	InitialBearing float64    `json:"initial_bearing"`
+	FinalBearing   float64    `json:"final_bearing"`
+}
+
+// BoundingBox is a lat/lng rectangle. West > East wraps the antimeridian.
+type BoundingBox struct {
+	South float64 `json:"south"`
+	West  float64 `json:"west"`
+	North float64 `json:"north"`
+	East  float64 `json:"east"`
+}
+
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return lng >= b.West && lng <= b.East
+	}
+	return lng >= b.West || lng <= b.East
 }
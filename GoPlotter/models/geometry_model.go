@@ -27,35 +27,70 @@ type Geometry struct {
 	Latitude  float64 `json:"lat" db:"latitude"`
 	Longitude float64 `json:"lng" db:"longitude"`
 
+	// Marker the geometry belongs to, and what generated it ("" for shapes
+	// drawn by hand, GeometrySourceField306 for SFAF authorization areas).
+	// A drawn shape belongs to its center marker; the two are deleted together.
+	MarkerID *uuid.UUID `json:"marker_id,omitempty" db:"marker_id"`
+	Source   string     `json:"source,omitempty" db:"source"`
+
+	// Coordination areas name their IRAC note and describe the shape
+	IRACNote string `json:"irac_note,omitempty" db:"irac_note"`
+	Label    string `json:"label,omitempty" db:"label"`
+
 	// Type-specific properties
 	CircleProps    *CircleGeometry    `json:"circle_properties,omitempty"`
 	PolygonProps   *PolygonGeometry   `json:"polygon_properties,omitempty"`
 	RectangleProps *RectangleGeometry `json:"rectangle_properties,omitempty"`
 }
 
+// Areas and perimeters are measured on the WGS 84 ellipsoid. Area keeps
+// square miles for older clients; Areas gives km², nm² and mi².
+
 type CircleGeometry struct {
-	Radius   float64 `json:"radius"`    // in meters
-	RadiusKm float64 `json:"radius_km"` // in kilometers
-	RadiusNm float64 `json:"radius_nm"` // in nautical miles
-	Area     float64 `json:"area"`      // in square miles
-	Unit     string  `json:"unit"`      // "km" or "nm"
+	Radius    float64  `json:"radius"`    // in meters
+	RadiusKm  float64  `json:"radius_km"` // in kilometers
+	RadiusNm  float64  `json:"radius_nm"` // in nautical miles
+	Area      float64  `json:"area"`      // in square miles
+	Areas     Area     `json:"areas"`
+	Perimeter Distance `json:"perimeter"`
+	Unit      string   `json:"unit"`            // "km" or "nm"
+	Scope     string   `json:"scope,omitempty"` // field306 suffix: RadiusScopeBoth or RadiusScopeTransmitter
 }
 
 type PolygonGeometry struct {
-	Points   []Coordinate `json:"points"`
-	Vertices int          `json:"vertices"`
-	Area     float64      `json:"area"` // in square miles
+	Points    []Coordinate `json:"points"`
+	Vertices  int          `json:"vertices"`
+	Area      float64      `json:"area"` // in square miles
+	Areas     Area         `json:"areas"`
+	Perimeter Distance     `json:"perimeter"`
 }
 
 type RectangleGeometry struct {
-	Bounds []Coordinate `json:"bounds"` // [SW, NE]
-	Area   float64      `json:"area"`   // in square miles
+	Bounds    []Coordinate `json:"bounds"` // [SW, NE]
+	Area      float64      `json:"area"`   // in square miles
+	Areas     Area         `json:"areas"`
+	Perimeter Distance     `json:"perimeter"`
 }
 
+// GeometrySourceField306 marks circles generated from an SFAF record's
+// authorized radius
+const GeometrySourceField306 = "field306"
+
+// GeometrySourceCoordination marks the coordination areas of IRAC notes
+const GeometrySourceCoordination = "irac-coordination"
+
+// Authorized radius (field306) suffixes: B applies the radius to both the
+// transmitter and receivers, T to the transmitter only
+const (
+	RadiusScopeBoth        = "B"
+	RadiusScopeTransmitter = "T"
+)
+
 // Create requests
 type CreateCircleRequest struct {
-	Lat       float64 `json:"lat" binding:"required"`
-	Lng       float64 `json:"lng" binding:"required"`
+	Lat       float64 `json:"lat"`
+	Lng       float64 `json:"lng"`
+	Center    string  `json:"center"` // MGRS, UTM or lat/lng text in place of lat/lng
 	Radius    float64 `json:"radius" binding:"required"`
 	Unit      string  `json:"unit"` // "km" or "nm", defaults to "km"
 	Color     string  `json:"color"`
@@ -64,16 +99,80 @@ type CreateCircleRequest struct {
 }
 
 type CreatePolygonRequest struct {
-	Points    []Coordinate `json:"points" binding:"required"`
+	Points    []Coordinate `json:"points"`
+	Positions []string     `json:"positions"` // MGRS, UTM or lat/lng text in place of points
 	Color     string       `json:"color"`
 	Frequency string       `json:"frequency"`
 	Notes     string       `json:"notes"`
 }
 
 type CreateRectangleRequest struct {
-	SouthWest Coordinate `json:"south_west" binding:"required"`
-	NorthEast Coordinate `json:"north_east" binding:"required"`
-	Color     string     `json:"color"`
-	Frequency string     `json:"frequency"`
-	Notes     string     `json:"notes"`
+	SouthWest Coordinate `json:"south_west"`
+	NorthEast Coordinate `json:"north_east"`
+	// MGRS, UTM or lat/lng text in place of south_west and north_east
+	SouthWestPosition string `json:"south_west_position"`
+	NorthEastPosition string `json:"north_east_position"`
+	Color             string `json:"color"`
+	Frequency         string `json:"frequency"`
+	Notes             string `json:"notes"`
+}
+
+// UpdateGeometryRequest edits a drawn geometry. Circles take Radius and
+// Unit, polygons Points or Positions, rectangles SouthWest and NorthEast.
+// Notes are kept on the center marker.
+type UpdateGeometryRequest struct {
+	Points    []Coordinate `json:"points,omitempty"`
+	Positions []string     `json:"positions,omitempty"` // MGRS, UTM or lat/lng text in place of points
+	SouthWest *Coordinate  `json:"south_west,omitempty"`
+	NorthEast *Coordinate  `json:"north_east,omitempty"`
+	Radius    *float64     `json:"radius,omitempty"`
+	Unit      *string      `json:"unit,omitempty"` // "km" or "nm"
+	Color     *string      `json:"color,omitempty"`
+	Notes     *string      `json:"notes,omitempty"`
+}
+
+// Coordination requirements: a marker inside a required area needs the
+// area's note; inside a suggested one the note is advised
+const (
+	CoordinationRequired  = "required"
+	CoordinationSuggested = "suggested"
+)
+
+// CoordinationAreaCatalogue is the data file describing where the IRAC
+// coordination notes apply
+type CoordinationAreaCatalogue struct {
+	Version     string             `json:"version"`
+	Source      string             `json:"source"`
+	Description string             `json:"description"`
+	Areas       []CoordinationArea `json:"areas"`
+}
+
+// CoordinationArea is the area of one note, made of one or more shapes
+type CoordinationArea struct {
+	Note        string              `json:"note"`
+	Name        string              `json:"name"`
+	Requirement string              `json:"requirement"`
+	Shapes      []CoordinationShape `json:"shapes"`
+}
+
+// CoordinationShape is a circle (Center, RadiusKm), rectangle (SouthWest,
+// NorthEast) or polygon (Points)
+type CoordinationShape struct {
+	Type      GeometryType `json:"type"`
+	Label     string       `json:"label"`
+	Center    *Coordinate  `json:"center,omitempty"`
+	RadiusKm  float64      `json:"radius_km,omitempty"`
+	SouthWest *Coordinate  `json:"south_west,omitempty"`
+	NorthEast *Coordinate  `json:"north_east,omitempty"`
+	Points    []Coordinate `json:"points,omitempty"`
+}
+
+// CoordinationMatch is a coordination area a marker lies in
+type CoordinationMatch struct {
+	Note       string    `json:"note"`
+	Name       string    `json:"name"`
+	Area       string    `json:"area"` // label of the shape that matched
+	GeometryID uuid.UUID `json:"geometry_id"`
+	Required   bool      `json:"required"`
+	Present    bool      `json:"present"` // the marker already carries the note
 }
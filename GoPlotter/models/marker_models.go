@@ -3,6 +3,9 @@ package models
 
 import (
 	"encoding/json"
+	"sfaf-plotter/frequency"
+	"sfaf-plotter/power"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +18,11 @@ type Marker struct {
 	Latitude    float64               `json:"lat" db:"latitude"`
 	Longitude   float64               `json:"lng" db:"longitude"`
 	Frequency   string                `json:"frequency" db:"frequency"`
+	FrequencyHz *float64              `json:"frequency_hz,omitempty" db:"frequency_hz"`         // parsed from Frequency
+	FreqMinHz   *float64              `json:"frequency_min_hz,omitempty" db:"frequency_min_hz"` // band lower edge
+	FreqMaxHz   *float64              `json:"frequency_max_hz,omitempty" db:"frequency_max_hz"` // band upper edge
+	Power       string                `json:"power" db:"power"`                                 // raw field115, e.g. "W20"
+	PowerWatts  *float64              `json:"power_watts,omitempty" db:"power_watts"`           // parsed from Power
 	Notes       string                `json:"notes" db:"notes"`
 	MarkerType  string                `json:"type" db:"marker_type"`
 	IsDraggable bool                  `json:"is_draggable" db:"is_draggable"`
@@ -22,6 +30,104 @@ type Marker struct {
 	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
 	IRACNotes   []IRACNoteAssociation `json:"irac_notes,omitempty"`
 	SFAFFields  []SFAFField           `json:"sfaf_fields,omitempty"`
+	Distance    *Distance             `json:"distance,omitempty" db:"-"` // from the spatial query origin, when there is one
+}
+
+// ParseFrequency fills the parsed frequency columns from Frequency. An empty
+// frequency clears them; an invalid one clears them and returns the error.
+func (m *Marker) ParseFrequency() error {
+	m.FrequencyHz, m.FreqMinHz, m.FreqMaxHz = nil, nil, nil
+	if strings.TrimSpace(m.Frequency) == "" {
+		return nil
+	}
+
+	f, err := frequency.Parse(m.Frequency)
+	if err != nil {
+		return err
+	}
+	m.FrequencyHz, m.FreqMinHz, m.FreqMaxHz = &f.Hz, &f.MinHz, &f.MaxHz
+	return nil
+}
+
+// ParsePower fills PowerWatts from Power the same way ParseFrequency does
+// for the frequency columns.
+func (m *Marker) ParsePower() error {
+	m.PowerWatts = nil
+	if strings.TrimSpace(m.Power) == "" {
+		return nil
+	}
+
+	p, err := power.Parse(m.Power)
+	if err != nil {
+		return err
+	}
+	m.PowerWatts = &p.Watts
+	return nil
+}
+
+// MarkerFilter narrows a marker listing. Nil and empty values match
+// everything.
+type MarkerFilter struct {
+	MinFreqHz *float64 // markers whose frequency or band reaches this
+	MaxFreqHz *float64
+	MinPowerW *float64 // watts, inclusive
+	MaxPowerW *float64 // watts, exclusive
+
+	// Bounds are boxes a marker must lie in, all of them. Storage applies
+	// them; Near and Within are tested exactly by MarkerService, which adds
+	// their enclosing boxes here first.
+	Bounds  []BoundingBox
+	Near    *Coordinate // with RadiusM: markers at most RadiusM metres away
+	RadiusM float64
+	Within  *Geometry // markers inside the geometry
+
+	// IDs, when not nil, restricts storage to these markers. MarkerService
+	// fills it from its spatial index.
+	IDs []uuid.UUID
+
+	// Limit, when positive, keeps that many markers: the nearest for Near
+	// and Within (the nearest anywhere for Near without RadiusM), else the
+	// first in SortBy order
+	Limit int
+
+	SortBy string // "frequency", "distance" or "" (nearest first for spatial queries, else newest first)
+}
+
+// FrequencyBefore orders markers by frequency, then upper band edge, then
+// serial, with markers without a frequency last
+func (m *Marker) FrequencyBefore(other *Marker) bool {
+	if (m.FrequencyHz == nil) != (other.FrequencyHz == nil) {
+		return other.FrequencyHz == nil
+	}
+	if m.FrequencyHz != nil && *m.FrequencyHz != *other.FrequencyHz {
+		return *m.FrequencyHz < *other.FrequencyHz
+	}
+	if m.FreqMaxHz != nil && other.FreqMaxHz != nil && *m.FreqMaxHz != *other.FreqMaxHz {
+		return *m.FreqMaxHz < *other.FreqMaxHz
+	}
+	return m.Serial < other.Serial
+}
+
+// Matches applies the attribute and Bounds filters to one marker
+func (f MarkerFilter) Matches(m *Marker) bool {
+	if f.MinFreqHz != nil && (m.FreqMaxHz == nil || *m.FreqMaxHz < *f.MinFreqHz) {
+		return false
+	}
+	if f.MaxFreqHz != nil && (m.FreqMinHz == nil || *m.FreqMinHz > *f.MaxFreqHz) {
+		return false
+	}
+	if f.MinPowerW != nil && (m.PowerWatts == nil || *m.PowerWatts < *f.MinPowerW) {
+		return false
+	}
+	if f.MaxPowerW != nil && (m.PowerWatts == nil || *m.PowerWatts >= *f.MaxPowerW) {
+		return false
+	}
+	for _, box := range f.Bounds {
+		if !box.Contains(m.Latitude, m.Longitude) {
+			return false
+		}
+	}
+	return true
 }
 
 type IRACNote struct {
@@ -56,17 +162,21 @@ type SFAFField struct {
 
 // Request/Response models for API
 type CreateMarkerRequest struct {
-	Latitude   float64 `json:"lat" binding:"required"`
-	Longitude  float64 `json:"lng" binding:"required"`
-	Frequency  string  `json:"frequency"`
-	Notes      string  `json:"notes"`
-	MarkerType string  `json:"type"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+	// Position replaces lat/lng: MGRS, UTM or any latitude/longitude text
+	Position   string `json:"position"`
+	Frequency  string `json:"frequency"`
+	Power      string `json:"power"`
+	Notes      string `json:"notes"`
+	MarkerType string `json:"type"`
 }
 
 type UpdateMarkerRequest struct {
 	Latitude    *float64 `json:"lat,omitempty"`
 	Longitude   *float64 `json:"lng,omitempty"`
 	Frequency   *string  `json:"frequency,omitempty"`
+	Power       *string  `json:"power,omitempty"`
 	Notes       *string  `json:"notes,omitempty"`
 	MarkerType  *string  `json:"type,omitempty"`
 	IsDraggable *bool    `json:"is_draggable,omitempty"`
@@ -76,6 +186,12 @@ type MarkerResponse struct {
 	Success bool    `json:"success"`
 	Message string  `json:"message"`
 	Marker  *Marker `json:"marker,omitempty"`
+
+	// Coordination areas the marker lies in, set on create and move
+	Coordination []CoordinationMatch `json:"coordination,omitempty"`
+	// Nearby assignments that may interfere, set on create, move and
+	// frequency change
+	FrequencyConflicts []FrequencyConflict `json:"frequency_conflicts,omitempty"`
 }
 
 type MarkersResponse struct {
@@ -0,0 +1,86 @@
+// models/nomination_model.go
+package models
+
+import "github.com/google/uuid"
+
+// RestrictedFrequencyCatalogue is the data file of frequencies that are
+// never nominated
+type RestrictedFrequencyCatalogue struct {
+	Version     string                `json:"version"`
+	Source      string                `json:"source"`
+	Description string                `json:"description"`
+	Frequencies []RestrictedFrequency `json:"frequencies"`
+}
+
+// RestrictedFrequency is a frequency or band (field110 style) no nominated
+// channel may overlap
+type RestrictedFrequency struct {
+	Frequency string  `json:"frequency"`
+	Name      string  `json:"name"`
+	Reference string  `json:"reference"`
+	MinHz     float64 `json:"min_hz,omitempty"` // parsed from Frequency on load
+	MaxHz     float64 `json:"max_hz,omitempty"`
+}
+
+// NominationRequest asks for clear frequencies for a new assignment. The
+// channels are either a band with a channel raster or an explicit list.
+type NominationRequest struct {
+	Band           string   `json:"band"`             // e.g. "M225-M400"
+	ChannelStepKHz float64  `json:"channel_step_khz"` // raster spacing from the lower band edge; the bandwidth by default
+	Channels       []string `json:"channels"`         // e.g. ["M225.5", "M226.1"], instead of band
+
+	Position  string  `json:"position"` // MGRS, UTM or latitude/longitude; or lat and lng
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+
+	BandwidthKHz float64 `json:"bandwidth_khz"`
+	Emission     string  `json:"emission"` // emission designator; its bandwidth replaces bandwidth_khz
+
+	Radius       float64  `json:"radius"`      // radius of concern
+	RadiusUnit   string   `json:"radius_unit"` // km (default), nm, mi or m
+	GuardBandKHz *float64 `json:"guard_khz"`   // the configured conflict guard band by default
+
+	From string `json:"from"` // time window, YYYY-MM-DD; either end may be left open
+	To   string `json:"to"`
+
+	Limit int `json:"limit"` // candidates to return, 20 by default
+}
+
+// NominationCandidate is one clear channel. SeparationHz is the gap between
+// its occupied band and that of the nearest assignment in frequency within
+// the radius, or nil when the radius holds no assignment.
+type NominationCandidate struct {
+	Rank         int                  `json:"rank"`
+	Frequency    string               `json:"frequency"` // field110 form, e.g. "M225.525"
+	Hz           float64              `json:"hz"`
+	Band         OccupiedBand         `json:"band"`
+	SeparationHz *float64             `json:"separation_hz"`
+	Nearest      *NominationNeighbour `json:"nearest,omitempty"`
+}
+
+// NominationNeighbour is the existing assignment closest in frequency to a
+// candidate
+type NominationNeighbour struct {
+	MarkerID  uuid.UUID    `json:"marker_id"`
+	Serial    string       `json:"serial"`
+	Frequency string       `json:"frequency"`
+	Band      OccupiedBand `json:"band"`
+	Distance  Distance     `json:"distance"`
+}
+
+// NominationResult lists the candidates best first, with how many channels
+// were looked at and why the others were left out
+type NominationResult struct {
+	Position    Coordinate            `json:"position"`
+	BandwidthHz float64               `json:"bandwidth_hz"`
+	GuardBandHz float64               `json:"guard_band_hz"`
+	Radius      Distance              `json:"radius"`
+	From        string                `json:"from,omitempty"`
+	To          string                `json:"to,omitempty"`
+	Assignments int                   `json:"assignments"` // active assignments within the radius
+	Channels    int                   `json:"channels"`    // channels considered
+	Restricted  int                   `json:"restricted"`  // left out for overlapping a restricted frequency
+	Occupied    int                   `json:"occupied"`    // left out for an assignment on or next to them
+	Clear       int                   `json:"clear"`       // channels that passed, before the limit
+	Candidates  []NominationCandidate `json:"candidates"`
+}
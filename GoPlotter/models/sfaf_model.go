@@ -2,47 +2,377 @@
 package models
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"sfaf-plotter/emission"
+
 	"github.com/google/uuid"
 )
 
 type SFAF struct {
-	ID        uuid.UUID         `json:"id"`
-	MarkerID  uuid.UUID         `json:"marker_id"`
+	ID       uuid.UUID   `json:"id"`
+	MarkerID uuid.UUID   `json:"marker_id"`
+	Entries  []SFAFEntry `json:"entries"`
+	// Fields is the flat compatibility view of Entries keyed "field103",
+	// "field103/02", ... Keep it in sync through SetEntries/SetFields.
 	Fields    map[string]string `json:"fields"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 }
 
+// SFAFEntry is one (field number, occurrence) value of an SFAF record.
+// Occurrence 1 is the unsuffixed line ("103."), 2 is "103/02." and so on.
+type SFAFEntry struct {
+	FieldNumber string `json:"field_number"` // three digits, e.g. "103"
+	Occurrence  int    `json:"occurrence"`
+	Value       string `json:"value"`
+}
+
+// FlatKey returns the compatibility map key for the entry.
+func (e SFAFEntry) FlatKey() string {
+	if e.Occurrence <= 1 {
+		return "field" + e.FieldNumber
+	}
+	return fmt.Sprintf("field%s/%02d", e.FieldNumber, e.Occurrence)
+}
+
+// ParseSFAFFlatKey splits "field103/02" (or "103/02") into its field number
+// and occurrence.
+func ParseSFAFFlatKey(key string) (string, int, error) {
+	key = strings.TrimPrefix(key, "field")
+	fieldNumber, occurrence := key, 1
+
+	if idx := strings.Index(key, "/"); idx >= 0 {
+		fieldNumber = key[:idx]
+		n, err := strconv.Atoi(key[idx+1:])
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("invalid occurrence in SFAF key %q", key)
+		}
+		occurrence = n
+	}
+
+	if _, err := strconv.Atoi(fieldNumber); err != nil || len(fieldNumber) != 3 {
+		return "", 0, fmt.Errorf("invalid SFAF field number in key %q", key)
+	}
+	return fieldNumber, occurrence, nil
+}
+
+// SortSFAFEntries orders entries by field number, then occurrence.
+func SortSFAFEntries(entries []SFAFEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].FieldNumber != entries[j].FieldNumber {
+			return entries[i].FieldNumber < entries[j].FieldNumber
+		}
+		return entries[i].Occurrence < entries[j].Occurrence
+	})
+}
+
+// SFAFEntriesFromFields converts a flat field map into ordered entries.
+// Keys that are not SFAF field keys are dropped.
+func SFAFEntriesFromFields(fields map[string]string) []SFAFEntry {
+	entries := make([]SFAFEntry, 0, len(fields))
+	for key, value := range fields {
+		fieldNumber, occurrence, err := ParseSFAFFlatKey(key)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, SFAFEntry{FieldNumber: fieldNumber, Occurrence: occurrence, Value: value})
+	}
+	SortSFAFEntries(entries)
+	return entries
+}
+
+// SFAFFieldsFromEntries builds the flat compatibility view of entries.
+func SFAFFieldsFromEntries(entries []SFAFEntry) map[string]string {
+	fields := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		fields[entry.FlatKey()] = entry.Value
+	}
+	return fields
+}
+
+// SetEntries replaces the record's entries, keeping their order (the order
+// of the source file for imported records), and rebuilds Fields.
+func (s *SFAF) SetEntries(entries []SFAFEntry) {
+	s.Entries = entries
+	s.Fields = SFAFFieldsFromEntries(entries)
+}
+
+// SetFields replaces the record from a flat field map and rebuilds Entries.
+func (s *SFAF) SetFields(fields map[string]string) {
+	s.SetEntries(SFAFEntriesFromFields(fields))
+}
+
+// EnsureEntries fills Entries from Fields for records stored before entries
+// existed, and Fields from Entries when only entries were supplied.
+func (s *SFAF) EnsureEntries() {
+	switch {
+	case len(s.Entries) == 0 && len(s.Fields) > 0:
+		s.Entries = SFAFEntriesFromFields(s.Fields)
+	case len(s.Entries) > 0:
+		s.Fields = SFAFFieldsFromEntries(s.Entries)
+	}
+}
+
+// Value returns the first occurrence of a field ("303" or "field303").
+func (s *SFAF) Value(fieldNumber string) string {
+	values := s.Values(fieldNumber)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Values returns every occurrence of a field in occurrence order.
+func (s *SFAF) Values(fieldNumber string) []string {
+	fieldNumber = strings.TrimPrefix(fieldNumber, "field")
+	var values []string
+	for _, entry := range s.Entries {
+		if entry.FieldNumber == fieldNumber {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// SFAFFieldRows converts entries into sfaf_fields table rows for a marker.
+func SFAFFieldRows(markerID uuid.UUID, entries []SFAFEntry) []SFAFField {
+	rows := make([]SFAFField, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, SFAFField{
+			ID:               uuid.New(),
+			MarkerID:         markerID,
+			FieldNumber:      entry.FieldNumber,
+			FieldValue:       entry.Value,
+			OccurrenceNumber: entry.Occurrence,
+		})
+	}
+	return rows
+}
+
 type SFAFFormDefinition struct {
-	FieldNumber string   `json:"field_number"`
-	Label       string   `json:"label"`
-	Required    bool     `json:"required"`
-	FieldType   string   `json:"field_type"`
-	Options     []string `json:"options,omitempty"`
-	Validation  string   `json:"validation,omitempty"`
-	Help        string   `json:"help,omitempty"`
+	FieldNumber    string   `json:"field_number"`
+	Label          string   `json:"label"`
+	Required       bool     `json:"required"` // required for new (N) records
+	FieldType      string   `json:"field_type"`
+	Options        []string `json:"options,omitempty"`
+	Validation     string   `json:"validation,omitempty"`
+	Help           string   `json:"help,omitempty"`
+	MaxLength      int      `json:"max_length,omitempty"`
+	MaxOccurrences int      `json:"max_occurrences,omitempty"`
+	RequiredFor    []string `json:"required_for,omitempty"` // field010 transaction types
+}
+
+// SFAF field format classes used by the field catalogue
+const (
+	SFAFFormatText        = "text"
+	SFAFFormatCode        = "code" // one of the allowed values
+	SFAFFormatNumeric     = "numeric"
+	SFAFFormatDate        = "date" // YYYYMMDD
+	SFAFFormatFrequency   = "frequency"
+	SFAFFormatEmission    = "emission"
+	SFAFFormatPower       = "power"
+	SFAFFormatCoordinates = "coordinates"
+	SFAFFormatRadius      = "radius"
+)
+
+// SFAFFieldSpec is one field of the MCEB Pub 7 field catalogue
+type SFAFFieldSpec struct {
+	Number         string   `json:"number"` // "110"
+	Label          string   `json:"label"`
+	MaxLength      int      `json:"max_length"`
+	MaxOccurrences int      `json:"max_occurrences"`
+	Format         string   `json:"format"`
+	AllowedValues  []string `json:"allowed_values,omitempty"`
+	RequiredFor    []string `json:"required_for,omitempty"`
+	Help           string   `json:"help,omitempty"`
+}
+
+// SFAFFieldCatalogue is the versioned field catalogue loaded at startup
+type SFAFFieldCatalogue struct {
+	Version     string            `json:"version"`
+	Publication string            `json:"publication,omitempty"`
+	Formats     map[string]string `json:"formats,omitempty"`
+	Fields      []SFAFFieldSpec   `json:"fields"`
+}
+
+// FormDefinition converts a catalogue entry to the form definition served
+// to the UI.
+func (spec SFAFFieldSpec) FormDefinition() SFAFFormDefinition {
+	def := SFAFFormDefinition{
+		FieldNumber:    "field" + spec.Number,
+		Label:          spec.Label,
+		FieldType:      spec.Format,
+		Options:        spec.AllowedValues,
+		Help:           spec.Help,
+		MaxLength:      spec.MaxLength,
+		MaxOccurrences: spec.MaxOccurrences,
+		RequiredFor:    spec.RequiredFor,
+	}
+	def.Required = def.IsRequiredFor(SFAFTransactionNew)
+	return def
+}
+
+// IsRequiredFor reports whether the field must be present in a record with
+// the given transaction type (field010)
+func (def SFAFFormDefinition) IsRequiredFor(transaction string) bool {
+	for _, t := range def.RequiredFor {
+		if strings.EqualFold(t, transaction) {
+			return true
+		}
+	}
+	return false
 }
 
 // Request/Response types
 type CreateSFAFRequest struct {
 	MarkerID string            `json:"marker_id" binding:"required"`
 	Fields   map[string]string `json:"fields"`
+	Entries  []SFAFEntry       `json:"entries,omitempty"` // takes precedence over Fields
 }
 
 type UpdateSFAFRequest struct {
-	Fields map[string]string `json:"fields" binding:"required"`
+	Fields  map[string]string `json:"fields"`
+	Entries []SFAFEntry       `json:"entries,omitempty"` // takes precedence over Fields
 }
 
 type ValidateSFAFRequest struct {
-	Fields map[string]string `json:"fields" binding:"required"`
+	Fields  map[string]string `json:"fields"`
+	Entries []SFAFEntry       `json:"entries,omitempty"`  // takes precedence over Fields
+	RuleSet string            `json:"rule_set,omitempty"` // defaults to the full MCEB Pub 7 set
 }
 
 type ValidationResult struct {
-	IsValid bool                          `json:"is_valid"`
-	Errors  map[string]string             `json:"errors,omitempty"`
-	Fields  map[string]SFAFFormDefinition `json:"fields"`
+	IsValid  bool                          `json:"is_valid"`
+	Errors   map[string]string             `json:"errors,omitempty"`
+	Warnings map[string]string             `json:"warnings,omitempty"`
+	Issues   []SFAFValidationIssue         `json:"issues,omitempty"` // cross-field rule findings
+	Fields   map[string]SFAFFormDefinition `json:"fields"`
+}
+
+// SFAF validation issue severities
+const (
+	SFAFSeverityError   = "error"
+	SFAFSeverityWarning = "warning"
+)
+
+// SFAFValidationIssue is one finding of a validation rule, tied to the
+// fields it concerns.
+type SFAFValidationIssue struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`
+	Fields   []string `json:"fields"` // flat keys, e.g. "field303"
+	Message  string   `json:"message"`
+}
+
+// Import report
+type SFAFImportStatus string
+
+const (
+	SFAFImportImported SFAFImportStatus = "imported"
+	SFAFImportUpdated  SFAFImportStatus = "updated"
+	SFAFImportDeleted  SFAFImportStatus = "deleted"
+	SFAFImportSkipped  SFAFImportStatus = "skipped"
+	SFAFImportConflict SFAFImportStatus = "conflict"
+)
+
+// SFAF transaction types (field010)
+const (
+	SFAFTransactionNew     = "N"
+	SFAFTransactionModify  = "M"
+	SFAFTransactionDelete  = "D"
+	SFAFTransactionRenewal = "R"
+)
+
+// SFAFImportMatch says how a record compares to the existing assignment with
+// the same agency serial (field102).
+type SFAFImportMatch string
+
+const (
+	SFAFMatchNew       SFAFImportMatch = "new"
+	SFAFMatchUnchanged SFAFImportMatch = "unchanged"
+	SFAFMatchModified  SFAFImportMatch = "modified"
+	SFAFMatchDelete    SFAFImportMatch = "delete" // a D record removes the assignment
+)
+
+// SFAFImportRecord reports what happened (or, in a preview, what would
+// happen) to one record of an imported file.
+type SFAFImportRecord struct {
+	Record      int                   `json:"record"` // 1-based position in the file
+	StartLine   int                   `json:"start_line"`
+	EndLine     int                   `json:"end_line"`
+	Serial      string                `json:"serial"`                // field102
+	Transaction string                `json:"transaction,omitempty"` // field010
+	Status      SFAFImportStatus      `json:"status,omitempty"`
+	Match       SFAFImportMatch       `json:"match,omitempty"`
+	Reason      string                `json:"reason,omitempty"`
+	MarkerID    *uuid.UUID            `json:"marker_id,omitempty"`
+	Changes     []SFAFFieldChange     `json:"changes,omitempty"`
+	Issues      []SFAFValidationIssue `json:"issues,omitempty"` // rule errors and warnings
+
+	// Nearby stored assignments the record may interfere with
+	FrequencyConflicts []FrequencyConflict `json:"frequency_conflicts,omitempty"`
+}
+
+// SFAFFieldChange is one field-level difference between an existing
+// assignment and an imported record.
+type SFAFFieldChange struct {
+	Key         string `json:"key"` // flat key, e.g. "field103/02"
+	FieldNumber string `json:"field_number"`
+	Occurrence  int    `json:"occurrence"`
+	Change      string `json:"change"` // "added", "removed" or "changed"
+	Old         string `json:"old,omitempty"`
+	New         string `json:"new,omitempty"`
+}
+
+// SFAFImportResult is the outcome of a committed import.
+type SFAFImportResult struct {
+	Markers     []Marker           `json:"markers"`
+	SFAFRecords []SFAF             `json:"sfaf_records"`
+	Records     []SFAFImportRecord `json:"records"`
+	DeletedIDs  []uuid.UUID        `json:"deleted_marker_ids"`
+	Imported    int                `json:"imported_count"`
+	Updated     int                `json:"updated_count"`
+	Deleted     int                `json:"deleted_count"`
+	Skipped     int                `json:"skipped_count"`
+	Conflicts   int                `json:"conflict_count"`
+}
+
+// DiffSFAFEntries lists the changes that turn before into after, ordered by
+// field number and occurrence.
+func DiffSFAFEntries(before, after []SFAFEntry) []SFAFFieldChange {
+	oldFields := SFAFFieldsFromEntries(before)
+	newFields := SFAFFieldsFromEntries(after)
+
+	var changes []SFAFFieldChange
+	for _, entry := range after {
+		key := entry.FlatKey()
+		oldValue, exists := oldFields[key]
+		switch {
+		case !exists:
+			changes = append(changes, SFAFFieldChange{Key: key, FieldNumber: entry.FieldNumber, Occurrence: entry.Occurrence, Change: "added", New: entry.Value})
+		case oldValue != entry.Value:
+			changes = append(changes, SFAFFieldChange{Key: key, FieldNumber: entry.FieldNumber, Occurrence: entry.Occurrence, Change: "changed", Old: oldValue, New: entry.Value})
+		}
+	}
+	for _, entry := range before {
+		key := entry.FlatKey()
+		if _, exists := newFields[key]; !exists {
+			changes = append(changes, SFAFFieldChange{Key: key, FieldNumber: entry.FieldNumber, Occurrence: entry.Occurrence, Change: "removed", Old: entry.Value})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].FieldNumber != changes[j].FieldNumber {
+			return changes[i].FieldNumber < changes[j].FieldNumber
+		}
+		return changes[i].Occurrence < changes[j].Occurrence
+	})
+	return changes
 }
 
 // Export format
@@ -52,4 +382,58 @@ const (
 	SFAFExportCSV  SFAFExportFormat = "csv"
 	SFAFExportJSON SFAFExportFormat = "json"
 	SFAFExportXML  SFAFExportFormat = "xml"
+	SFAFExportText SFAFExportFormat = "sfaf" // MCEB Pub 7 "NNN.  value" text
+	SFAFExportZip  SFAFExportFormat = "zip"  // one file per record
 )
+
+// SFAFExportFilter selects records for a bulk export. Zero values match
+// everything.
+type SFAFExportFilter struct {
+	Agency     string       // field200, case-insensitive
+	State      string       // field300, case-insensitive
+	MarkerType string       // marker_type of the linked marker
+	MinFreqMHz *float64     // field110
+	MaxFreqMHz *float64     // field110
+	Bounds     *BoundingBox // transmitter position (linked marker)
+	From       *time.Time   // assignment date (field107), inclusive
+	To         *time.Time   // assignment date (field107), inclusive
+}
+
+// SFAFEmission is one decoded field114 occurrence. Error is set (and the
+// designator left empty) when the value is not a valid designator.
+type SFAFEmission struct {
+	Occurrence int `json:"occurrence"`
+	emission.Designator
+	Error string `json:"error,omitempty"`
+}
+
+// Emissions decodes every field114 (emission designator) occurrence
+func (s *SFAF) Emissions() []SFAFEmission {
+	var emissions []SFAFEmission
+	for _, entry := range s.Entries {
+		if entry.FieldNumber != "114" {
+			continue
+		}
+		decoded := SFAFEmission{Occurrence: entry.Occurrence}
+		if designator, err := emission.Parse(entry.Value); err != nil {
+			decoded.Designator.Raw = entry.Value
+			decoded.Error = err.Error()
+		} else {
+			decoded.Designator = designator
+		}
+		emissions = append(emissions, decoded)
+	}
+	return emissions
+}
+
+// NecessaryBandwidthHz is the widest necessary bandwidth of the record's
+// valid emission designators, or 0 when it has none.
+func (s *SFAF) NecessaryBandwidthHz() float64 {
+	widest := 0.0
+	for _, decoded := range s.Emissions() {
+		if decoded.Error == "" && decoded.BandwidthHz > widest {
+			widest = decoded.BandwidthHz
+		}
+	}
+	return widest
+}
@@ -0,0 +1,95 @@
+// power/power.go
+package power
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// "W20", "K1", "M2.5": unit prefix then the power
+var powerPattern = regexp.MustCompile(`^([WKMG])(\d+(?:\.\d+)?)$`)
+
+// Unit multipliers for the MCEB Pub 7 field115 prefixes. Note that M is
+// megawatts, not milliwatts.
+var unitWatts = map[string]float64{"W": 1, "K": 1e3, "M": 1e6, "G": 1e9}
+
+// Power is a parsed transmitter power (SFAF field115)
+type Power struct {
+	Raw   string  `json:"raw"`
+	Watts float64 `json:"watts"`
+	DBW   float64 `json:"dbw"`
+	DBm   float64 `json:"dbm"`
+}
+
+// Parse reads a field115 power: W (watts), K (kilowatts), M (megawatts) or
+// G (gigawatts) followed by a number, e.g. "W20" or "K1".
+func Parse(value string) (Power, error) {
+	raw := strings.TrimSpace(value)
+	match := powerPattern.FindStringSubmatch(strings.ToUpper(raw))
+	if match == nil {
+		return Power{}, fmt.Errorf("invalid power %q (expected W, K, M or G followed by a number, e.g. W20)", raw)
+	}
+
+	number, err := strconv.ParseFloat(match[2], 64)
+	if err != nil || number <= 0 {
+		return Power{}, fmt.Errorf("invalid power %q: must be greater than zero", raw)
+	}
+
+	p := FromWatts(number * unitWatts[match[1]])
+	p.Raw = raw
+	return p, nil
+}
+
+// FromWatts fills in the dBW and dBm equivalents of a power in watts
+func FromWatts(watts float64) Power {
+	dbw := 10 * math.Log10(watts)
+	return Power{Watts: watts, DBW: round(dbw), DBm: round(dbw + 30)}
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Class is a named transmitter power range
+type Class string
+
+const (
+	ClassLow      Class = "low"       // below 10 W
+	ClassMedium   Class = "medium"    // 10 W to below 1 kW
+	ClassHigh     Class = "high"      // 1 kW to below 100 kW
+	ClassVeryHigh Class = "very-high" // 100 kW and above
+)
+
+// ClassRange returns the [min, max) watt range of a class; max is +Inf for
+// the top class.
+func ClassRange(class Class) (float64, float64, error) {
+	switch class {
+	case ClassLow:
+		return 0, 10, nil
+	case ClassMedium:
+		return 10, 1e3, nil
+	case ClassHigh:
+		return 1e3, 1e5, nil
+	case ClassVeryHigh:
+		return 1e5, math.Inf(1), nil
+	default:
+		return 0, 0, fmt.Errorf("unknown power class %q (use low, medium, high or very-high)", class)
+	}
+}
+
+// ClassOf returns the class a power in watts falls into
+func ClassOf(watts float64) Class {
+	switch {
+	case watts < 10:
+		return ClassLow
+	case watts < 1e3:
+		return ClassMedium
+	case watts < 1e5:
+		return ClassHigh
+	default:
+		return ClassVeryHigh
+	}
+}
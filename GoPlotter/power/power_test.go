@@ -0,0 +1,81 @@
+// power/power_test.go
+package power
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value           string
+		watts, dbw, dbm float64
+	}{
+		{"W20", 20, 13.01, 43.01},
+		{"W1", 1, 0, 30},
+		{"W0.5", 0.5, -3.01, 26.99},
+		{"K1", 1e3, 30, 60},
+		{"k2.5", 2.5e3, 33.98, 63.98},
+		{"M2", 2e6, 63.01, 93.01},
+		{"G1", 1e9, 90, 120},
+		{" W100 ", 100, 20, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			p, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if p.Watts != tt.watts || p.DBW != tt.dbw || p.DBm != tt.dbm {
+				t.Errorf("Parse(%q) = %+v, want %v W, %v dBW, %v dBm", tt.value, p, tt.watts, tt.dbw, tt.dbm)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{"", "20", "X20", "W", "W0", "W-5", "W1.", "W1.2.3", "20W", "mW5"} {
+		t.Run(value, func(t *testing.T) {
+			if p, err := Parse(value); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", value, p)
+			}
+		})
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		watts float64
+		class Class
+	}{
+		{0.1, ClassLow},
+		{9.99, ClassLow},
+		{10, ClassMedium},
+		{999, ClassMedium},
+		{1e3, ClassHigh},
+		{99999, ClassHigh},
+		{1e5, ClassVeryHigh},
+		{1e9, ClassVeryHigh},
+	}
+
+	for _, tt := range tests {
+		if got := ClassOf(tt.watts); got != tt.class {
+			t.Errorf("ClassOf(%v) = %q, want %q", tt.watts, got, tt.class)
+		}
+		low, high, err := ClassRange(tt.class)
+		if err != nil {
+			t.Fatalf("ClassRange(%q): %v", tt.class, err)
+		}
+		if tt.watts < low || tt.watts >= high {
+			t.Errorf("%v W is outside ClassRange(%q) = [%v, %v)", tt.watts, tt.class, low, high)
+		}
+	}
+
+	if _, high, _ := ClassRange(ClassVeryHigh); !math.IsInf(high, 1) {
+		t.Errorf("ClassRange(%q) max = %v, want +Inf", ClassVeryHigh, high)
+	}
+	if _, _, err := ClassRange("extreme"); err == nil {
+		t.Errorf("ClassRange(%q) succeeded, want an error", "extreme")
+	}
+}
@@ -21,13 +21,15 @@ func NewMarkerRepository(db *sqlx.DB) *MarkerRepository {
 
 func (r *MarkerRepository) Create(marker *models.Marker) error {
 	query := `
-        INSERT INTO markers (id, serial, latitude, longitude, frequency, notes, marker_type, is_draggable)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        INSERT INTO markers (id, serial, latitude, longitude, frequency, notes, marker_type, is_draggable,
+                             frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
         RETURNING created_at, updated_at`
 
 	err := r.db.QueryRow(query,
 		marker.ID, marker.Serial, marker.Latitude, marker.Longitude,
 		marker.Frequency, marker.Notes, marker.MarkerType, marker.IsDraggable,
+		marker.FrequencyHz, marker.FreqMinHz, marker.FreqMaxHz, marker.Power, marker.PowerWatts,
 	).Scan(&marker.CreatedAt, &marker.UpdatedAt)
 
 	return err
@@ -35,7 +37,8 @@ func (r *MarkerRepository) Create(marker *models.Marker) error {
 
 func (r *MarkerRepository) GetAll() ([]models.Marker, error) {
 	query := `
-        SELECT id, serial, latitude, longitude, frequency, notes, 
+        SELECT id, serial, latitude, longitude, frequency, notes,
+               frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts,
                marker_type, is_draggable, created_at, updated_at
         FROM markers
         ORDER BY created_at DESC`
@@ -45,6 +48,100 @@ func (r *MarkerRepository) GetAll() ([]models.Marker, error) {
 	return markers, err
 }
 
+// Find returns markers matching filter. Frequency bounds match any marker
+// whose frequency or band reaches into the range; power bounds are
+// [min, max) in watts. Markers without a parsed value are left out of the
+// queries that need it.
+func (r *MarkerRepository) Find(filter models.MarkerFilter) ([]models.Marker, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.MinFreqHz != nil {
+		args = append(args, *filter.MinFreqHz)
+		where = append(where, fmt.Sprintf("frequency_max_hz >= $%d", len(args)))
+	}
+	if filter.MaxFreqHz != nil {
+		args = append(args, *filter.MaxFreqHz)
+		where = append(where, fmt.Sprintf("frequency_min_hz <= $%d", len(args)))
+	}
+
+	if filter.MinPowerW != nil {
+		args = append(args, *filter.MinPowerW)
+		where = append(where, fmt.Sprintf("power_watts >= $%d", len(args)))
+	}
+	if filter.MaxPowerW != nil {
+		args = append(args, *filter.MaxPowerW)
+		where = append(where, fmt.Sprintf("power_watts < $%d", len(args)))
+	}
+
+	if filter.IDs != nil {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		args = append(args, pq.Array(ids))
+		where = append(where, fmt.Sprintf("id = ANY($%d::uuid[])", len(args)))
+	}
+
+	for _, box := range filter.Bounds {
+		args = append(args, box.South, box.North, box.West, box.East)
+		n := len(args)
+		where = append(where, fmt.Sprintf("latitude BETWEEN $%d AND $%d", n-3, n-2))
+		if box.West <= box.East {
+			where = append(where, fmt.Sprintf("longitude BETWEEN $%d AND $%d", n-1, n))
+		} else {
+			where = append(where, fmt.Sprintf("(longitude >= $%d OR longitude <= $%d)", n-1, n))
+		}
+	}
+
+	query := `
+        SELECT id, serial, latitude, longitude, frequency, notes,
+               frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts,
+               marker_type, is_draggable, created_at, updated_at
+        FROM markers`
+	if len(where) > 0 {
+		query += "\n        WHERE " + strings.Join(where, " AND ")
+	}
+
+	switch filter.SortBy {
+	case "frequency":
+		query += "\n        ORDER BY frequency_hz ASC NULLS LAST, frequency_max_hz ASC, serial"
+	default:
+		query += "\n        ORDER BY created_at DESC"
+	}
+	if filter.Limit > 0 {
+		query += fmt.Sprintf("\n        LIMIT %d", filter.Limit)
+	}
+
+	var markers []models.Marker
+	err := r.db.Select(&markers, query, args...)
+	return markers, err
+}
+
+// GetPositions returns every marker with only its ID, latitude and
+// longitude, for building the spatial index
+func (r *MarkerRepository) GetPositions() ([]models.Marker, error) {
+	var markers []models.Marker
+	err := r.db.Select(&markers, `SELECT id, latitude, longitude FROM markers`)
+	return markers, err
+}
+
+// GetBySerials returns markers whose serial is in serials, most recently
+// updated first
+func (r *MarkerRepository) GetBySerials(serials []string) ([]models.Marker, error) {
+	query := `
+        SELECT id, serial, latitude, longitude, frequency, notes,
+               frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts,
+               marker_type, is_draggable, created_at, updated_at
+        FROM markers
+        WHERE serial = ANY($1)
+        ORDER BY updated_at DESC`
+
+	var markers []models.Marker
+	err := r.db.Select(&markers, query, pq.Array(serials))
+	return markers, err
+}
+
 // Repository method building dynamic UPDATE queries
 func (r *MarkerRepository) buildUpdateClause(req models.UpdateMarkerRequest) []string {
 	var setParts []string
@@ -83,6 +180,7 @@ func (r *MarkerRepository) buildUpdateClause(req models.UpdateMarkerRequest) []s
 func (r *MarkerRepository) GetByID(id uuid.UUID) (*models.Marker, error) {
 	query := `
         SELECT id, serial, latitude, longitude, frequency, notes,
+               frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts,
                marker_type, is_draggable, created_at, updated_at
         FROM markers
         WHERE id = $1`
@@ -197,6 +295,101 @@ func (r *MarkerRepository) getSFAFFieldsByMarkerID(markerID uuid.UUID) ([]models
 	return fields, err
 }
 
+// MarkerBatch groups marker writes that must land together, e.g. one SFAF
+// file import. SFAFFields replaces the sfaf_fields rows of the listed
+// markers, one row per (field number, occurrence); an empty list removes
+// them.
+type MarkerBatch struct {
+	Create     []*models.Marker
+	Update     []*models.Marker
+	Delete     []uuid.UUID
+	SFAFFields map[uuid.UUID][]models.SFAFField
+}
+
+// ApplyBatch writes a MarkerBatch in one transaction. beforeCommit runs
+// inside the transaction so callers can tie other writes to it; if it or any
+// statement fails, nothing is written.
+func (r *MarkerRepository) ApplyBatch(batch MarkerBatch, beforeCommit func() error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+        INSERT INTO markers (id, serial, latitude, longitude, frequency, notes, marker_type, is_draggable,
+                             frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING created_at, updated_at`
+
+	for _, marker := range batch.Create {
+		err := tx.QueryRow(insertQuery,
+			marker.ID, marker.Serial, marker.Latitude, marker.Longitude,
+			marker.Frequency, marker.Notes, marker.MarkerType, marker.IsDraggable,
+			marker.FrequencyHz, marker.FreqMinHz, marker.FreqMaxHz, marker.Power, marker.PowerWatts,
+		).Scan(&marker.CreatedAt, &marker.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("marker %s: %w", marker.Serial, err)
+		}
+	}
+
+	updateQuery := `
+        UPDATE markers
+        SET serial = $2, latitude = $3, longitude = $4, frequency = $5, notes = $6,
+            frequency_hz = $7, frequency_min_hz = $8, frequency_max_hz = $9,
+            power = $10, power_watts = $11,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING created_at, updated_at`
+
+	for _, marker := range batch.Update {
+		err := tx.QueryRow(updateQuery,
+			marker.ID, marker.Serial, marker.Latitude, marker.Longitude,
+			marker.Frequency, marker.Notes,
+			marker.FrequencyHz, marker.FreqMinHz, marker.FreqMaxHz, marker.Power, marker.PowerWatts,
+		).Scan(&marker.CreatedAt, &marker.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("marker %s: %w", marker.Serial, err)
+		}
+	}
+
+	for _, markerID := range batch.Delete {
+		if _, err := tx.Exec(`DELETE FROM markers WHERE id = $1`, markerID); err != nil {
+			return fmt.Errorf("marker %s: %w", markerID, err)
+		}
+	}
+
+	for markerID, fields := range batch.SFAFFields {
+		if _, err := tx.Exec(`DELETE FROM sfaf_fields WHERE marker_id = $1`, markerID); err != nil {
+			return err
+		}
+		if err := insertSFAFFields(tx, markerID, fields); err != nil {
+			return err
+		}
+	}
+
+	if beforeCommit != nil {
+		if err := beforeCommit(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertSFAFFields(tx *sqlx.Tx, markerID uuid.UUID, fields []models.SFAFField) error {
+	query := `
+        INSERT INTO sfaf_fields (id, marker_id, field_number, field_value, occurrence_number)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	for _, field := range fields {
+		if _, err := tx.Exec(query, field.ID, markerID, field.FieldNumber, field.FieldValue, field.OccurrenceNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // IRAC Notes management
 func (r *MarkerRepository) AddIRACNote(markerID uuid.UUID, noteCode string, fieldNumber, occurrenceNumber int) error {
 	query := `
@@ -215,3 +408,68 @@ func (r *MarkerRepository) RemoveIRACNote(markerID uuid.UUID, noteCode string, f
 	_, err := r.db.Exec(query, markerID, noteCode, fieldNumber, occurrenceNumber)
 	return err
 }
+
+// EnsureParsedColumns adds the parsed frequency and power columns to
+// markers on databases created before they existed.
+func (r *MarkerRepository) EnsureParsedColumns() error {
+	statements := []string{
+		`ALTER TABLE markers ADD COLUMN IF NOT EXISTS frequency_hz DOUBLE PRECISION`,
+		`ALTER TABLE markers ADD COLUMN IF NOT EXISTS frequency_min_hz DOUBLE PRECISION`,
+		`ALTER TABLE markers ADD COLUMN IF NOT EXISTS frequency_max_hz DOUBLE PRECISION`,
+		`ALTER TABLE markers ADD COLUMN IF NOT EXISTS power TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE markers ADD COLUMN IF NOT EXISTS power_watts DOUBLE PRECISION`,
+		`CREATE INDEX IF NOT EXISTS idx_markers_frequency_hz ON markers (frequency_hz)`,
+		`CREATE INDEX IF NOT EXISTS idx_markers_power_watts ON markers (power_watts)`,
+	}
+	for _, statement := range statements {
+		if _, err := r.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyPowerFromSFAFFields fills the raw power of markers that have none from
+// the first field115 occurrence in sfaf_fields.
+func (r *MarkerRepository) CopyPowerFromSFAFFields() (int64, error) {
+	query := `
+        UPDATE markers m
+        SET power = sf.field_value
+        FROM sfaf_fields sf
+        WHERE sf.marker_id = m.id
+          AND sf.field_number = '115' AND sf.occurrence_number = 1
+          AND m.power = '' AND sf.field_value <> ''`
+
+	result, err := r.db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetUnparsedMarkers returns markers that have a raw frequency or power but
+// no parsed value for it yet.
+func (r *MarkerRepository) GetUnparsedMarkers() ([]models.Marker, error) {
+	query := `
+        SELECT id, serial, latitude, longitude, frequency, notes,
+               frequency_hz, frequency_min_hz, frequency_max_hz, power, power_watts,
+               marker_type, is_draggable, created_at, updated_at
+        FROM markers
+        WHERE (frequency_hz IS NULL AND COALESCE(frequency, '') <> '')
+           OR (power_watts IS NULL AND power <> '')`
+
+	var markers []models.Marker
+	err := r.db.Select(&markers, query)
+	return markers, err
+}
+
+// UpdateParsedColumns stores a marker's parsed frequency and power without
+// touching updated_at.
+func (r *MarkerRepository) UpdateParsedColumns(marker *models.Marker) error {
+	query := `
+        UPDATE markers
+        SET frequency_hz = $2, frequency_min_hz = $3, frequency_max_hz = $4, power_watts = $5
+        WHERE id = $1`
+	_, err := r.db.Exec(query, marker.ID, marker.FrequencyHz, marker.FreqMinHz, marker.FreqMaxHz, marker.PowerWatts)
+	return err
+}
@@ -0,0 +1,240 @@
+// conflict_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sfaf-plotter/frequency"
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAssignmentNotFound is returned when the marker or SFAF record to
+	// check does not exist
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	// ErrNoFrequency is returned for an assignment without a valid field110
+	// frequency, which cannot conflict with anything
+	ErrNoFrequency = errors.New("assignment has no valid frequency")
+	// ErrInvalidConflictSettings wraps a bad guard band or distance
+	ErrInvalidConflictSettings = errors.New("invalid conflict settings")
+)
+
+// ConflictService finds existing assignments that may interfere with a new
+// or changed one: those nearby whose occupied bands overlap (co-channel) or
+// are within a guard band (adjacent-channel).
+type ConflictService struct {
+	storage       storage.Storage
+	markerService *MarkerService
+	settings      models.ConflictSettings
+}
+
+func NewConflictService(storage storage.Storage, markerService *MarkerService, settings models.ConflictSettings) *ConflictService {
+	return &ConflictService{
+		storage:       storage,
+		markerService: markerService,
+		settings:      settings,
+	}
+}
+
+// ParseConflictSettings reads a guard band in kHz and a distance in unit
+// (km, nm, mi or m). Empty values keep those of defaults.
+func ParseConflictSettings(guardKHz, distance, unit string, defaults models.ConflictSettings) (models.ConflictSettings, error) {
+	settings := defaults
+	if text := strings.TrimSpace(guardKHz); text != "" {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil || value < 0 || math.IsInf(value, 0) {
+			return settings, fmt.Errorf("%w: guard band %q must be zero or more kHz", ErrInvalidConflictSettings, guardKHz)
+		}
+		settings.GuardBandHz = value * frequency.KHz
+	}
+	if text := strings.TrimSpace(distance); text != "" {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil || value <= 0 || math.IsInf(value, 0) {
+			return settings, fmt.Errorf("%w: distance %q must be greater than zero", ErrInvalidConflictSettings, distance)
+		}
+		if settings.DistanceM, err = DistanceToMeters(value, unit); err != nil {
+			return settings, fmt.Errorf("%w: %v", ErrInvalidConflictSettings, err)
+		}
+	}
+	return settings, nil
+}
+
+// Settings are the guard band and distance used when a check is not given
+// its own
+func (cs *ConflictService) Settings() models.ConflictSettings {
+	return cs.settings
+}
+
+// OccupiedBand works out the spectrum an assignment uses from field110 and
+// field114 of its SFAF record, or from the marker's frequency when the
+// record is missing or has no field110
+func OccupiedBand(marker *models.Marker, sfaf *models.SFAF) (models.OccupiedBand, error) {
+	value := marker.Frequency
+	bandwidth := 0.0
+	if sfaf != nil {
+		if field110 := strings.TrimSpace(sfaf.Value("110")); field110 != "" {
+			value = field110
+		}
+		bandwidth = sfaf.NecessaryBandwidthHz()
+	}
+	if strings.TrimSpace(value) == "" {
+		return models.OccupiedBand{}, ErrNoFrequency
+	}
+
+	f, err := frequency.Parse(value)
+	if err != nil {
+		return models.OccupiedBand{}, fmt.Errorf("%w: %v", ErrNoFrequency, err)
+	}
+	return models.OccupiedBand{
+		MinHz:       f.MinHz - bandwidth/2,
+		MaxHz:       f.MaxHz + bandwidth/2,
+		CenterHz:    (f.MinHz + f.MaxHz) / 2,
+		BandwidthHz: bandwidth,
+	}, nil
+}
+
+// CheckMarker checks the assignment of a stored marker
+func (cs *ConflictService) CheckMarker(id string, settings models.ConflictSettings) (*models.ConflictReport, error) {
+	response, err := cs.markerService.GetMarker(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: marker %s", ErrAssignmentNotFound, id)
+	}
+
+	sfaf, _ := cs.storage.GetSFAFByMarkerID(id) // a marker may have no record yet
+	return cs.CheckAssignment(response.Marker, sfaf, settings)
+}
+
+// CheckSFAF checks the assignment of a stored SFAF record at its marker
+func (cs *ConflictService) CheckSFAF(id string, settings models.ConflictSettings) (*models.ConflictReport, error) {
+	sfaf, err := cs.storage.GetSFAF(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: SFAF %s", ErrAssignmentNotFound, id)
+	}
+
+	response, err := cs.markerService.GetMarker(sfaf.MarkerID.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: marker %s of SFAF %s", ErrAssignmentNotFound, sfaf.MarkerID, id)
+	}
+	return cs.CheckAssignment(response.Marker, sfaf, settings)
+}
+
+// CheckAssignment lists the stored assignments within settings.DistanceM of
+// marker whose occupied bands overlap its own or are at most
+// settings.GuardBandHz away, ranked. marker and sfaf need not be saved yet;
+// a stored assignment with the marker's ID is not compared with itself.
+func (cs *ConflictService) CheckAssignment(marker *models.Marker, sfaf *models.SFAF, settings models.ConflictSettings) (*models.ConflictReport, error) {
+	band, err := OccupiedBand(marker, sfaf)
+	if err != nil {
+		return nil, err
+	}
+	if settings.DistanceM <= 0 || settings.GuardBandHz < 0 {
+		return nil, fmt.Errorf("%w: distance must be greater than zero and guard band zero or more", ErrInvalidConflictSettings)
+	}
+
+	nearby, err := cs.markerService.FindMarkers(models.MarkerFilter{
+		Near:    &models.Coordinate{Lat: marker.Latitude, Lng: marker.Longitude},
+		RadiusM: settings.DistanceM,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConflictReport{
+		MarkerID:  marker.ID,
+		Band:      band,
+		Settings:  settings,
+		Conflicts: cs.conflictsAmong(band, marker.ID, nearby.Markers, settings),
+	}, nil
+}
+
+// conflictsAmong compares band with the nearby assignments, other than the
+// one with markerID, and returns the conflicts ranked
+func (cs *ConflictService) conflictsAmong(band models.OccupiedBand, markerID uuid.UUID, nearby []models.Marker, settings models.ConflictSettings) []models.FrequencyConflict {
+	conflicts := []models.FrequencyConflict{}
+	for i := range nearby {
+		other := &nearby[i]
+		if other.ID == markerID {
+			continue
+		}
+		if conflict, ok := cs.compare(band, other, settings); ok {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	rankConflicts(conflicts)
+	return conflicts
+}
+
+// compare tests one nearby assignment against band. Assignments without a
+// valid frequency are skipped.
+func (cs *ConflictService) compare(band models.OccupiedBand, other *models.Marker, settings models.ConflictSettings) (models.FrequencyConflict, bool) {
+	var sfafID *uuid.UUID
+	sfaf, err := cs.storage.GetSFAFByMarkerID(other.ID.String())
+	if err != nil {
+		sfaf = nil
+	} else {
+		sfafID = &sfaf.ID
+	}
+
+	otherBand, err := OccupiedBand(other, sfaf)
+	if err != nil {
+		return models.FrequencyConflict{}, false
+	}
+
+	gap := math.Max(otherBand.MinHz-band.MaxHz, band.MinHz-otherBand.MaxHz)
+	conflict := models.FrequencyConflict{
+		MarkerID:     other.ID,
+		SFAFID:       sfafID,
+		Serial:       other.Serial,
+		Frequency:    other.Frequency,
+		Band:         otherBand,
+		SeparationHz: math.Abs(otherBand.CenterHz - band.CenterHz),
+	}
+	if sfaf != nil && strings.TrimSpace(sfaf.Value("110")) != "" {
+		conflict.Frequency = sfaf.Value("110")
+	}
+	if other.Distance != nil {
+		conflict.Distance = *other.Distance
+	}
+
+	switch {
+	case gap <= 0:
+		conflict.Type = models.ConflictCoChannel
+		conflict.OverlapHz = math.Min(band.MaxHz, otherBand.MaxHz) - math.Max(band.MinHz, otherBand.MinHz)
+	case gap <= settings.GuardBandHz:
+		conflict.Type = models.ConflictAdjacentChannel
+		conflict.GapHz = gap
+	default:
+		return models.FrequencyConflict{}, false
+	}
+	return conflict, true
+}
+
+// rankConflicts orders co-channel conflicts before adjacent-channel ones,
+// then nearest first, then closest in frequency, and numbers them from 1
+func rankConflicts(conflicts []models.FrequencyConflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.Type != b.Type {
+			return a.Type == models.ConflictCoChannel
+		}
+		if a.Distance.Meters != b.Distance.Meters {
+			return a.Distance.Meters < b.Distance.Meters
+		}
+		if a.GapHz != b.GapHz {
+			return a.GapHz < b.GapHz
+		}
+		return a.SeparationHz < b.SeparationHz
+	})
+	for i := range conflicts {
+		conflicts[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,215 @@
+// conflict_service_test.go
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+
+	"github.com/google/uuid"
+)
+
+func TestOccupiedBand(t *testing.T) {
+	tests := []struct {
+		name         string
+		frequency    string   // marker frequency
+		sfaf         []string // record entries, nil for no record
+		min, max, bw float64
+		err          error
+	}{
+		{
+			name:      "marker frequency without a record",
+			frequency: "M225.5",
+			min:       225.5e6, max: 225.5e6,
+		},
+		{
+			name:      "field110 and field114",
+			frequency: "M300",
+			sfaf:      []string{"110 K225500", "114 16K0F3E"},
+			min:       225.492e6, max: 225.508e6, bw: 16e3,
+		},
+		{
+			name:      "widest emission",
+			frequency: "M225.5",
+			sfaf:      []string{"114 16K0F3E", "114 25K0F3E"},
+			min:       225.4875e6, max: 225.5125e6, bw: 25e3,
+		},
+		{
+			name:      "band widened on both sides",
+			frequency: "M225-M226",
+			sfaf:      []string{"114 16K0F3E"},
+			min:       224.992e6, max: 226.008e6, bw: 16e3,
+		},
+		{
+			name:      "blank field110 keeps the marker frequency",
+			frequency: "K4551.5",
+			sfaf:      []string{"110  "},
+			min:       4551.5e3, max: 4551.5e3,
+		},
+		{
+			name: "no frequency",
+			sfaf: []string{"114 16K0F3E"},
+			err:  ErrNoFrequency,
+		},
+		{
+			name:      "unreadable frequency",
+			frequency: "X12",
+			err:       ErrNoFrequency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sfaf *models.SFAF
+			if tt.sfaf != nil {
+				sfaf = sfafRecord(tt.sfaf...)
+			}
+			band, err := OccupiedBand(&models.Marker{Frequency: tt.frequency}, sfaf)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OccupiedBand: %v", err)
+			}
+			if math.Abs(band.MinHz-tt.min) > 1e-3 || math.Abs(band.MaxHz-tt.max) > 1e-3 || band.BandwidthHz != tt.bw {
+				t.Errorf("band %.3f-%.3f Hz (%.0f Hz), want %.3f-%.3f Hz (%.0f Hz)",
+					band.MinHz, band.MaxHz, band.BandwidthHz, tt.min, tt.max, tt.bw)
+			}
+		})
+	}
+}
+
+func TestConflictsAmong(t *testing.T) {
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	cs := NewConflictService(store, nil, models.ConflictSettings{})
+	settings := models.ConflictSettings{GuardBandHz: 25e3, DistanceM: 50e3}
+
+	// The checked assignment occupies 225.492-225.508 MHz
+	self := uuid.New()
+	band, err := OccupiedBand(&models.Marker{Frequency: "M225.5"}, sfafRecord("114 16K0F3E"))
+	if err != nil {
+		t.Fatalf("OccupiedBand: %v", err)
+	}
+
+	nearby := func(serial, frequency string, km float64) models.Marker {
+		return models.Marker{ID: uuid.New(), Serial: serial, Frequency: frequency, Distance: &models.Distance{Meters: km * 1000}}
+	}
+	markers := []models.Marker{
+		{ID: self, Serial: "SELF", Frequency: "M225.5"},
+		nearby("GAP 12K", "K225520", 10),
+		nearby("RECORD", "M300", 30), // field110 below wins
+		nearby("GAP 25K", "K225533", 5),
+		nearby("GAP 26K", "K225534", 1),
+		nearby("NO FREQUENCY", "", 1),
+		nearby("INSIDE", "K225500", 30),
+		nearby("GAP 7K", "K225485", 10),
+	}
+	record := sfafRecord("110 M225.51", "114 16K0F3E")
+	record.ID, record.MarkerID = uuid.New(), markers[2].ID
+	if err := store.SaveSFAF(record); err != nil {
+		t.Fatalf("SaveSFAF: %v", err)
+	}
+
+	want := []struct {
+		serial    string
+		kind      models.ConflictType
+		gapHz     float64
+		overlapHz float64
+	}{
+		{"INSIDE", models.ConflictCoChannel, 0, 0},
+		{"RECORD", models.ConflictCoChannel, 0, 6e3},
+		{"GAP 25K", models.ConflictAdjacentChannel, 25e3, 0},
+		{"GAP 7K", models.ConflictAdjacentChannel, 7e3, 0},
+		{"GAP 12K", models.ConflictAdjacentChannel, 12e3, 0},
+	}
+
+	conflicts := cs.conflictsAmong(band, self, markers, settings)
+	if len(conflicts) != len(want) {
+		t.Fatalf("%d conflicts %+v, want %d", len(conflicts), conflicts, len(want))
+	}
+	for i, conflict := range conflicts {
+		w := want[i]
+		if conflict.Rank != i+1 || conflict.Serial != w.serial || conflict.Type != w.kind ||
+			math.Abs(conflict.GapHz-w.gapHz) > 1e-3 || math.Abs(conflict.OverlapHz-w.overlapHz) > 1e-3 {
+			t.Errorf("conflict %d: %d %s %s gap %.3f overlap %.3f; want %s %s gap %.0f overlap %.0f", i+1,
+				conflict.Rank, conflict.Serial, conflict.Type, conflict.GapHz, conflict.OverlapHz, w.serial, w.kind, w.gapHz, w.overlapHz)
+		}
+	}
+
+	recorded := conflicts[1]
+	if recorded.SFAFID == nil || *recorded.SFAFID != record.ID || recorded.Frequency != "M225.51" || recorded.Band.BandwidthHz != 16e3 {
+		t.Errorf("conflict with a record: SFAF %v, frequency %q, bandwidth %.0f", recorded.SFAFID, recorded.Frequency, recorded.Band.BandwidthHz)
+	}
+	if conflicts[0].SFAFID != nil {
+		t.Errorf("conflict without a record has SFAF %v", conflicts[0].SFAFID)
+	}
+}
+
+func TestRankConflicts(t *testing.T) {
+	conflict := func(serial string, kind models.ConflictType, km, gapHz, separationHz float64) models.FrequencyConflict {
+		return models.FrequencyConflict{Serial: serial, Type: kind, Distance: models.Distance{Meters: km * 1000}, GapHz: gapHz, SeparationHz: separationHz}
+	}
+	conflicts := []models.FrequencyConflict{
+		conflict("adjacent near", models.ConflictAdjacentChannel, 1, 20e3, 40e3),
+		conflict("co-channel far", models.ConflictCoChannel, 40, 0, 0),
+		conflict("co-channel near, apart", models.ConflictCoChannel, 2, 0, 10e3),
+		conflict("co-channel near, centred", models.ConflictCoChannel, 2, 0, 0),
+		conflict("adjacent near, small gap", models.ConflictAdjacentChannel, 1, 5e3, 30e3),
+	}
+	want := []string{"co-channel near, centred", "co-channel near, apart", "co-channel far", "adjacent near, small gap", "adjacent near"}
+
+	rankConflicts(conflicts)
+	for i, conflict := range conflicts {
+		if conflict.Serial != want[i] || conflict.Rank != i+1 {
+			t.Errorf("rank %d: %d %s, want %s", i+1, conflict.Rank, conflict.Serial, want[i])
+		}
+	}
+}
+
+func TestParseConflictSettings(t *testing.T) {
+	defaults := models.ConflictSettings{GuardBandHz: 25e3, DistanceM: 50e3}
+	tests := []struct {
+		name               string
+		guardKHz, distance string
+		unit               string
+		guardHz, distanceM float64
+		invalid            bool
+	}{
+		{name: "defaults", guardHz: 25e3, distanceM: 50e3},
+		{name: "kilometres", guardKHz: "12.5", distance: "10", unit: "km", guardHz: 12.5e3, distanceM: 10e3},
+		{name: "nautical miles", guardKHz: " 0 ", distance: "10", unit: "nm", guardHz: 0, distanceM: 18520},
+		{name: "negative guard band", guardKHz: "-1", distance: "10", invalid: true},
+		{name: "unreadable guard band", guardKHz: "wide", invalid: true},
+		{name: "infinite guard band", guardKHz: "Inf", invalid: true},
+		{name: "zero distance", distance: "0", invalid: true},
+		{name: "negative distance", distance: "-5", invalid: true},
+		{name: "unknown unit", distance: "5", unit: "furlong", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := ParseConflictSettings(tt.guardKHz, tt.distance, tt.unit, defaults)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidConflictSettings) {
+					t.Errorf("error = %v, want ErrInvalidConflictSettings", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConflictSettings: %v", err)
+			}
+			if math.Abs(settings.GuardBandHz-tt.guardHz) > 1e-6 || math.Abs(settings.DistanceM-tt.distanceM) > 1e-6 {
+				t.Errorf("settings %+v, want guard %.1f Hz, distance %.1f m", settings, tt.guardHz, tt.distanceM)
+			}
+		})
+	}
+}
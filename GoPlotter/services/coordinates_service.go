@@ -3,82 +3,414 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
 	"sfaf-plotter/models"
+	"strconv"
+	"strings"
 )
 
+// ErrInvalidCoordinates wraps every coordinate parse and range error
+var ErrInvalidCoordinates = errors.New("invalid coordinates")
+
 type CoordinateService struct{}
 
 func NewCoordinateService() *CoordinateService {
 	return &CoordinateService{}
 }
 
-func (cs *CoordinateService) ConvertToDMS(decimal float64, isLongitude bool) string {
-	absDecimal := math.Abs(decimal)
-	degrees := int(absDecimal)
-	minutesFloat := (absDecimal - float64(degrees)) * 60
-	minutes := int(minutesFloat)
-	seconds := int((minutesFloat - float64(minutes)) * 60) // Convert to int for 0 decimal places
+// Seconds precision of formatted DMS: whole seconds or tenths
+const (
+	DMSWholeSeconds = 0
+	DMSTenthSeconds = 1
+)
+
+// ToDMS splits a decimal angle into degrees, minutes and seconds rounded to
+// precision decimal places of seconds. Rounding carries into minutes and
+// degrees, so seconds never reach 60, and parsing the result gives back the
+// angle to within half the last place: 0.5" (about 15 m) for whole seconds,
+// 0.05" for tenths.
+func (cs *CoordinateService) ToDMS(decimal float64, isLongitude bool, precision int) models.DMSCoordinate {
+	precision = clampDMSPrecision(precision)
+	scale := math.Pow10(precision)
 
-	var direction string
+	// Count in whole units of the last place so the carry is exact
+	units := int64(math.Round(math.Abs(decimal) * 3600 * scale))
+	perMinute := int64(60 * scale)
+	perDegree := 60 * perMinute
+
+	dms := models.DMSCoordinate{
+		Degrees: int(units / perDegree),
+		Minutes: int(units % perDegree / perMinute),
+		Seconds: float64(units%perMinute) / scale,
+	}
+
+	// An angle that rounds to zero takes the N/E hemisphere
+	negative := decimal < 0 && units > 0
+	switch {
+	case isLongitude && negative:
+		dms.Direction = "W"
+	case isLongitude:
+		dms.Direction = "E"
+	case negative:
+		dms.Direction = "S"
+	default:
+		dms.Direction = "N"
+	}
+	return dms
+}
+
+// FormatDMS writes an angle for display, e.g. 30°25'21.5" N
+func (cs *CoordinateService) FormatDMS(decimal float64, isLongitude bool, precision int) string {
+	precision = clampDMSPrecision(precision)
+	dms := cs.ToDMS(decimal, isLongitude, precision)
+	return fmt.Sprintf("%d°%d'%.*f\" %s", dms.Degrees, dms.Minutes, precision, dms.Seconds, dms.Direction)
+}
+
+// FormatCompactDMS writes an angle the way SFAF field303 holds it:
+// DDMMSS[.s]N for latitude, DDDMMSS[.s]W for longitude
+func (cs *CoordinateService) FormatCompactDMS(decimal float64, isLongitude bool, precision int) string {
+	precision = clampDMSPrecision(precision)
+	dms := cs.ToDMS(decimal, isLongitude, precision)
+
+	degreesPadLength := 2
 	if isLongitude {
-		if decimal < 0 {
-			direction = "W"
-		} else {
-			direction = "E"
+		degreesPadLength = 3
+	}
+
+	// Seconds keep two integer digits: 05 or 05.0
+	secondsWidth := 2
+	if precision > 0 {
+		secondsWidth = 3 + precision
+	}
+	return fmt.Sprintf("%0*d%02d%0*.*f%s",
+		degreesPadLength, dms.Degrees, dms.Minutes, secondsWidth, precision, dms.Seconds, dms.Direction)
+}
+
+// FormatCompactLatLng writes a field303-style coordinate pair
+func (cs *CoordinateService) FormatCompactLatLng(lat, lng float64, precision int) string {
+	return cs.FormatCompactDMS(lat, false, precision) + cs.FormatCompactDMS(lng, true, precision)
+}
+
+// DMSPrecisionOf reports the seconds precision of a compact DMS value, so a
+// coordinate can be written back the way it was read
+func (cs *CoordinateService) DMSPrecisionOf(compact string) int {
+	match := compactSecondsFraction.FindStringSubmatch(strings.ToUpper(compact))
+	if match == nil {
+		return DMSWholeSeconds
+	}
+	return clampDMSPrecision(len(match[1]))
+}
+
+// ParseDMSPrecision reads a requested seconds precision. Blank means whole
+// seconds.
+func (cs *CoordinateService) ParseDMSPrecision(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return DMSWholeSeconds, nil
+	}
+	precision, err := strconv.Atoi(text)
+	if err != nil || precision != clampDMSPrecision(precision) {
+		return 0, fmt.Errorf("%w: precision must be %d (whole seconds) or %d (tenths)",
+			ErrInvalidCoordinates, DMSWholeSeconds, DMSTenthSeconds)
+	}
+	return precision, nil
+}
+
+// compactSecondsFraction finds the decimals on the seconds of compact DMS
+var compactSecondsFraction = regexp.MustCompile(`\d{6}\.(\d+)[NS]`)
+
+func clampDMSPrecision(precision int) int {
+	if precision < DMSWholeSeconds {
+		return DMSWholeSeconds
+	}
+	if precision > DMSTenthSeconds {
+		return DMSTenthSeconds
+	}
+	return precision
+}
+
+func (cs *CoordinateService) ConvertToDMS(decimal float64, isLongitude bool) string {
+	return cs.FormatDMS(decimal, isLongitude, DMSWholeSeconds)
+}
+
+func (cs *CoordinateService) DecimalToCompactDMS(decimal float64, isLongitude bool) string {
+	return cs.FormatCompactDMS(decimal, isLongitude, DMSWholeSeconds)
+}
+
+func (cs *CoordinateService) ConvertLatLngToCompactDMS(lat, lng float64) string {
+	return cs.FormatCompactLatLng(lat, lng, DMSWholeSeconds)
+}
+
+// GetAllFormats writes a position in every supported format
+func (cs *CoordinateService) GetAllFormats(lat, lng float64, opts models.CoordinateFormatOptions) models.CoordinateResponse {
+	precision := clampDMSPrecision(opts.SecondsPrecision)
+	formats := models.CoordinateResponse{
+		Decimal:   fmt.Sprintf("%.4f, %.4f", lat, lng), // Always 4 decimal places
+		DMS:       cs.FormatDMS(lat, false, precision) + ", " + cs.FormatDMS(lng, true, precision),
+		Compact:   cs.FormatCompactLatLng(lat, lng, precision),
+		Precision: precision,
+	}
+
+	// Polar positions have no UTM or MGRS form
+	if utm, err := cs.FormatUTM(lat, lng); err == nil {
+		formats.UTM = utm
+		formats.MGRSPrecision = clampMGRSPrecision(opts.MGRSPrecision)
+		formats.MGRS, _ = cs.FormatMGRS(lat, lng, formats.MGRSPrecision)
+	}
+	return formats
+}
+
+// ParseCoordinates reads a latitude/longitude pair in any of the forms
+// analysts paste in:
+//
+//	302521N0864150W, 302521.45N0864150.3W   compact DMS, optional decimal seconds
+//	3025.35N08641.83W                       compact degrees and decimal minutes
+//	30 25 21N 086 41 50W, 30°25'21"N 86°41'50"W, N30:25:21 W86:41:50
+//	30.4225N 86.6972W, N30.4225 W86.6972    decimal degrees with hemisphere letters
+//	30.4225, -86.6972                       signed decimal degrees
+//
+// Errors wrap ErrInvalidCoordinates and name the part that is wrong. MGRS
+// and UTM are read by ParsePosition.
+func (cs *CoordinateService) ParseCoordinates(input string) (models.Coordinate, error) {
+	return parseCoordinatePair(input)
+}
+
+// ParseLatitude reads a single latitude in the forms ParseCoordinates accepts
+func (cs *CoordinateService) ParseLatitude(input string) (float64, error) {
+	return parseAngle(normalizeCoordinateText(input), latitudeAxis)
+}
+
+// ParseLongitude reads a single longitude in the forms ParseCoordinates accepts
+func (cs *CoordinateService) ParseLongitude(input string) (float64, error) {
+	return parseAngle(normalizeCoordinateText(input), longitudeAxis)
+}
+
+// ResolvePosition gives the coordinate of a request that carries either a
+// position in text (MGRS, UTM or latitude/longitude) or decimal degrees.
+// The text wins when both are given.
+func (cs *CoordinateService) ResolvePosition(position string, lat, lng float64) (models.Coordinate, error) {
+	if strings.TrimSpace(position) != "" {
+		return cs.ParsePosition(position)
+	}
+	// lat and lng used to be required; 0,0 is almost always a missing value
+	if lat == 0 && lng == 0 {
+		return models.Coordinate{}, fmt.Errorf("%w: lat and lng, or a position, are required", ErrInvalidCoordinates)
+	}
+	if err := cs.CheckCoordinate(lat, lng); err != nil {
+		return models.Coordinate{}, err
+	}
+	return models.Coordinate{Lat: lat, Lng: lng}, nil
+}
+
+// CheckCoordinate range-checks decimal degrees that arrive as numbers
+func (cs *CoordinateService) CheckCoordinate(lat, lng float64) error {
+	if err := cs.CheckLatitude(lat); err != nil {
+		return err
+	}
+	return cs.CheckLongitude(lng)
+}
+
+func (cs *CoordinateService) CheckLatitude(lat float64) error {
+	return checkAngle(lat, latitudeAxis)
+}
+
+func (cs *CoordinateService) CheckLongitude(lng float64) error {
+	return checkAngle(lng, longitudeAxis)
+}
+
+// coordinateAxis describes latitude or longitude for the parser
+type coordinateAxis struct {
+	name             string
+	positive         byte // hemisphere letter for positive values
+	negative         byte
+	max              float64
+	compactDegDigits int // degree digits in compact DMS
+}
+
+var (
+	latitudeAxis  = coordinateAxis{name: "latitude", positive: 'N', negative: 'S', max: 90, compactDegDigits: 2}
+	longitudeAxis = coordinateAxis{name: "longitude", positive: 'E', negative: 'W', max: 180, compactDegDigits: 3}
+)
+
+// coordinateSymbols separate the parts of an angle written out in full
+var coordinateSymbols = strings.NewReplacer("°", " ", "º", " ", "'", " ", "′", " ", "\"", " ", "″", " ", ":", " ")
+
+// digitHyphen is a hyphen used as a separator, as in 30-25-21N
+var digitHyphen = regexp.MustCompile(`(\d)-(\d)`)
+
+var unsignedNumber = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+func normalizeCoordinateText(input string) string {
+	text := coordinateSymbols.Replace(strings.ToUpper(input))
+	for i := 0; i < 2; i++ { // twice, as matches can't overlap
+		text = digitHyphen.ReplaceAllString(text, "$1 $2")
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func parseCoordinatePair(input string) (models.Coordinate, error) {
+	text := normalizeCoordinateText(input)
+	if text == "" {
+		return models.Coordinate{}, fmt.Errorf("%w: no coordinates given", ErrInvalidCoordinates)
+	}
+
+	latText, lngText, err := splitCoordinatePair(text)
+	if err != nil {
+		return models.Coordinate{}, err
+	}
+
+	lat, err := parseAngle(latText, latitudeAxis)
+	if err != nil {
+		return models.Coordinate{}, err
+	}
+	lng, err := parseAngle(lngText, longitudeAxis)
+	if err != nil {
+		return models.Coordinate{}, err
+	}
+	return models.Coordinate{Lat: lat, Lng: lng}, nil
+}
+
+// splitCoordinatePair finds where the latitude ends: at a comma, semicolon
+// or slash, after the N/S letter, before the E/W letter, or at the only
+// space between two numbers.
+func splitCoordinatePair(text string) (string, string, error) {
+	if i := strings.IndexAny(text, ",;/"); i >= 0 {
+		lat, lng := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		if strings.ContainsAny(lng, ",;/") {
+			return "", "", fmt.Errorf("%w: %q has more than two parts", ErrInvalidCoordinates, text)
 		}
-	} else {
-		if decimal < 0 {
-			direction = "S"
-		} else {
-			direction = "N"
+		return lat, lng, nil
+	}
+
+	var lat, lng string
+	if text[0] == 'N' || text[0] == 'S' {
+		if i := strings.IndexAny(text[1:], "EW"); i >= 0 {
+			lat, lng = text[:i+1], text[i+1:]
 		}
+	} else if i := strings.IndexAny(text, "NS"); i >= 0 {
+		lat, lng = text[:i+1], text[i+1:]
+	}
+	if hasDigit(lat) && hasDigit(lng) {
+		return strings.TrimSpace(lat), strings.TrimSpace(lng), nil
 	}
 
-	return fmt.Sprintf("%d°%d'%d\" %s", degrees, minutes, seconds, direction)
+	if fields := strings.Fields(text); len(fields) == 2 {
+		return fields[0], fields[1], nil
+	}
+	return "", "", fmt.Errorf("%w: can't tell where the latitude ends in %q; separate latitude and longitude with a comma or add hemisphere letters",
+		ErrInvalidCoordinates, text)
 }
 
-func (cs *CoordinateService) DecimalToCompactDMS(decimal float64, isLongitude bool) string {
-	absDecimal := math.Abs(decimal)
-	degrees := int(absDecimal)
-	minutesFloat := (absDecimal - float64(degrees)) * 60
-	minutes := int(minutesFloat)
-	seconds := int((minutesFloat - float64(minutes)) * 60)
+// parseAngle reads one latitude or longitude: decimal degrees, degrees and
+// minutes, or degrees, minutes and seconds, written compactly or with
+// separators, signed or with a hemisphere letter before or after.
+func parseAngle(text string, axis coordinateAxis) (float64, error) {
+	original := text
+	fail := func(format string, args ...interface{}) (float64, error) {
+		return 0, fmt.Errorf("%w: %s %q: %s", ErrInvalidCoordinates, axis.name, original, fmt.Sprintf(format, args...))
+	}
 
-	var direction string
-	var degreesPadLength int
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, fmt.Errorf("%w: %s missing", ErrInvalidCoordinates, axis.name)
+	}
 
-	if isLongitude {
-		degreesPadLength = 3
-		if decimal < 0 {
-			direction = "W"
-		} else {
-			direction = "E"
+	var hemisphere byte
+	if last := text[len(text)-1]; last >= 'A' && last <= 'Z' {
+		hemisphere, text = last, strings.TrimSpace(text[:len(text)-1])
+	} else if first := text[0]; first >= 'A' && first <= 'Z' {
+		hemisphere, text = first, strings.TrimSpace(text[1:])
+	}
+	if hemisphere != 0 && hemisphere != axis.positive && hemisphere != axis.negative {
+		return fail("hemisphere must be %c or %c", axis.positive, axis.negative)
+	}
+
+	sign := 1.0
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		if hemisphere != 0 {
+			return fail("use a sign or a hemisphere letter, not both")
 		}
-	} else {
-		degreesPadLength = 2
-		if decimal < 0 {
-			direction = "S"
-		} else {
-			direction = "N"
+		if text[0] == '-' {
+			sign = -1
 		}
+		text = strings.TrimSpace(text[1:])
+	}
+	if hemisphere == axis.negative {
+		sign = -1
 	}
 
-	return fmt.Sprintf("%0*d%02d%02d%s",
-		degreesPadLength, degrees, minutes, seconds, direction)
+	parts := strings.Fields(text)
+	if len(parts) == 1 {
+		var err error
+		if parts, err = expandCompactAngle(parts[0], axis); err != nil {
+			return fail("%v", err)
+		}
+	}
+	if len(parts) == 0 || len(parts) > 3 {
+		return fail("expected degrees, minutes and seconds at most")
+	}
+
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		if !unsignedNumber.MatchString(part) {
+			return fail("%q is not a number", part)
+		}
+		if i < len(parts)-1 && strings.Contains(part, ".") {
+			return fail("only the last part may have decimals")
+		}
+		values[i], _ = strconv.ParseFloat(part, 64)
+	}
+
+	degrees := values[0]
+	if len(values) > 1 {
+		if values[1] >= 60 {
+			return fail("minutes must be below 60")
+		}
+		degrees += values[1] / 60
+	}
+	if len(values) > 2 {
+		if values[2] >= 60 {
+			return fail("seconds must be below 60")
+		}
+		degrees += values[2] / 3600
+	}
+	if degrees > axis.max {
+		return fail("must be at most %g degrees", axis.max)
+	}
+	return sign * degrees, nil
 }
 
-func (cs *CoordinateService) ConvertLatLngToCompactDMS(lat, lng float64) string {
-	latDMS := cs.DecimalToCompactDMS(lat, false)
-	lngDMS := cs.DecimalToCompactDMS(lng, true)
-	return latDMS + lngDMS
+// expandCompactAngle splits compact DDMMSS / DDDMMSS forms into degrees,
+// minutes and seconds. Shorter values are taken as plain degrees.
+func expandCompactAngle(value string, axis coordinateAxis) ([]string, error) {
+	whole, fraction := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		whole, fraction = value[:i], value[i:]
+	}
+
+	width := axis.compactDegDigits
+	switch len(whole) {
+	case width + 2:
+		return []string{whole[:width], whole[width:] + fraction}, nil
+	case width + 4:
+		return []string{whole[:width], whole[width : width+2], whole[width+2:] + fraction}, nil
+	}
+	if len(whole) <= width {
+		return []string{value}, nil
+	}
+	return nil, fmt.Errorf("compact form needs %d degree digits followed by minutes and seconds", width)
 }
 
-func (cs *CoordinateService) GetAllFormats(lat, lng float64) models.CoordinateResponse {
-	return models.CoordinateResponse{
-		Decimal: fmt.Sprintf("%.4f, %.4f", lat, lng), // Always 4 decimal places
-		DMS:     cs.ConvertToDMS(lat, false) + ", " + cs.ConvertToDMS(lng, true),
-		Compact: cs.ConvertLatLngToCompactDMS(lat, lng),
+func checkAngle(value float64, axis coordinateAxis) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) || math.Abs(value) > axis.max {
+		return fmt.Errorf("%w: %s %g is outside -%g to %g", ErrInvalidCoordinates, axis.name, value, axis.max, axis.max)
 	}
+	return nil
+}
+
+func hasDigit(text string) bool {
+	return strings.ContainsAny(text, "0123456789")
 }
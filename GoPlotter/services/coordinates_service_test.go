@@ -0,0 +1,213 @@
+// coordinates_service_test.go
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	const lat, lng = 30 + 25.0/60 + 21.0/3600, -(86 + 41.0/60 + 50.0/3600)
+
+	tests := []struct {
+		input    string
+		lat, lng float64
+	}{
+		{"302521N0864150W", lat, lng},
+		{"302521.45N0864150.3W", lat + 0.45/3600, lng - 0.3/3600},
+		{"3025.35N08641.83W", 30 + 25.35/60, -(86 + 41.83/60)},
+		{"30 25 21N 086 41 50W", lat, lng},
+		{"30°25'21\"N 86°41'50\"W", lat, lng},
+		{"N30:25:21 W86:41:50", lat, lng},
+		{"30-25-21N 086-41-50W", lat, lng},
+		{"30.4225N 86.6972W", 30.4225, -86.6972},
+		{"N30.4225 W86.6972", 30.4225, -86.6972},
+		{"30.4225, -86.6972", 30.4225, -86.6972},
+		{"30.4225 -86.6972", 30.4225, -86.6972},
+		{"-33.8688; 151.2093", -33.8688, 151.2093},
+		{"s33.8688/e151.2093", -33.8688, 151.2093},
+		{"90, 180", 90, 180},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := cs.ParseCoordinates(tt.input)
+			if err != nil {
+				t.Fatalf("ParseCoordinates(%q): %v", tt.input, err)
+			}
+			if math.Abs(got.Lat-tt.lat) > 1e-9 || math.Abs(got.Lng-tt.lng) > 1e-9 {
+				t.Errorf("ParseCoordinates(%q) = %v, %v, want %v, %v", tt.input, got.Lat, got.Lng, tt.lat, tt.lng)
+			}
+		})
+	}
+}
+
+func TestParseCoordinatesErrors(t *testing.T) {
+	tests := []struct {
+		name, input string
+	}{
+		{"empty", "  "},
+		{"latitude out of range", "91, 10"},
+		{"longitude out of range", "10, 181"},
+		{"compact latitude out of range", "912521N0864150W"},
+		{"minutes of 60", "306021N0864150W"},
+		{"seconds of 60", "302560N0864150W"},
+		{"wrong hemisphere letter", "30.4225E 86.6972W"},
+		{"sign and hemisphere", "-30.4225N 86.6972W"},
+		{"three parts", "30.1, 86.2, 10"},
+		{"no separator", "30 25 21 86 41 50"},
+		{"decimals before the last part", "30.5 25 21N 086 41 50W"},
+		{"not a number", "thirty, ninety"},
+		{"bad compact width", "3025211N0864150W"},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cs.ParseCoordinates(tt.input)
+			if err == nil {
+				t.Fatalf("ParseCoordinates(%q) = %+v, want an error", tt.input, got)
+			}
+			if !errors.Is(err, ErrInvalidCoordinates) {
+				t.Errorf("ParseCoordinates(%q) error %v does not wrap ErrInvalidCoordinates", tt.input, err)
+			}
+		})
+	}
+}
+
+func TestParseSingleAngle(t *testing.T) {
+	cs := NewCoordinateService()
+	if lat, err := cs.ParseLatitude("302521S"); err != nil || math.Abs(lat+30.4225) > 1e-9 {
+		t.Errorf("ParseLatitude(302521S) = %v, %v", lat, err)
+	}
+	if lng, err := cs.ParseLongitude("0864150E"); err != nil || math.Abs(lng-(86+41.0/60+50.0/3600)) > 1e-9 {
+		t.Errorf("ParseLongitude(0864150E) = %v, %v", lng, err)
+	}
+	if _, err := cs.ParseLatitude("302521W"); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("ParseLatitude(302521W) error = %v, want ErrInvalidCoordinates", err)
+	}
+	if _, err := cs.ParseLongitude("1810000E"); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("ParseLongitude(1810000E) error = %v, want ErrInvalidCoordinates", err)
+	}
+}
+
+func TestCheckCoordinate(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		valid    bool
+	}{
+		{30.4225, -86.6972, true},
+		{-90, 180, true},
+		{90.0001, 0, false},
+		{0, -180.0001, false},
+		{math.NaN(), 0, false},
+		{0, math.Inf(1), false},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		err := cs.CheckCoordinate(tt.lat, tt.lng)
+		if (err == nil) != tt.valid {
+			t.Errorf("CheckCoordinate(%v, %v) = %v, want valid %v", tt.lat, tt.lng, err, tt.valid)
+		}
+	}
+}
+
+func TestToDMSCarry(t *testing.T) {
+	tests := []struct {
+		name        string
+		decimal     float64
+		isLongitude bool
+		precision   int
+		want        models.DMSCoordinate
+	}{
+		{"whole seconds", 30.4225, false, DMSWholeSeconds, models.DMSCoordinate{Degrees: 30, Minutes: 25, Seconds: 21, Direction: "N"}},
+		{"tenths", 30 + 25.0/60 + 21.46/3600, false, DMSTenthSeconds, models.DMSCoordinate{Degrees: 30, Minutes: 25, Seconds: 21.5, Direction: "N"}},
+		{"59.6 seconds carry into minutes", 30 + 25.0/60 + 59.6/3600, false, DMSWholeSeconds, models.DMSCoordinate{Degrees: 30, Minutes: 26, Seconds: 0, Direction: "N"}},
+		{"59.99 seconds carry into degrees", 30 + 59.0/60 + 59.99/3600, false, DMSWholeSeconds, models.DMSCoordinate{Degrees: 31, Minutes: 0, Seconds: 0, Direction: "N"}},
+		{"59.96 seconds carry at tenths", 30 + 59.0/60 + 59.96/3600, false, DMSTenthSeconds, models.DMSCoordinate{Degrees: 31, Minutes: 0, Seconds: 0, Direction: "N"}},
+		{"59.94 seconds stay at tenths", 30 + 59.0/60 + 59.94/3600, false, DMSTenthSeconds, models.DMSCoordinate{Degrees: 30, Minutes: 59, Seconds: 59.9, Direction: "N"}},
+		{"west longitude", -(179 + 59.0/60 + 59.7/3600), true, DMSWholeSeconds, models.DMSCoordinate{Degrees: 180, Minutes: 0, Seconds: 0, Direction: "W"}},
+		{"south latitude", -12.5, false, DMSWholeSeconds, models.DMSCoordinate{Degrees: 12, Minutes: 30, Seconds: 0, Direction: "S"}},
+		{"negative rounding to zero", -0.0001, false, DMSWholeSeconds, models.DMSCoordinate{Direction: "N"}},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cs.ToDMS(tt.decimal, tt.isLongitude, tt.precision); got != tt.want {
+				t.Errorf("ToDMS(%v) = %+v, want %+v", tt.decimal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCompactDMS(t *testing.T) {
+	tests := []struct {
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{30.4225, -86.697222, DMSWholeSeconds, "302521N0864150W"},
+		{30 + 25.0/60 + 21.45/3600, -(86 + 41.0/60 + 50.3/3600), DMSTenthSeconds, "302521.5N0864150.3W"},
+		{-1.0001, 1.0001, DMSWholeSeconds, "010000S0010000E"},
+		{-1.0001, 1.0001, DMSTenthSeconds, "010000.4S0010000.4E"},
+		{0, 0, DMSWholeSeconds, "000000N0000000E"},
+		{30 + 59.0/60 + 59.99/3600, -(179 + 59.0/60 + 59.99/3600), DMSWholeSeconds, "310000N1800000W"},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := cs.FormatCompactLatLng(tt.lat, tt.lng, tt.precision); got != tt.want {
+				t.Errorf("FormatCompactLatLng(%v, %v, %d) = %q, want %q", tt.lat, tt.lng, tt.precision, got, tt.want)
+			}
+			if got := cs.DMSPrecisionOf(tt.want); got != tt.precision {
+				t.Errorf("DMSPrecisionOf(%q) = %d, want %d", tt.want, got, tt.precision)
+			}
+		})
+	}
+}
+
+// TestDMSRoundTrip checks that parsing a formatted angle gives it back to
+// within half the last place of seconds
+func TestDMSRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cs := NewCoordinateService()
+
+	for _, precision := range []int{DMSWholeSeconds, DMSTenthSeconds} {
+		tolerance := 0.5/math.Pow10(precision)/3600 + 1e-9
+
+		for i := 0; i < 10000; i++ {
+			lat := rng.Float64()*180 - 90
+			lng := rng.Float64()*360 - 180
+			if i < 4 {
+				// The edges of the ranges and a carry into the next degree
+				lat, lng = []float64{90, -90, 0, 45 - 0.01/3600}[i], []float64{180, -180, 0, -(120 - 0.01/3600)}[i]
+			}
+
+			compact := cs.FormatCompactLatLng(lat, lng, precision)
+			got, err := cs.ParseCoordinates(compact)
+			if err != nil {
+				t.Fatalf("ParseCoordinates(%q): %v", compact, err)
+			}
+			if math.Abs(got.Lat-lat) > tolerance || math.Abs(got.Lng-lng) > tolerance {
+				t.Fatalf("%v, %v -> %q -> %v, %v: off by more than %g", lat, lng, compact, got.Lat, got.Lng, tolerance)
+			}
+
+			display := cs.FormatDMS(lat, false, precision)
+			parsed, err := cs.ParseLatitude(display)
+			if err != nil {
+				t.Fatalf("ParseLatitude(%q): %v", display, err)
+			}
+			if math.Abs(parsed-lat) > tolerance {
+				t.Fatalf("%v -> %q -> %v: off by more than %g", lat, display, parsed, tolerance)
+			}
+		}
+	}
+}
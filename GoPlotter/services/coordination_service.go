@@ -0,0 +1,294 @@
+// coordination_service.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+
+	"github.com/google/uuid"
+)
+
+// coordinationAreaColor is used for the coordination area geometries
+const coordinationAreaColor = "#f39c12"
+
+// coordinationNamespace seeds the IDs of coordination area geometries so
+// they stay the same from one start to the next
+var coordinationNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("sfaf-plotter/irac-coordination"))
+
+// CoordinationService keeps the IRAC coordination areas as geometries and
+// tests markers and SFAF records against them
+type CoordinationService struct {
+	storage         storage.Storage
+	geometryService *GeometryService
+	areas           map[string]models.CoordinationArea // by note code
+}
+
+// LoadCoordinationAreas reads and checks the coordination area data file
+func LoadCoordinationAreas(path string) (*models.CoordinationAreaCatalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coordination areas: %w", err)
+	}
+
+	var catalogue models.CoordinationAreaCatalogue
+	if err := json.Unmarshal(data, &catalogue); err != nil {
+		return nil, fmt.Errorf("failed to parse coordination areas %s: %w", path, err)
+	}
+	if err := checkCoordinationAreas(&catalogue); err != nil {
+		return nil, fmt.Errorf("invalid coordination areas %s: %w", path, err)
+	}
+	return &catalogue, nil
+}
+
+func checkCoordinationAreas(catalogue *models.CoordinationAreaCatalogue) error {
+	seen := make(map[string]bool, len(catalogue.Areas))
+	for i := range catalogue.Areas {
+		area := &catalogue.Areas[i]
+		area.Note = strings.ToUpper(strings.TrimSpace(area.Note))
+		if !iracNoteCode.MatchString(area.Note) {
+			return fmt.Errorf("area %d: invalid note code %q", i+1, area.Note)
+		}
+		if seen[area.Note] {
+			return fmt.Errorf("%s is defined more than once", area.Note)
+		}
+		seen[area.Note] = true
+
+		switch area.Requirement {
+		case "":
+			area.Requirement = models.CoordinationRequired
+		case models.CoordinationRequired, models.CoordinationSuggested:
+		default:
+			return fmt.Errorf("%s: unknown requirement %q", area.Note, area.Requirement)
+		}
+		if len(area.Shapes) == 0 {
+			return fmt.Errorf("%s: no shapes defined", area.Note)
+		}
+
+		for j, shape := range area.Shapes {
+			if err := checkCoordinationShape(shape); err != nil {
+				return fmt.Errorf("%s shape %d: %w", area.Note, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkCoordinationShape(shape models.CoordinationShape) error {
+	switch shape.Type {
+	case models.GeometryTypeCircle:
+		if shape.Center == nil || shape.RadiusKm <= 0 {
+			return fmt.Errorf("circles need a center and a positive radius_km")
+		}
+	case models.GeometryTypeRectangle:
+		if shape.SouthWest == nil || shape.NorthEast == nil {
+			return fmt.Errorf("rectangles need south_west and north_east")
+		}
+		if shape.SouthWest.Lat >= shape.NorthEast.Lat || shape.SouthWest.Lng >= shape.NorthEast.Lng {
+			return fmt.Errorf("south_west must be south and west of north_east")
+		}
+	case models.GeometryTypePolygon:
+		if len(shape.Points) < 3 {
+			return fmt.Errorf("polygons need at least 3 points")
+		}
+	default:
+		return fmt.Errorf("unknown shape type %q", shape.Type)
+	}
+	return nil
+}
+
+func NewCoordinationService(storage storage.Storage, geometryService *GeometryService, catalogue *models.CoordinationAreaCatalogue) *CoordinationService {
+	service := &CoordinationService{
+		storage:         storage,
+		geometryService: geometryService,
+		areas:           make(map[string]models.CoordinationArea, len(catalogue.Areas)),
+	}
+	for _, area := range catalogue.Areas {
+		service.areas[area.Note] = area
+	}
+	return service
+}
+
+// SyncAreas stores a geometry for every shape of every coordination area
+// and removes coordination geometries the data file no longer defines. It
+// returns the number of geometries stored.
+func (cs *CoordinationService) SyncAreas() (int, error) {
+	existing, err := cs.coordinationGeometries()
+	if err != nil {
+		return 0, err
+	}
+	current := make(map[uuid.UUID]*models.Geometry, len(existing))
+	for _, geometry := range existing {
+		current[geometry.ID] = geometry
+	}
+
+	var geometries []*models.Geometry
+	for _, area := range cs.areas {
+		for i, shape := range area.Shapes {
+			geometry := cs.areaGeometry(area, i, shape)
+			if previous, exists := current[geometry.ID]; exists {
+				geometry.CreatedAt = previous.CreatedAt
+				delete(current, geometry.ID)
+			}
+			geometries = append(geometries, geometry)
+		}
+	}
+	if err := cs.geometryService.storeGeometries(geometries); err != nil {
+		return 0, fmt.Errorf("failed to save coordination areas: %w", err)
+	}
+
+	retired := make([]uuid.UUID, 0, len(current))
+	for id := range current {
+		retired = append(retired, id)
+	}
+	if err := cs.geometryService.removeGeometries(retired); err != nil {
+		return len(geometries), fmt.Errorf("failed to delete coordination areas: %w", err)
+	}
+	return len(geometries), nil
+}
+
+func (cs *CoordinationService) areaGeometry(area models.CoordinationArea, index int, shape models.CoordinationShape) *models.Geometry {
+	now := time.Now()
+	geometry := &models.Geometry{
+		ID:        uuid.NewSHA1(coordinationNamespace, []byte(fmt.Sprintf("%s/%d", area.Note, index+1))),
+		Type:      shape.Type,
+		Serial:    area.Note,
+		Color:     coordinationAreaColor,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Source:    models.GeometrySourceCoordination,
+		IRACNote:  area.Note,
+		Label:     shape.Label,
+	}
+
+	switch shape.Type {
+	case models.GeometryTypeCircle:
+		geometry.Latitude, geometry.Longitude = shape.Center.Lat, shape.Center.Lng
+		geometry.CircleProps = circleProperties(shape.RadiusKm*1000, "km")
+	case models.GeometryTypeRectangle:
+		geometry.Latitude = (shape.SouthWest.Lat + shape.NorthEast.Lat) / 2
+		geometry.Longitude = (shape.SouthWest.Lng + shape.NorthEast.Lng) / 2
+		geometry.RectangleProps = rectangleProperties(*shape.SouthWest, *shape.NorthEast)
+	case models.GeometryTypePolygon:
+		center := cs.geometryService.calculateCentroid(shape.Points)
+		geometry.Latitude, geometry.Longitude = center.Lat, center.Lng
+		geometry.PolygonProps = polygonProperties(shape.Points)
+	}
+	return geometry
+}
+
+// CheckMarker lists the coordination areas a marker lies in, one match per
+// note, and whether its SFAF record already cites the note
+func (cs *CoordinationService) CheckMarker(marker *models.Marker) ([]models.CoordinationMatch, error) {
+	// A marker without an SFAF record yet cites no notes
+	present := make(map[string]bool)
+	if sfaf, err := cs.storage.GetSFAFByMarkerID(marker.ID.String()); err == nil && sfaf != nil {
+		sfaf.EnsureEntries()
+		present = recordNotes(sfaf)
+	}
+	return cs.matchesAt(marker.Latitude, marker.Longitude, present)
+}
+
+// matchesAt lists the coordination areas at a point, one match per note
+func (cs *CoordinationService) matchesAt(lat, lng float64, present map[string]bool) ([]models.CoordinationMatch, error) {
+	geometries, err := cs.geometryService.GeometriesAt(lat, lng)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool)
+	var matches []models.CoordinationMatch
+
+	for _, geometry := range geometries {
+		if geometry.Source != models.GeometrySourceCoordination || matched[geometry.IRACNote] {
+			continue
+		}
+		matched[geometry.IRACNote] = true
+
+		area, defined := cs.areas[geometry.IRACNote]
+		if !defined {
+			// Left over from an older data file; treat it as required
+			area = models.CoordinationArea{Note: geometry.IRACNote, Requirement: models.CoordinationRequired}
+		}
+		matches = append(matches, models.CoordinationMatch{
+			Note:       geometry.IRACNote,
+			Name:       area.Name,
+			Area:       geometry.Label,
+			GeometryID: geometry.ID,
+			Required:   area.Requirement == models.CoordinationRequired,
+			Present:    present[geometry.IRACNote],
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Note < matches[j].Note })
+	return matches, nil
+}
+
+// recordNotes collects the note codes a record cites in fields 500-504
+func recordNotes(record *models.SFAF) map[string]bool {
+	notes := make(map[string]bool)
+	for _, cited := range citedNotes(record) {
+		notes[cited.Code] = true
+	}
+	return notes
+}
+
+// Rules returns the record rule that tests the transmitter coordinates
+// against the coordination areas
+func (cs *CoordinationService) Rules() []SFAFRule {
+	return []SFAFRule{
+		{
+			Name:        "coordination-notes",
+			Description: "Transmitters (303) inside a coordination area cite the area's IRAC note in fields 500-504",
+			Check:       cs.checkCoordinationNotes,
+		},
+	}
+}
+
+func (cs *CoordinationService) checkCoordinationNotes(record *models.SFAF) []models.SFAFValidationIssue {
+	// Unparseable coordinates are reported by the field format check
+	coord, err := parseCoordinatePair(record.Value("303"))
+	if err != nil {
+		return nil
+	}
+	matches, err := cs.matchesAt(coord.Lat, coord.Lng, recordNotes(record))
+	if err != nil {
+		return []models.SFAFValidationIssue{ruleWarning(fmt.Sprintf("coordination areas could not be checked: %v", err), "field303")}
+	}
+
+	var issues []models.SFAFValidationIssue
+	for _, match := range matches {
+		if match.Present {
+			continue
+		}
+		if match.Required {
+			issues = append(issues, ruleError(fmt.Sprintf("the transmitter (303) is in the %s coordination area (%s) but IRAC note %s is not cited",
+				match.Note, match.Area, match.Note), "field303", "field500"))
+		} else {
+			issues = append(issues, ruleWarning(fmt.Sprintf("the transmitter (303) is in the %s coordination area (%s); consider citing IRAC note %s",
+				match.Note, match.Area, match.Note), "field303", "field500"))
+		}
+	}
+	return issues
+}
+
+func (cs *CoordinationService) coordinationGeometries() ([]*models.Geometry, error) {
+	geometries, err := cs.geometryService.GetAllGeometries()
+	if err != nil {
+		return nil, err
+	}
+
+	var areas []*models.Geometry
+	for _, geometry := range geometries {
+		if geometry.Source == models.GeometrySourceCoordination {
+			areas = append(areas, geometry)
+		}
+	}
+	return areas, nil
+}
@@ -0,0 +1,154 @@
+// coordination_service_test.go
+package services
+
+import (
+	"testing"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+
+	"github.com/google/uuid"
+)
+
+func newCoordinationTestService(t *testing.T) (*CoordinationService, storage.Storage) {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	catalogue := &models.CoordinationAreaCatalogue{Areas: []models.CoordinationArea{
+		{
+			Note: "C004",
+			Shapes: []models.CoordinationShape{{
+				Type:      models.GeometryTypeRectangle,
+				Label:     "24N-31N30, 77W-83W",
+				SouthWest: &models.Coordinate{Lat: 24, Lng: -83},
+				NorthEast: &models.Coordinate{Lat: 31.5, Lng: -77},
+			}},
+		},
+		{
+			Note:        "C010",
+			Requirement: models.CoordinationSuggested,
+			Shapes: []models.CoordinationShape{{
+				Type:     models.GeometryTypeCircle,
+				Label:    "100 km of Eglin AFB",
+				Center:   &models.Coordinate{Lat: 30.46, Lng: -86.55},
+				RadiusKm: 100,
+			}},
+		},
+	}}
+	if err := checkCoordinationAreas(catalogue); err != nil {
+		t.Fatalf("checkCoordinationAreas: %v", err)
+	}
+
+	geometryService := NewGeometryService(store, nil, nil, NewCoordinateService())
+	if _, err := geometryService.IndexGeometries(); err != nil {
+		t.Fatalf("IndexGeometries: %v", err)
+	}
+	service := NewCoordinationService(store, geometryService, catalogue)
+	if stored, err := service.SyncAreas(); err != nil || stored != 2 {
+		t.Fatalf("SyncAreas = %d, %v", stored, err)
+	}
+	return service, store
+}
+
+func TestCheckCoordinationNotes(t *testing.T) {
+	const (
+		eglin   = "302521N0864150W" // in the C010 circle
+		orlando = "283200N0812300W" // in the C004 box
+		houston = "294600N0952200W" // in neither
+	)
+	service, _ := newCoordinationTestService(t)
+	tests := []struct {
+		name   string
+		record *models.SFAF
+		issues []ruleIssue
+	}{
+		{
+			name:   "required note missing",
+			record: noteRecord("FL", orlando),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field303", "C004 coordination area (24N-31N30, 77W-83W) but IRAC note C004 is not cited"}},
+		},
+		{
+			name:   "required note in 500",
+			record: noteRecord("FL", orlando, "500 S189", "500 c004"),
+		},
+		{
+			name:   "required note in another note field",
+			record: noteRecord("FL", orlando, "503 C004"),
+		},
+		{
+			name:   "suggested note missing",
+			record: noteRecord("FL", eglin, "500 C004"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field303", "consider citing IRAC note C010"}},
+		},
+		{
+			name:   "outside every area",
+			record: noteRecord("TX", houston),
+		},
+		{
+			name:   "no coordinates",
+			record: noteRecord("FL", ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRuleIssues(t, service.checkCoordinationNotes(tt.record), tt.issues)
+		})
+	}
+}
+
+func TestCoordinationCheckMarker(t *testing.T) {
+	service, store := newCoordinationTestService(t)
+
+	// Orlando lies in the C004 box only
+	marker := &models.Marker{ID: uuid.New(), Latitude: 28.5333, Longitude: -81.3833}
+	matches, err := service.CheckMarker(marker)
+	if err != nil {
+		t.Fatalf("CheckMarker: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Note != "C004" || !matches[0].Required || matches[0].Present {
+		t.Fatalf("marker without a record: %+v", matches)
+	}
+
+	record := noteRecord("FL", "283200N0812300W", "500 C004")
+	record.ID, record.MarkerID = uuid.New(), marker.ID
+	if err := store.SaveSFAF(record); err != nil {
+		t.Fatalf("SaveSFAF: %v", err)
+	}
+	matches, err = service.CheckMarker(marker)
+	if err != nil {
+		t.Fatalf("CheckMarker: %v", err)
+	}
+	if len(matches) != 1 || !matches[0].Present {
+		t.Errorf("marker whose record cites C004: %+v", matches)
+	}
+
+	// Eglin lies in the suggested C010 circle
+	matches, err = service.CheckMarker(&models.Marker{ID: uuid.New(), Latitude: 30.4225, Longitude: -86.6972})
+	if err != nil {
+		t.Fatalf("CheckMarker: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Note != "C010" || matches[0].Required {
+		t.Errorf("suggested area: %+v", matches)
+	}
+}
+
+func TestCoordinationRuleSet(t *testing.T) {
+	service, _ := newCoordinationTestService(t)
+	ruleSets := newSFAFRuleSets(nil, service)
+
+	for _, name := range []string{IRACNotesSFAFRuleSet, DefaultSFAFRuleSet} {
+		found := false
+		for _, rule := range ruleSets[name] {
+			found = found || rule.Name == "coordination-notes"
+		}
+		if !found {
+			t.Errorf("rule set %s has no coordination-notes rule", name)
+		}
+	}
+	if _, exists := newSFAFRuleSets(nil, nil)[IRACNotesSFAFRuleSet]; exists {
+		t.Errorf("irac-notes rule set without a catalogue or coordination areas")
+	}
+}
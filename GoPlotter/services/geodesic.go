@@ -0,0 +1,377 @@
+// geodesic.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+// Distance units
+const (
+	metersPerKm           = 1000.0
+	metersPerNauticalMile = 1852.0
+	metersPerStatuteMile  = 1609.344
+)
+
+// wgs84E2 is the squared eccentricity of the WGS 84 ellipsoid
+const wgs84E2 = wgs84F * (2 - wgs84F)
+
+// wgs84B is the semi-minor axis of the WGS 84 ellipsoid
+const wgs84B = wgs84A * (1 - wgs84F)
+
+// vincentyMaxIterations bounds the iterations of Vincenty's formulae; only
+// nearly antipodal points need more
+const vincentyMaxIterations = 200
+
+// NewDistance expresses a distance in metres in every unit we report
+func NewDistance(meters float64) models.Distance {
+	return models.Distance{
+		Meters:       meters,
+		Km:           meters / metersPerKm,
+		NM:           meters / metersPerNauticalMile,
+		StatuteMiles: meters / metersPerStatuteMile,
+	}
+}
+
+// NewArea expresses an area in square metres in every unit we report
+func NewArea(squareMeters float64) models.Area {
+	return models.Area{
+		SquareKm:    squareMeters / (metersPerKm * metersPerKm),
+		SquareNM:    squareMeters / (metersPerNauticalMile * metersPerNauticalMile),
+		SquareMiles: squareMeters / (metersPerStatuteMile * metersPerStatuteMile),
+	}
+}
+
+// DistanceToMeters converts a distance in km, nm, mi or m to metres
+func DistanceToMeters(value float64, unit string) (float64, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "km":
+		return value * metersPerKm, nil
+	case "nm":
+		return value * metersPerNauticalMile, nil
+	case "mi":
+		return value * metersPerStatuteMile, nil
+	case "m":
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown distance unit %q, use km, nm, mi or m", unit)
+}
+
+// Geodesic solves the inverse problem on the WGS 84 ellipsoid with
+// Vincenty's formulae: the distance between two points and the bearings at
+// each end, to well under a millimetre. Nearly antipodal points, where the
+// iteration fails to converge, fall back to a great circle on the mean
+// sphere, which is within 0.5% there.
+func (cs *CoordinateService) Geodesic(from, to models.Coordinate) (models.GeodesicResult, error) {
+	if err := cs.CheckCoordinate(from.Lat, from.Lng); err != nil {
+		return models.GeodesicResult{}, err
+	}
+	if err := cs.CheckCoordinate(to.Lat, to.Lng); err != nil {
+		return models.GeodesicResult{}, err
+	}
+
+	meters, initial, final, converged := vincentyInverse(from, to)
+	if !converged {
+		meters, initial, final = greatCircleInverse(from, to)
+	}
+	return models.GeodesicResult{
+		From:           from,
+		To:             to,
+		Distance:       NewDistance(meters),
+		InitialBearing: initial,
+		FinalBearing:   final,
+		Ellipsoidal:    converged,
+	}, nil
+}
+
+// Destination solves the direct problem: the point reached by travelling
+// meters from a start along an initial bearing in degrees, and the bearing
+// on arrival
+func (cs *CoordinateService) Destination(from models.Coordinate, bearing, meters float64) (models.DestinationResult, error) {
+	if err := cs.CheckCoordinate(from.Lat, from.Lng); err != nil {
+		return models.DestinationResult{}, err
+	}
+	if meters < 0 || math.IsNaN(meters) || math.IsInf(meters, 0) {
+		return models.DestinationResult{}, fmt.Errorf("%w: distance must be zero or more", ErrInvalidCoordinates)
+	}
+	if math.IsNaN(bearing) || math.IsInf(bearing, 0) {
+		return models.DestinationResult{}, fmt.Errorf("%w: bearing must be a number of degrees", ErrInvalidCoordinates)
+	}
+
+	to, final := vincentyDirect(from, normalizeBearing(bearing), meters)
+	return models.DestinationResult{
+		From:           from,
+		To:             to,
+		Distance:       NewDistance(meters),
+		InitialBearing: normalizeBearing(bearing),
+		FinalBearing:   final,
+	}, nil
+}
+
+// Areas are worked out on the authalic sphere: the sphere with the same
+// surface area as the WGS 84 ellipsoid, onto which latitude maps (as
+// authalic latitude) so that areas are kept exactly. A box between two
+// parallels and two meridians therefore has its exact ellipsoidal area;
+// polygon edges are taken as great circles on this sphere, which stand in
+// for the ellipsoid's geodesics.
+
+// authalicQ is q(φ) of the authalic latitude, sin β = q(φ) / q(90°)
+func authalicQ(sinPhi float64) float64 {
+	e := math.Sqrt(wgs84E2)
+	return (1 - wgs84E2) * (sinPhi/(1-wgs84E2*sinPhi*sinPhi) - math.Log((1-e*sinPhi)/(1+e*sinPhi))/(2*e))
+}
+
+var (
+	authalicQPole = authalicQ(1)
+	// authalicRadius is the radius of the sphere with the ellipsoid's area
+	authalicRadius = wgs84A * math.Sqrt(authalicQPole/2)
+)
+
+// authalicLatitude maps a geodetic latitude in degrees to the authalic
+// latitude in radians
+func authalicLatitude(lat float64) float64 {
+	return math.Asin(math.Max(-1, math.Min(1, authalicQ(math.Sin(toRadians(lat)))/authalicQPole)))
+}
+
+// ringArea is the ellipsoidal area in square metres enclosed by a ring of
+// points joined by geodesics. The ring closes itself; it must not enclose
+// a pole.
+func ringArea(points []models.Coordinate) float64 {
+	if len(points) < 3 {
+		return 0
+	}
+
+	// Sum the signed areas between each edge and the north pole
+	var excess float64
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		dLambda := toRadians(math.Mod(b.Lng-a.Lng+540, 360) - 180)
+		t1 := math.Tan(authalicLatitude(a.Lat) / 2)
+		t2 := math.Tan(authalicLatitude(b.Lat) / 2)
+		excess += 2 * math.Atan2(math.Tan(dLambda/2)*(t1+t2), 1+t1*t2)
+	}
+	return math.Abs(excess) * authalicRadius * authalicRadius
+}
+
+// ringPerimeter is the length in metres of a closed ring of geodesics
+func ringPerimeter(points []models.Coordinate) float64 {
+	var meters float64
+	for i := range points {
+		meters += geodesicDistance(points[i], points[(i+1)%len(points)])
+	}
+	return meters
+}
+
+// boxArea is the exact area in square metres between two parallels and two
+// meridians
+func boxArea(southWest, northEast models.Coordinate) float64 {
+	dLambda := toRadians(math.Abs(northEast.Lng - southWest.Lng))
+	band := math.Sin(authalicLatitude(northEast.Lat)) - math.Sin(authalicLatitude(southWest.Lat))
+	return authalicRadius * authalicRadius * dLambda * math.Abs(band)
+}
+
+// boxPerimeter is the length in metres of the two meridian and two
+// parallel sides of a box
+func boxPerimeter(southWest, northEast models.Coordinate) float64 {
+	meridian := geodesicDistance(southWest, models.Coordinate{Lat: northEast.Lat, Lng: southWest.Lng})
+	dLambda := toRadians(math.Abs(northEast.Lng - southWest.Lng))
+	return 2*meridian + (parallelRadius(southWest.Lat)+parallelRadius(northEast.Lat))*dLambda
+}
+
+// parallelRadius is the radius in metres of the circle of latitude lat
+func parallelRadius(lat float64) float64 {
+	sinPhi, cosPhi := math.Sincos(toRadians(lat))
+	return wgs84A * cosPhi / math.Sqrt(1-wgs84E2*sinPhi*sinPhi)
+}
+
+// capArea and capPerimeter measure a circle of radius metres on the
+// authalic sphere
+func capArea(radius float64) float64 {
+	// 1 - cos x written as 2 sin²(x/2), which keeps its digits for small x
+	half := math.Sin(radius / authalicRadius / 2)
+	return 4 * math.Pi * authalicRadius * authalicRadius * half * half
+}
+
+func capPerimeter(radius float64) float64 {
+	return 2 * math.Pi * authalicRadius * math.Sin(radius/authalicRadius)
+}
+
+// radiusBounds is a box holding every point within radius metres of center.
+// It is conservative: the latitude span uses the smallest meridian radius of
+// curvature and the longitude span the smallest circle of latitude inside
+// it, and a circle that reaches a pole takes every longitude.
+func radiusBounds(center models.Coordinate, radius float64) models.BoundingBox {
+	dLat := toDegrees(radius / (wgs84A * (1 - wgs84E2)))
+	box := models.BoundingBox{
+		South: math.Max(-90, center.Lat-dLat),
+		North: math.Min(90, center.Lat+dLat),
+		West:  -180,
+		East:  180,
+	}
+	if box.South == -90 || box.North == 90 {
+		return box
+	}
+
+	dLng := toDegrees(radius / parallelRadius(math.Max(-box.South, box.North)))
+	if dLng >= 180 {
+		return box
+	}
+	box.West, box.East = center.Lng-dLng, center.Lng+dLng
+	if box.West < -180 {
+		box.West += 360
+	}
+	if box.East > 180 {
+		box.East -= 360
+	}
+	return box
+}
+
+// geodesicDistance is the distance in metres between two points, with the
+// same spherical fallback as Geodesic
+func geodesicDistance(from, to models.Coordinate) float64 {
+	meters, _, _, converged := vincentyInverse(from, to)
+	if !converged {
+		meters, _, _ = greatCircleInverse(from, to)
+	}
+	return meters
+}
+
+func vincentyInverse(from, to models.Coordinate) (meters, initial, final float64, converged bool) {
+	phi1, phi2 := toRadians(from.Lat), toRadians(to.Lat)
+	l := toRadians(to.Lng - from.Lng)
+
+	u1 := math.Atan((1 - wgs84F) * math.Tan(phi1))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(phi2))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := l
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM, sinLambda, cosLambda float64
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda = math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0, 0, 0, true // same point
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0 // both points on the equator
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		c := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		previous := lambda
+		lambda = l + (1-c)*wgs84F*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+		if math.IsNaN(lambda) {
+			return 0, 0, 0, false
+		}
+		if math.Abs(lambda-previous) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, 0, 0, false
+	}
+
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	a, b := vincentyCoefficients(uSq)
+	deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	meters = wgs84B * a * (sigma - deltaSigma)
+	initial = toBearing(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda))
+	final = toBearing(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda))
+	return meters, initial, final, true
+}
+
+func vincentyDirect(from models.Coordinate, bearing, meters float64) (models.Coordinate, float64) {
+	sinAlpha1, cosAlpha1 := math.Sincos(toRadians(bearing))
+
+	tanU1 := (1 - wgs84F) * math.Tan(toRadians(from.Lat))
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cosSqAlpha := 1 - sinAlpha*sinAlpha
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	a, b := vincentyCoefficients(uSq)
+
+	sigma := meters / (wgs84B * a)
+	var sinSigma, cosSigma, cos2SigmaM float64
+	for i := 0; i < vincentyMaxIterations; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		previous := sigma
+		sigma = meters/(wgs84B*a) + deltaSigma
+		if math.Abs(sigma-previous) < 1e-12 {
+			break
+		}
+	}
+	sinSigma, cosSigma = math.Sincos(sigma)
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
+
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	phi2 := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-wgs84F)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	c := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+	l := lambda - (1-c)*wgs84F*sinAlpha*
+		(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+	to := models.Coordinate{
+		Lat: toDegrees(phi2),
+		Lng: math.Mod(from.Lng+toDegrees(l)+540, 360) - 180,
+	}
+	return to, toBearing(math.Atan2(sinAlpha, -x))
+}
+
+// vincentyCoefficients are Vincenty's A and B series in u²
+func vincentyCoefficients(uSq float64) (a, b float64) {
+	a = 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	b = uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	return a, b
+}
+
+// greatCircleInverse is the spherical fallback for vincentyInverse
+func greatCircleInverse(from, to models.Coordinate) (meters, initial, final float64) {
+	meters = haversineKm(from.Lat, from.Lng, to.Lat, to.Lng) * metersPerKm
+	initial = sphericalBearing(from, to)
+	final = math.Mod(sphericalBearing(to, from)+180, 360)
+	return meters, initial, final
+}
+
+func sphericalBearing(from, to models.Coordinate) float64 {
+	phi1, phi2 := toRadians(from.Lat), toRadians(to.Lat)
+	dLambda := toRadians(to.Lng - from.Lng)
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return toBearing(math.Atan2(y, x))
+}
+
+func toRadians(degrees float64) float64 { return degrees * math.Pi / 180 }
+func toDegrees(radians float64) float64 { return radians * 180 / math.Pi }
+
+// toBearing turns an angle in radians into a bearing of 0-360°
+func toBearing(radians float64) float64 {
+	return normalizeBearing(toDegrees(radians))
+}
+
+func normalizeBearing(degrees float64) float64 {
+	bearing := math.Mod(degrees, 360)
+	if bearing < 0 {
+		bearing += 360
+	}
+	return bearing
+}
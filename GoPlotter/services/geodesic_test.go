@@ -0,0 +1,251 @@
+// geodesic_test.go
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+// dms is an angle in degrees, minutes and seconds as decimal degrees
+func dms(degrees, minutes, seconds float64) float64 {
+	sign := 1.0
+	if degrees < 0 {
+		sign, degrees = -1, -degrees
+	}
+	return sign * (degrees + minutes/60 + seconds/3600)
+}
+
+// Flinders Peak to Buninyong is the worked example in Vincenty (1975)
+var (
+	flindersPeak = models.Coordinate{Lat: dms(-37, 57, 3.72030), Lng: dms(144, 25, 29.52440)}
+	buninyong    = models.Coordinate{Lat: dms(-37, 39, 10.15610), Lng: dms(143, 55, 35.38390)}
+)
+
+func TestGeodesic(t *testing.T) {
+	tests := []struct {
+		name           string
+		from, to       models.Coordinate
+		meters         float64
+		initial, final float64
+	}{
+		{"Flinders Peak to Buninyong", flindersPeak, buninyong, 54972.271, dms(306, 52, 5.37), dms(307, 10, 25.07)},
+		{"one degree of the equator", models.Coordinate{}, models.Coordinate{Lng: 1}, 111319.491, 90, 90},
+		{"meridian quadrant", models.Coordinate{}, models.Coordinate{Lat: 90}, 10001965.729, 0, 0},
+		{"equatorial quadrant", models.Coordinate{}, models.Coordinate{Lng: 90}, 10018754.171, 90, 90},
+		{"westward", models.Coordinate{Lng: 1}, models.Coordinate{}, 111319.491, 270, 270},
+		{"same point", flindersPeak, flindersPeak, 0, 0, 0},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cs.Geodesic(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Geodesic: %v", err)
+			}
+			if !got.Ellipsoidal {
+				t.Errorf("Geodesic fell back to the sphere")
+			}
+			if math.Abs(got.Distance.Meters-tt.meters) > 0.001 {
+				t.Errorf("distance %.4f m, want %.3f m", got.Distance.Meters, tt.meters)
+			}
+			if bearingDiff(got.InitialBearing, tt.initial) > 0.01/3600 || bearingDiff(got.FinalBearing, tt.final) > 0.01/3600 {
+				t.Errorf("bearings %.6f, %.6f, want %.6f, %.6f", got.InitialBearing, got.FinalBearing, tt.initial, tt.final)
+			}
+		})
+	}
+}
+
+// TestGeodesicNearlyAntipodal checks the spherical fallback stays within
+// 0.5% of the ellipsoidal distance (GeographicLib gives 19936288.579 m for
+// the second pair)
+func TestGeodesicNearlyAntipodal(t *testing.T) {
+	cs := NewCoordinateService()
+	tests := []struct {
+		to     models.Coordinate
+		meters float64
+	}{
+		{models.Coordinate{Lng: 180}, 20003931.459},
+		{models.Coordinate{Lat: 0.5, Lng: 179.7}, 19936288.579},
+	}
+	for _, tt := range tests {
+		got, err := cs.Geodesic(models.Coordinate{}, tt.to)
+		if err != nil {
+			t.Fatalf("Geodesic: %v", err)
+		}
+		if math.Abs(got.Distance.Meters-tt.meters)/tt.meters > 0.005 {
+			t.Errorf("Geodesic to %+v = %.3f m, want within 0.5%% of %.3f m", tt.to, got.Distance.Meters, tt.meters)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	cs := NewCoordinateService()
+
+	got, err := cs.Destination(flindersPeak, dms(306, 52, 5.37), 54972.271)
+	if err != nil {
+		t.Fatalf("Destination: %v", err)
+	}
+	if d := geodesicDistance(got.To, buninyong); d > 0.001 {
+		t.Errorf("Destination reached %+v, %.4f m from Buninyong", got.To, d)
+	}
+	if bearingDiff(got.FinalBearing, dms(307, 10, 25.07)) > 0.01/3600 {
+		t.Errorf("final bearing %.6f, want %.6f", got.FinalBearing, dms(307, 10, 25.07))
+	}
+
+	// Travelling the inverse solution lands back on the target
+	targets := []models.Coordinate{{Lat: 51.5, Lng: -0.12}, {Lat: -33.87, Lng: 151.21}, {Lat: 64.1, Lng: -21.9}, {Lat: 1, Lng: -179.5}}
+	start := models.Coordinate{Lat: 30.4225, Lng: -86.6972}
+	for _, target := range targets {
+		inverse, err := cs.Geodesic(start, target)
+		if err != nil {
+			t.Fatalf("Geodesic: %v", err)
+		}
+		direct, err := cs.Destination(start, inverse.InitialBearing, inverse.Distance.Meters)
+		if err != nil {
+			t.Fatalf("Destination: %v", err)
+		}
+		if d := geodesicDistance(direct.To, target); d > 0.001 {
+			t.Errorf("Destination toward %+v landed %.4f m away", target, d)
+		}
+	}
+}
+
+func TestGeodesicErrors(t *testing.T) {
+	cs := NewCoordinateService()
+	if _, err := cs.Geodesic(models.Coordinate{Lat: 91}, models.Coordinate{}); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("Geodesic from latitude 91: %v, want ErrInvalidCoordinates", err)
+	}
+	if _, err := cs.Geodesic(models.Coordinate{}, models.Coordinate{Lng: -181}); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("Geodesic to longitude -181: %v, want ErrInvalidCoordinates", err)
+	}
+	for _, tt := range []struct{ bearing, meters float64 }{{0, -1}, {0, math.Inf(1)}, {math.NaN(), 10}} {
+		if _, err := cs.Destination(models.Coordinate{}, tt.bearing, tt.meters); !errors.Is(err, ErrInvalidCoordinates) {
+			t.Errorf("Destination(%v, %v): %v, want ErrInvalidCoordinates", tt.bearing, tt.meters, err)
+		}
+	}
+}
+
+func TestDistanceUnits(t *testing.T) {
+	tests := []struct {
+		value  float64
+		unit   string
+		meters float64
+	}{
+		{2, "", 2000},
+		{2, "km", 2000},
+		{2, " NM ", 3704},
+		{2, "mi", 3218.688},
+		{2, "m", 2},
+	}
+	for _, tt := range tests {
+		got, err := DistanceToMeters(tt.value, tt.unit)
+		if err != nil || math.Abs(got-tt.meters) > 1e-9 {
+			t.Errorf("DistanceToMeters(%v, %q) = %v, %v, want %v", tt.value, tt.unit, got, err, tt.meters)
+		}
+	}
+	if _, err := DistanceToMeters(1, "furlong"); err == nil {
+		t.Errorf("DistanceToMeters with an unknown unit succeeded")
+	}
+
+	d := NewDistance(1852)
+	if d.Km != 1.852 || d.NM != 1 || math.Abs(d.StatuteMiles-1.150779) > 1e-6 {
+		t.Errorf("NewDistance(1852) = %+v", d)
+	}
+}
+
+// wgs84SurfaceArea is the area of the WGS 84 ellipsoid in square metres
+const wgs84SurfaceArea = 510065621724088.5
+
+func TestAreaAndPerimeter(t *testing.T) {
+	square := []models.Coordinate{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}, {Lat: 1, Lng: 0}}
+	octant := []models.Coordinate{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 90}, {Lat: 90, Lng: 0}}
+
+	tests := []struct {
+		name              string
+		area, perimeter   float64
+		wantArea, wantPer float64
+		tolerance         float64 // relative, for the area
+	}{
+		// GeographicLib's Planimeter gives 12308778361.469 m² and
+		// 443770.917 m for this square of geodesics; great circles on the
+		// authalic sphere stand in for them
+		{"one degree square polygon", ringArea(square), ringPerimeter(square), 12308778361.469, 443770.917, 1e-6},
+		{"octant polygon", ringArea(octant), ringPerimeter(octant), wgs84SurfaceArea / 8, 30022685.630, 1e-12},
+		{"whole earth box", boxArea(models.Coordinate{Lat: -90, Lng: -180}, models.Coordinate{Lat: 90, Lng: 180}),
+			boxPerimeter(models.Coordinate{Lat: -90, Lng: -180}, models.Coordinate{Lat: 90, Lng: 180}),
+			wgs84SurfaceArea, 2 * 20003931.459, 1e-12},
+		{"northern hemisphere box", boxArea(models.Coordinate{Lat: 0, Lng: -180}, models.Coordinate{Lat: 90, Lng: 180}),
+			boxPerimeter(models.Coordinate{Lat: 0, Lng: -180}, models.Coordinate{Lat: 90, Lng: 180}),
+			wgs84SurfaceArea / 2, 2*10001965.729 + 2*math.Pi*wgs84A, 1e-12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.area-tt.wantArea)/tt.wantArea > tt.tolerance {
+				t.Errorf("area %.3f m², want %.3f m² to within %g", tt.area, tt.wantArea, tt.tolerance)
+			}
+			if math.Abs(tt.perimeter-tt.wantPer) > 0.001 {
+				t.Errorf("perimeter %.4f m, want %.3f m", tt.perimeter, tt.wantPer)
+			}
+		})
+	}
+}
+
+// TestAreaAwayFromTheEquator checks the area of a box does not depend on
+// the direction its ring is walked, matches a polygon with the same corners
+// when it is small, and shrinks with the cosine of the latitude as the old
+// planar scaling did not
+func TestAreaAwayFromTheEquator(t *testing.T) {
+	for _, lat := range []float64{0, 30, 60, 80} {
+		southWest := models.Coordinate{Lat: lat, Lng: 10}
+		northEast := models.Coordinate{Lat: lat + 0.01, Lng: 10.01}
+		ring := []models.Coordinate{southWest, {Lat: lat, Lng: 10.01}, northEast, {Lat: lat + 0.01, Lng: 10}}
+		reversed := []models.Coordinate{ring[3], ring[2], ring[1], ring[0]}
+
+		box := boxArea(southWest, northEast)
+		if polygon := ringArea(ring); math.Abs(polygon-box)/box > 1e-6 {
+			t.Errorf("latitude %v: polygon %.3f m², box %.3f m²", lat, polygon, box)
+		}
+		if ringArea(reversed) != ringArea(ring) {
+			t.Errorf("latitude %v: area depends on the ring direction", lat)
+		}
+		if perimeter := ringPerimeter(ring); math.Abs(perimeter-boxPerimeter(southWest, northEast)) > 0.01 {
+			t.Errorf("latitude %v: polygon perimeter %.3f m, box %.3f m", lat, perimeter, boxPerimeter(southWest, northEast))
+		}
+
+		equator := boxArea(models.Coordinate{Lng: 10}, models.Coordinate{Lat: 0.01, Lng: 10.01})
+		if ratio := box / equator; math.Abs(ratio-math.Cos(toRadians(lat+0.005))) > 0.01 {
+			t.Errorf("latitude %v: area ratio to the equator %.4f, want about cos(latitude)", lat, ratio)
+		}
+	}
+}
+
+func TestCapAreaAndPerimeter(t *testing.T) {
+	for _, radius := range []float64{1, 1000, 50000} {
+		if area := capArea(radius); math.Abs(area-math.Pi*radius*radius)/area > 1e-4 {
+			t.Errorf("capArea(%v) = %.6f, want about πr² = %.6f", radius, area, math.Pi*radius*radius)
+		}
+		if perimeter := capPerimeter(radius); math.Abs(perimeter-2*math.Pi*radius)/perimeter > 1e-4 {
+			t.Errorf("capPerimeter(%v) = %.6f, want about 2πr = %.6f", radius, perimeter, 2*math.Pi*radius)
+		}
+	}
+	if area := capArea(math.Pi * authalicRadius); math.Abs(area-wgs84SurfaceArea)/wgs84SurfaceArea > 1e-12 {
+		t.Errorf("a cap reaching the antipode covers %.3f m², want the whole ellipsoid", area)
+	}
+}
+
+func TestAreaUnits(t *testing.T) {
+	a := NewArea(1852 * 1852)
+	if math.Abs(a.SquareNM-1) > 1e-12 || math.Abs(a.SquareKm-3.429904) > 1e-12 || math.Abs(a.SquareMiles-1.324293) > 1e-6 {
+		t.Errorf("NewArea(1 nm²) = %+v", a)
+	}
+}
+
+// bearingDiff is the difference between two bearings in degrees
+func bearingDiff(a, b float64) float64 {
+	return math.Abs(math.Mod(a-b+540, 360) - 180)
+}
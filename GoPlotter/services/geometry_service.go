@@ -2,21 +2,43 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"sfaf-plotter/models"
+	"sfaf-plotter/spatial"
 	"sfaf-plotter/storage"
 
 	"github.com/google/uuid"
 )
 
+// authorizationAreaColor matches the field306 circle drawn in map.js
+const authorizationAreaColor = "#ff6b6b"
+
+var (
+	ErrGeometryNotFound = errors.New("geometry not found")
+	// ErrGeometryReadOnly is returned for geometries generated from other
+	// data: field306 areas and IRAC coordination areas
+	ErrGeometryReadOnly = errors.New("geometry is read-only")
+	ErrInvalidGeometry  = errors.New("invalid geometry")
+)
+
 type GeometryService struct {
 	storage       storage.Storage
 	markerService *MarkerService
 	serialService *SerialService
 	coordService  *CoordinateService
+
+	// index holds geometry bounds once IndexGeometries has run; every save
+	// and delete goes through storeGeometry(s) and removeGeometry(s) to keep
+	// it current
+	index *spatial.Index
 }
 
 func NewGeometryService(storage storage.Storage, markerService *MarkerService, serialService *SerialService, coordService *CoordinateService) *GeometryService {
@@ -30,6 +52,12 @@ func NewGeometryService(storage storage.Storage, markerService *MarkerService, s
 
 // CreateCircle matches your handleCircleCreation function
 func (gs *GeometryService) CreateCircle(req models.CreateCircleRequest) (*models.Geometry, error) {
+	center, err := gs.coordService.ResolvePosition(req.Center, req.Lat, req.Lng)
+	if err != nil {
+		return nil, err
+	}
+	req.Lat, req.Lng = center.Lat, center.Lng
+
 	// Default unit to km if not specified
 	if req.Unit == "" {
 		req.Unit = "km"
@@ -46,10 +74,6 @@ func (gs *GeometryService) CreateCircle(req models.CreateCircleRequest) (*models
 		radiusMeters = req.Radius * 1852 // nautical miles to meters
 	}
 
-	// Calculate area in square miles
-	areaM2 := math.Pi * math.Pow(radiusMeters, 2)
-	areaSqMi := areaM2 / 2.59e6
-
 	// Create center marker
 	centerMarkerReq := models.CreateMarkerRequest{
 		Latitude:   req.Lat,
@@ -59,43 +83,37 @@ func (gs *GeometryService) CreateCircle(req models.CreateCircleRequest) (*models
 		MarkerType: "circle-center",
 	}
 
-	_, err := gs.markerService.CreateMarker(centerMarkerReq)
+	centerMarker, err := gs.markerService.CreateMarker(centerMarkerReq)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create geometry
 	geometry := &models.Geometry{
-		ID:        uuid.New(),
-		Type:      models.GeometryTypeCircle,
-		Serial:    gs.serialService.GenerateSerial(),
-		Color:     req.Color,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Latitude:  req.Lat,
-		Longitude: req.Lng,
-		CircleProps: &models.CircleGeometry{
-			Radius:   radiusMeters,
-			RadiusKm: radiusMeters / 1000,
-			RadiusNm: radiusMeters / 1852,
-			Area:     areaSqMi,
-			Unit:     req.Unit,
-		},
-	}
-
-	err = gs.storage.SaveGeometry(geometry)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save geometry: %w", err)
+		ID:          uuid.New(),
+		Type:        models.GeometryTypeCircle,
+		Serial:      gs.serialService.GenerateSerial(),
+		Color:       req.Color,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Latitude:    req.Lat,
+		Longitude:   req.Lng,
+		CircleProps: circleProperties(radiusMeters, req.Unit),
 	}
 
-	return geometry, nil
+	return gs.saveDrawnGeometry(geometry, centerMarker.Marker)
 }
 
 // CreatePolygon matches your handlePolygonCreation function
 func (gs *GeometryService) CreatePolygon(req models.CreatePolygonRequest) (*models.Geometry, error) {
-	if len(req.Points) < 3 {
-		return nil, fmt.Errorf("polygon must have at least 3 points")
+	points, err := gs.resolvePoints(req.Points, req.Positions)
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		return nil, fmt.Errorf("%w: polygon must have at least 3 points", ErrInvalidGeometry)
 	}
+	req.Points = points
 
 	// Default color if not specified
 	if req.Color == "" {
@@ -114,36 +132,39 @@ func (gs *GeometryService) CreatePolygon(req models.CreatePolygonRequest) (*mode
 		MarkerType: "polygon-center",
 	}
 
-	_, err := gs.markerService.CreateMarker(centerMarkerReq)
+	centerMarker, err := gs.markerService.CreateMarker(centerMarkerReq)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate area (simplified - you might want a more accurate method)
-	area := gs.calculatePolygonArea(req.Points)
-
 	// Create geometry
 	geometry := &models.Geometry{
-		ID:        uuid.New(),
-		Type:      models.GeometryTypePolygon,
-		Serial:    gs.serialService.GenerateSerial(),
-		Color:     req.Color,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Latitude:  center.Lat,
-		Longitude: center.Lng,
-		PolygonProps: &models.PolygonGeometry{
-			Points:   req.Points,
-			Vertices: len(req.Points),
-			Area:     area,
-		},
+		ID:           uuid.New(),
+		Type:         models.GeometryTypePolygon,
+		Serial:       gs.serialService.GenerateSerial(),
+		Color:        req.Color,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Latitude:     center.Lat,
+		Longitude:    center.Lng,
+		PolygonProps: polygonProperties(req.Points),
 	}
 
-	return geometry, nil
+	return gs.saveDrawnGeometry(geometry, centerMarker.Marker)
 }
 
 // CreateRectangle matches your handleRectangleCreation function
 func (gs *GeometryService) CreateRectangle(req models.CreateRectangleRequest) (*models.Geometry, error) {
+	southWest, err := gs.coordService.ResolvePosition(req.SouthWestPosition, req.SouthWest.Lat, req.SouthWest.Lng)
+	if err != nil {
+		return nil, fmt.Errorf("south_west: %w", err)
+	}
+	northEast, err := gs.coordService.ResolvePosition(req.NorthEastPosition, req.NorthEast.Lat, req.NorthEast.Lng)
+	if err != nil {
+		return nil, fmt.Errorf("north_east: %w", err)
+	}
+	req.SouthWest, req.NorthEast = southWest, northEast
+
 	// Default color if not specified
 	if req.Color == "" {
 		req.Color = gs.getRandomColor()
@@ -162,36 +183,729 @@ func (gs *GeometryService) CreateRectangle(req models.CreateRectangleRequest) (*
 		MarkerType: "rectangle-center",
 	}
 
-	_, err := gs.markerService.CreateMarker(centerMarkerReq)
+	centerMarker, err := gs.markerService.CreateMarker(centerMarkerReq)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate area (simplified)
-	latDiff := math.Abs(req.NorthEast.Lat - req.SouthWest.Lat)
-	lngDiff := math.Abs(req.NorthEast.Lng - req.SouthWest.Lng)
-	area := latDiff * lngDiff * 3959 // Rough conversion to square miles
-
 	// Create geometry
 	geometry := &models.Geometry{
-		ID:        uuid.New(),
-		Type:      models.GeometryTypeRectangle,
-		Serial:    gs.serialService.GenerateSerial(),
-		Color:     req.Color,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Latitude:  centerLat,
-		Longitude: centerLng,
-		RectangleProps: &models.RectangleGeometry{
-			Bounds: []models.Coordinate{req.SouthWest, req.NorthEast},
-			Area:   area,
-		},
+		ID:             uuid.New(),
+		Type:           models.GeometryTypeRectangle,
+		Serial:         gs.serialService.GenerateSerial(),
+		Color:          req.Color,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Latitude:       centerLat,
+		Longitude:      centerLng,
+		RectangleProps: rectangleProperties(req.SouthWest, req.NorthEast),
+	}
+
+	return gs.saveDrawnGeometry(geometry, centerMarker.Marker)
+}
+
+// saveDrawnGeometry links a drawn geometry to its center marker and stores
+// it. The marker is removed again if the geometry can't be saved.
+func (gs *GeometryService) saveDrawnGeometry(geometry *models.Geometry, centerMarker *models.Marker) (*models.Geometry, error) {
+	markerID := centerMarker.ID
+	geometry.MarkerID = &markerID
+
+	if err := gs.storeGeometry(geometry); err != nil {
+		if deleteErr := gs.markerService.DeleteMarker(markerID.String()); deleteErr != nil {
+			log.Printf("❌ Center marker %s left behind: %v", markerID, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to save geometry: %w", err)
 	}
+	return geometry, nil
+}
 
+// GetGeometry finds a stored geometry by ID
+func (gs *GeometryService) GetGeometry(id string) (*models.Geometry, error) {
+	geometry, err := gs.storage.GetGeometry(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGeometryNotFound, id)
+	}
 	return geometry, nil
 }
 
+// CenterMarker returns the marker at the center of a drawn geometry, or nil
+// for geometries that have none
+func (gs *GeometryService) CenterMarker(geometry *models.Geometry) (*models.Marker, error) {
+	if geometry.MarkerID == nil || geometry.Source != "" {
+		return nil, nil
+	}
+	response, err := gs.markerService.GetMarker(geometry.MarkerID.String())
+	if err != nil {
+		return nil, err
+	}
+	return response.Marker, nil
+}
+
+// UpdateGeometry edits a drawn geometry: its points or bounds, radius,
+// color, and the notes on its center marker. The center marker follows the
+// geometry when it moves.
+func (gs *GeometryService) UpdateGeometry(id string, req models.UpdateGeometryRequest) (*models.Geometry, error) {
+	stored, err := gs.GetGeometry(id)
+	if err != nil {
+		return nil, err
+	}
+	// Update a copy so the stored geometry stays as it was until saved
+	updated := *stored
+	geometry := &updated
+	if geometry.Source != "" {
+		return nil, fmt.Errorf("%w: %s geometries are generated; edit their source instead", ErrGeometryReadOnly, geometry.Source)
+	}
+
+	if req.Color != nil {
+		geometry.Color = *req.Color
+	}
+
+	switch geometry.Type {
+	case models.GeometryTypeCircle:
+		if len(req.Points) > 0 || len(req.Positions) > 0 || req.SouthWest != nil || req.NorthEast != nil {
+			return nil, fmt.Errorf("%w: circles take a radius, not points or bounds", ErrInvalidGeometry)
+		}
+		if err := gs.updateCircle(geometry, req); err != nil {
+			return nil, err
+		}
+	case models.GeometryTypePolygon:
+		if req.Radius != nil || req.SouthWest != nil || req.NorthEast != nil {
+			return nil, fmt.Errorf("%w: polygons take points, not a radius or bounds", ErrInvalidGeometry)
+		}
+		if err := gs.updatePolygon(geometry, req); err != nil {
+			return nil, err
+		}
+	case models.GeometryTypeRectangle:
+		if req.Radius != nil || len(req.Points) > 0 || len(req.Positions) > 0 {
+			return nil, fmt.Errorf("%w: rectangles take bounds, not a radius or points", ErrInvalidGeometry)
+		}
+		if err := gs.updateRectangle(geometry, req); err != nil {
+			return nil, err
+		}
+	}
+
+	geometry.UpdatedAt = time.Now()
+	if err := gs.storeGeometry(geometry); err != nil {
+		return nil, fmt.Errorf("failed to save geometry: %w", err)
+	}
+
+	if geometry.MarkerID != nil {
+		lat, lng := geometry.Latitude, geometry.Longitude
+		markerReq := models.UpdateMarkerRequest{Latitude: &lat, Longitude: &lng, Notes: req.Notes}
+		if _, err := gs.markerService.UpdateMarker(geometry.MarkerID.String(), markerReq); err != nil {
+			return nil, fmt.Errorf("failed to update center marker: %w", err)
+		}
+	}
+	return geometry, nil
+}
+
+func (gs *GeometryService) updateCircle(geometry *models.Geometry, req models.UpdateGeometryRequest) error {
+	if req.Radius == nil && req.Unit == nil {
+		return nil
+	}
+
+	unit := geometry.CircleProps.Unit
+	if req.Unit != nil {
+		unit = *req.Unit
+	}
+	radius := geometry.CircleProps.RadiusKm
+	if unit == "nm" {
+		radius = geometry.CircleProps.RadiusNm
+	}
+	if req.Radius != nil {
+		radius = *req.Radius
+	}
+
+	meters, err := DistanceToMeters(radius, unit)
+	if err != nil || (unit != "km" && unit != "nm") {
+		return fmt.Errorf("%w: unit must be km or nm", ErrInvalidGeometry)
+	}
+	if meters <= 0 {
+		return fmt.Errorf("%w: radius must be more than zero", ErrInvalidGeometry)
+	}
+	geometry.CircleProps = circleProperties(meters, unit)
+	return nil
+}
+
+func (gs *GeometryService) updatePolygon(geometry *models.Geometry, req models.UpdateGeometryRequest) error {
+	points, err := gs.resolvePoints(req.Points, req.Positions)
+	if err != nil || points == nil {
+		return err
+	}
+
+	center := gs.calculateCentroid(points)
+	geometry.Latitude, geometry.Longitude = center.Lat, center.Lng
+	geometry.PolygonProps = polygonProperties(points)
+	return nil
+}
+
+func (gs *GeometryService) updateRectangle(geometry *models.Geometry, req models.UpdateGeometryRequest) error {
+	if req.SouthWest == nil && req.NorthEast == nil {
+		return nil
+	}
+	if req.SouthWest == nil || req.NorthEast == nil {
+		return fmt.Errorf("%w: rectangles need both south_west and north_east", ErrInvalidGeometry)
+	}
+	for _, corner := range []*models.Coordinate{req.SouthWest, req.NorthEast} {
+		if err := gs.coordService.CheckCoordinate(corner.Lat, corner.Lng); err != nil {
+			return err
+		}
+	}
+
+	geometry.Latitude = (req.SouthWest.Lat + req.NorthEast.Lat) / 2
+	geometry.Longitude = (req.SouthWest.Lng + req.NorthEast.Lng) / 2
+	geometry.RectangleProps = rectangleProperties(*req.SouthWest, *req.NorthEast)
+	return nil
+}
+
+// resolvePoints reads polygon points given as numbers or as positions in
+// text. It returns nil when neither is given.
+func (gs *GeometryService) resolvePoints(points []models.Coordinate, positions []string) ([]models.Coordinate, error) {
+	if len(positions) > 0 {
+		points = make([]models.Coordinate, 0, len(positions))
+		for i, position := range positions {
+			point, err := gs.coordService.ParsePosition(position)
+			if err != nil {
+				return nil, fmt.Errorf("point %d: %w", i+1, err)
+			}
+			points = append(points, point)
+		}
+	} else {
+		if len(points) == 0 {
+			return nil, nil
+		}
+		for _, point := range points {
+			if err := gs.coordService.CheckCoordinate(point.Lat, point.Lng); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(points) < 3 {
+		return nil, fmt.Errorf("%w: polygon must have at least 3 points", ErrInvalidGeometry)
+	}
+	return points, nil
+}
+
+// DeleteGeometry removes a drawn geometry and its center marker, and
+// returns what was deleted
+func (gs *GeometryService) DeleteGeometry(id string) (*models.Geometry, error) {
+	geometry, err := gs.GetGeometry(id)
+	if err != nil {
+		return nil, err
+	}
+	if geometry.Source != "" {
+		return nil, fmt.Errorf("%w: %s geometries are generated; edit their source instead", ErrGeometryReadOnly, geometry.Source)
+	}
+
+	if err := gs.removeGeometry(geometry.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete geometry: %w", err)
+	}
+	if geometry.MarkerID != nil {
+		if err := gs.markerService.DeleteMarker(geometry.MarkerID.String()); err != nil {
+			return nil, fmt.Errorf("failed to delete center marker: %w", err)
+		}
+	}
+	return geometry, nil
+}
+
+// DeleteMarkerGeometries removes the geometries that belong to a deleted
+// marker: the shape it is the center of, and its field306 area
+func (gs *GeometryService) DeleteMarkerGeometries(markerID string) error {
+	geometries, err := gs.storage.GetGeometriesByMarkerID(markerID)
+	if err != nil {
+		return fmt.Errorf("failed to load geometries: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(geometries))
+	for i, geometry := range geometries {
+		ids[i] = geometry.ID
+	}
+	if err := gs.removeGeometries(ids); err != nil {
+		return fmt.Errorf("failed to delete geometries of marker %s: %w", markerID, err)
+	}
+	return nil
+}
+
+// DeleteAllMarkerGeometries removes every geometry that belongs to a
+// marker, for when all markers are deleted. Coordination areas stay.
+func (gs *GeometryService) DeleteAllMarkerGeometries() error {
+	return gs.deleteGeometriesWhere(func(geometry *models.Geometry) bool {
+		return geometry.MarkerID != nil
+	})
+}
+
+func (gs *GeometryService) deleteGeometriesWhere(match func(*models.Geometry) bool) error {
+	geometries, err := gs.storage.GetAllGeometries()
+	if err != nil {
+		return fmt.Errorf("failed to load geometries: %w", err)
+	}
+
+	var ids []uuid.UUID
+	for _, geometry := range geometries {
+		if match(geometry) {
+			ids = append(ids, geometry.ID)
+		}
+	}
+	if err := gs.removeGeometries(ids); err != nil {
+		return fmt.Errorf("failed to delete geometries: %w", err)
+	}
+	return nil
+}
+
+// IndexGeometries loads the bounds of every stored geometry into the
+// spatial index behind FindGeometries and GeometriesAt, and returns the
+// number indexed
+func (gs *GeometryService) IndexGeometries() (int, error) {
+	geometries, err := gs.storage.GetAllGeometries()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load geometries: %w", err)
+	}
+
+	index := spatial.NewIndex()
+	for _, geometry := range geometries {
+		if box, ok := geometryBounds(geometry); ok {
+			index.Insert(geometry.ID.String(), box)
+		}
+	}
+	gs.index = index
+	return index.Len(), nil
+}
+
+// storeGeometry saves a geometry and updates its indexed bounds
+func (gs *GeometryService) storeGeometry(geometry *models.Geometry) error {
+	if err := gs.storage.SaveGeometry(geometry); err != nil {
+		return err
+	}
+	if gs.index != nil {
+		if box, ok := geometryBounds(geometry); ok {
+			gs.index.Insert(geometry.ID.String(), box)
+		} else {
+			gs.index.Remove(geometry.ID.String())
+		}
+	}
+	return nil
+}
+
+// storeGeometries saves a batch of geometries with one storage write and
+// updates their indexed bounds
+func (gs *GeometryService) storeGeometries(geometries []*models.Geometry) error {
+	if len(geometries) == 0 {
+		return nil
+	}
+	if err := gs.storage.SaveGeometries(geometries); err != nil {
+		return err
+	}
+	if gs.index != nil {
+		for _, geometry := range geometries {
+			if box, ok := geometryBounds(geometry); ok {
+				gs.index.Insert(geometry.ID.String(), box)
+			} else {
+				gs.index.Remove(geometry.ID.String())
+			}
+		}
+	}
+	return nil
+}
+
+// removeGeometry deletes a geometry and drops it from the index
+func (gs *GeometryService) removeGeometry(id uuid.UUID) error {
+	if err := gs.storage.DeleteGeometry(id.String()); err != nil {
+		return err
+	}
+	if gs.index != nil {
+		gs.index.Remove(id.String())
+	}
+	return nil
+}
+
+// removeGeometries deletes a batch of geometries with one storage write
+func (gs *GeometryService) removeGeometries(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	if err := gs.storage.DeleteGeometries(keys); err != nil {
+		return err
+	}
+	if gs.index != nil {
+		for _, key := range keys {
+			gs.index.Remove(key)
+		}
+	}
+	return nil
+}
+
+// FindGeometries returns the geometries whose bounds overlap box, oldest
+// first
+func (gs *GeometryService) FindGeometries(box models.BoundingBox) ([]*models.Geometry, error) {
+	if gs.index == nil {
+		all, err := gs.GetAllGeometries()
+		if err != nil {
+			return nil, err
+		}
+		var geometries []*models.Geometry
+		for _, geometry := range all {
+			if bounds, ok := geometryBounds(geometry); ok && spatial.Overlaps(bounds, box) {
+				geometries = append(geometries, geometry)
+			}
+		}
+		return geometries, nil
+	}
+
+	var geometries []*models.Geometry
+	for _, id := range gs.index.Search(box) {
+		geometry, err := gs.storage.GetGeometry(id)
+		if err != nil {
+			continue // deleted since the search
+		}
+		geometries = append(geometries, geometry)
+	}
+	sort.Slice(geometries, func(i, j int) bool {
+		return geometries[i].CreatedAt.Before(geometries[j].CreatedAt)
+	})
+	return geometries, nil
+}
+
+// GeometriesAt returns the geometries containing a point, oldest first
+func (gs *GeometryService) GeometriesAt(lat, lng float64) ([]*models.Geometry, error) {
+	candidates, err := gs.FindGeometries(spatial.PointBox(lat, lng))
+	if err != nil {
+		return nil, err
+	}
+
+	var geometries []*models.Geometry
+	for _, geometry := range candidates {
+		if geometryContains(geometry, lat, lng) {
+			geometries = append(geometries, geometry)
+		}
+	}
+	return geometries, nil
+}
+
+// GetAllGeometries returns every stored geometry, oldest first
+func (gs *GeometryService) GetAllGeometries() ([]*models.Geometry, error) {
+	geometries, err := gs.storage.GetAllGeometries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get geometries: %w", err)
+	}
+
+	sort.Slice(geometries, func(i, j int) bool {
+		return geometries[i].CreatedAt.Before(geometries[j].CreatedAt)
+	})
+	return geometries, nil
+}
+
+// ParseAuthorizedRadius reads an SFAF field306 value: the radius in
+// kilometres around the transmitter, optionally followed by B (transmitter
+// and receivers operate within it) or T (transmitter only), e.g. "30B".
+func ParseAuthorizedRadius(value string) (float64, string, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	scope := ""
+	if strings.HasSuffix(text, models.RadiusScopeBoth) || strings.HasSuffix(text, models.RadiusScopeTransmitter) {
+		scope = text[len(text)-1:]
+		text = text[:len(text)-1]
+	}
+
+	radiusKm, err := strconv.ParseFloat(text, 64)
+	if err != nil || radiusKm <= 0 || math.IsInf(radiusKm, 0) {
+		return 0, "", fmt.Errorf("invalid authorized radius %q (expected kilometres optionally followed by B or T, e.g. 30B)", value)
+	}
+	return radiusKm, scope, nil
+}
+
+// SyncAuthorizationArea keeps a marker's field306 circle in step with its
+// SFAF record: it is created, moved or resized to match radius, or removed
+// when radius is empty.
+func (gs *GeometryService) SyncAuthorizationArea(marker models.Marker, radius string) (*models.Geometry, error) {
+	if strings.TrimSpace(radius) == "" {
+		return nil, gs.RemoveAuthorizationArea(marker.ID)
+	}
+
+	radiusKm, scope, err := ParseAuthorizedRadius(radius)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := gs.findAuthorizationArea(marker.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	geometry := authorizationArea(existing, marker, radiusKm, scope)
+	if err := gs.storeGeometry(geometry); err != nil {
+		return nil, fmt.Errorf("failed to save authorization area: %w", err)
+	}
+	return geometry, nil
+}
+
+// SyncAuthorizationAreas does SyncAuthorizationArea for many markers, such
+// as one SFAF import, with one storage write for the circles saved and one
+// for those removed. radii[i] is the field306 value of markers[i]. If any
+// radius is invalid nothing is written.
+func (gs *GeometryService) SyncAuthorizationAreas(markers []models.Marker, radii []string) error {
+	var save []*models.Geometry
+	var remove []uuid.UUID
+
+	for i, marker := range markers {
+		existing, err := gs.findAuthorizationArea(marker.ID)
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(radii[i]) == "" {
+			if existing != nil {
+				remove = append(remove, existing.ID)
+			}
+			continue
+		}
+
+		radiusKm, scope, err := ParseAuthorizedRadius(radii[i])
+		if err != nil {
+			return fmt.Errorf("marker %s: %w", marker.ID, err)
+		}
+		save = append(save, authorizationArea(existing, marker, radiusKm, scope))
+	}
+
+	if err := gs.storeGeometries(save); err != nil {
+		return fmt.Errorf("failed to save authorization areas: %w", err)
+	}
+	if err := gs.removeGeometries(remove); err != nil {
+		return fmt.Errorf("failed to delete authorization areas: %w", err)
+	}
+	return nil
+}
+
+// authorizationArea builds a marker's field306 circle: a copy of existing
+// (nil for a new one) centered on the marker with the given radius
+func authorizationArea(existing *models.Geometry, marker models.Marker, radiusKm float64, scope string) *models.Geometry {
+	var geometry models.Geometry
+	if existing != nil {
+		geometry = *existing
+	} else {
+		markerID := marker.ID
+		geometry = models.Geometry{
+			ID:        uuid.New(),
+			Type:      models.GeometryTypeCircle,
+			Color:     authorizationAreaColor,
+			CreatedAt: time.Now(),
+			MarkerID:  &markerID,
+			Source:    models.GeometrySourceField306,
+		}
+	}
+
+	geometry.Serial = marker.Serial
+	geometry.Latitude = marker.Latitude
+	geometry.Longitude = marker.Longitude
+	geometry.UpdatedAt = time.Now()
+	geometry.CircleProps = circleProperties(radiusKm*1000, "km")
+	geometry.CircleProps.Scope = scope
+	return &geometry
+}
+
+// MoveAuthorizationArea recenters a marker's field306 circle on the
+// marker after it has moved. Markers without one are left alone.
+func (gs *GeometryService) MoveAuthorizationArea(marker models.Marker) error {
+	existing, err := gs.findAuthorizationArea(marker.ID)
+	if err != nil || existing == nil {
+		return err
+	}
+
+	geometry := *existing
+	geometry.Latitude = marker.Latitude
+	geometry.Longitude = marker.Longitude
+	geometry.UpdatedAt = time.Now()
+	if err := gs.storeGeometry(&geometry); err != nil {
+		return fmt.Errorf("failed to save authorization area: %w", err)
+	}
+	return nil
+}
+
+// RemoveAuthorizationArea deletes a marker's field306 circle, if it has one
+func (gs *GeometryService) RemoveAuthorizationArea(markerID uuid.UUID) error {
+	geometry, err := gs.findAuthorizationArea(markerID)
+	if err != nil || geometry == nil {
+		return err
+	}
+
+	if err := gs.removeGeometry(geometry.ID); err != nil {
+		return fmt.Errorf("failed to delete authorization area: %w", err)
+	}
+	return nil
+}
+
+// findAuthorizationArea looks a marker's field306 circle up among the
+// geometries that belong to the marker
+func (gs *GeometryService) findAuthorizationArea(markerID uuid.UUID) (*models.Geometry, error) {
+	geometries, err := gs.storage.GetGeometriesByMarkerID(markerID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get geometries: %w", err)
+	}
+
+	for _, geometry := range geometries {
+		if geometry.Source == models.GeometrySourceField306 && geometry.MarkerID != nil && *geometry.MarkerID == markerID {
+			return geometry, nil
+		}
+	}
+	return nil, nil
+}
+
+// RecomputeMeasurements works out the area and perimeter of every stored
+// geometry again and saves those that differ, such as geometries saved with
+// the old planar estimates. It returns the number of geometries saved.
+func (gs *GeometryService) RecomputeMeasurements() (int, error) {
+	geometries, err := gs.storage.GetAllGeometries()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load geometries: %w", err)
+	}
+
+	updated := 0
+	for _, geometry := range geometries {
+		changed := false
+		switch {
+		case geometry.CircleProps != nil:
+			props := circleProperties(geometry.CircleProps.Radius, geometry.CircleProps.Unit)
+			props.Scope = geometry.CircleProps.Scope
+			changed = *props != *geometry.CircleProps
+			geometry.CircleProps = props
+		case geometry.PolygonProps != nil:
+			props := polygonProperties(geometry.PolygonProps.Points)
+			changed = props.Areas != geometry.PolygonProps.Areas || props.Perimeter != geometry.PolygonProps.Perimeter
+			geometry.PolygonProps = props
+		case geometry.RectangleProps != nil && len(geometry.RectangleProps.Bounds) == 2:
+			props := rectangleProperties(geometry.RectangleProps.Bounds[0], geometry.RectangleProps.Bounds[1])
+			changed = props.Areas != geometry.RectangleProps.Areas || props.Perimeter != geometry.RectangleProps.Perimeter
+			geometry.RectangleProps = props
+		}
+		if !changed {
+			continue
+		}
+		if err := gs.storeGeometry(geometry); err != nil {
+			return updated, fmt.Errorf("failed to save geometry %s: %w", geometry.ID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
 // Helper functions
+func circleProperties(radiusMeters float64, unit string) *models.CircleGeometry {
+	areas := NewArea(capArea(radiusMeters))
+	return &models.CircleGeometry{
+		Radius:    radiusMeters,
+		RadiusKm:  radiusMeters / metersPerKm,
+		RadiusNm:  radiusMeters / metersPerNauticalMile,
+		Area:      areas.SquareMiles,
+		Areas:     areas,
+		Perimeter: NewDistance(capPerimeter(radiusMeters)),
+		Unit:      unit,
+	}
+}
+
+func polygonProperties(points []models.Coordinate) *models.PolygonGeometry {
+	areas := NewArea(ringArea(points))
+	return &models.PolygonGeometry{
+		Points:    points,
+		Vertices:  len(points),
+		Area:      areas.SquareMiles,
+		Areas:     areas,
+		Perimeter: NewDistance(ringPerimeter(points)),
+	}
+}
+
+func rectangleProperties(southWest, northEast models.Coordinate) *models.RectangleGeometry {
+	areas := NewArea(boxArea(southWest, northEast))
+	return &models.RectangleGeometry{
+		Bounds:    []models.Coordinate{southWest, northEast},
+		Area:      areas.SquareMiles,
+		Areas:     areas,
+		Perimeter: NewDistance(boxPerimeter(southWest, northEast)),
+	}
+}
+
+// earthRadiusKm is the mean Earth radius
+const earthRadiusKm = 6371.0
+
+// haversineKm is the great-circle distance between two points
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// geometryContains reports whether a point lies inside a circle, rectangle
+// or polygon. Polygon edges are straight in latitude/longitude.
+func geometryContains(geometry *models.Geometry, lat, lng float64) bool {
+	switch geometry.Type {
+	case models.GeometryTypeCircle:
+		if geometry.CircleProps == nil {
+			return false
+		}
+		center := models.Coordinate{Lat: geometry.Latitude, Lng: geometry.Longitude}
+		return geodesicDistance(center, models.Coordinate{Lat: lat, Lng: lng}) <= geometry.CircleProps.Radius
+	case models.GeometryTypeRectangle:
+		// A rectangle is its own bounds, which wrap the antimeridian when the
+		// west edge is east of the east edge
+		box, ok := geometryBounds(geometry)
+		return ok && box.Contains(lat, lng)
+	case models.GeometryTypePolygon:
+		if geometry.PolygonProps == nil {
+			return false
+		}
+		return pointInPolygon(geometry.PolygonProps.Points, lat, lng)
+	}
+	return false
+}
+
+// geometryBounds is a box holding everything geometryContains accepts. ok is
+// false for a geometry without its properties.
+func geometryBounds(geometry *models.Geometry) (box models.BoundingBox, ok bool) {
+	switch geometry.Type {
+	case models.GeometryTypeCircle:
+		if geometry.CircleProps == nil {
+			return box, false
+		}
+		center := models.Coordinate{Lat: geometry.Latitude, Lng: geometry.Longitude}
+		return radiusBounds(center, geometry.CircleProps.Radius), true
+	case models.GeometryTypeRectangle:
+		if geometry.RectangleProps == nil || len(geometry.RectangleProps.Bounds) != 2 {
+			return box, false
+		}
+		sw, ne := geometry.RectangleProps.Bounds[0], geometry.RectangleProps.Bounds[1]
+		return models.BoundingBox{South: sw.Lat, West: sw.Lng, North: ne.Lat, East: ne.Lng}, true
+	case models.GeometryTypePolygon:
+		if geometry.PolygonProps == nil || len(geometry.PolygonProps.Points) == 0 {
+			return box, false
+		}
+		first := geometry.PolygonProps.Points[0]
+		box = models.BoundingBox{South: first.Lat, West: first.Lng, North: first.Lat, East: first.Lng}
+		for _, point := range geometry.PolygonProps.Points[1:] {
+			box.South, box.North = math.Min(box.South, point.Lat), math.Max(box.North, point.Lat)
+			box.West, box.East = math.Min(box.West, point.Lng), math.Max(box.East, point.Lng)
+		}
+		return box, true
+	}
+	return box, false
+}
+
+// pointInPolygon casts a ray east from the point and counts edge crossings
+func pointInPolygon(points []models.Coordinate, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		a, b := points[i], points[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lng < (b.Lng-a.Lng)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
 func (gs *GeometryService) getRandomColor() string {
 	colors := []string{"#FF6B6B", "#4ECDC4", "#45B7D1", "#96CEB4", "#FCEA2B", "#FF9FF3", "#54A0FF"}
 	return colors[time.Now().UnixNano()%int64(len(colors))]
@@ -208,23 +922,3 @@ func (gs *GeometryService) calculateCentroid(points []models.Coordinate) models.
 		Lng: sumLng / float64(len(points)),
 	}
 }
-
-func (gs *GeometryService) calculatePolygonArea(points []models.Coordinate) float64 {
-	// Simplified area calculation - you might want to use a more accurate method
-	// This is a rough approximation
-	if len(points) < 3 {
-		return 0
-	}
-
-	var area float64
-	n := len(points)
-
-	for i := 0; i < n; i++ {
-		j := (i + 1) % n
-		area += points[i].Lat * points[j].Lng
-		area -= points[j].Lat * points[i].Lng
-	}
-
-	area = math.Abs(area) / 2.0
-	return area * 3959 // Rough conversion to square miles
-}
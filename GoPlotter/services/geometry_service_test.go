@@ -0,0 +1,328 @@
+// geometry_service_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+
+	"github.com/google/uuid"
+)
+
+func circleGeometry(lat, lng, radiusKm float64) *models.Geometry {
+	return &models.Geometry{ID: uuid.New(), Type: models.GeometryTypeCircle, Latitude: lat, Longitude: lng,
+		CircleProps: circleProperties(radiusKm*metersPerKm, "km")}
+}
+
+func rectangleGeometry(south, west, north, east float64) *models.Geometry {
+	return &models.Geometry{ID: uuid.New(), Type: models.GeometryTypeRectangle,
+		Latitude: (south + north) / 2, Longitude: (west + east) / 2,
+		RectangleProps: rectangleProperties(models.Coordinate{Lat: south, Lng: west}, models.Coordinate{Lat: north, Lng: east})}
+}
+
+func polygonGeometry(points ...models.Coordinate) *models.Geometry {
+	return &models.Geometry{ID: uuid.New(), Type: models.GeometryTypePolygon, PolygonProps: polygonProperties(points)}
+}
+
+func TestGeometryContains(t *testing.T) {
+	// An L-shaped polygon: the 2x2 square at the origin without its
+	// north-east quarter
+	lShape := polygonGeometry(
+		models.Coordinate{Lat: 0, Lng: 0}, models.Coordinate{Lat: 0, Lng: 2}, models.Coordinate{Lat: 1, Lng: 2},
+		models.Coordinate{Lat: 1, Lng: 1}, models.Coordinate{Lat: 2, Lng: 1}, models.Coordinate{Lat: 2, Lng: 0},
+	)
+	tests := []struct {
+		name     string
+		geometry *models.Geometry
+		lat, lng float64
+		contains bool
+	}{
+		{"circle: center", circleGeometry(30, -86, 10), 30, -86, true},
+		{"circle: inside the radius", circleGeometry(30, -86, 10), 30.08, -86, true},
+		{"circle: outside the radius", circleGeometry(30, -86, 10), 30.1, -86, false},
+		{"circle: no properties", &models.Geometry{Type: models.GeometryTypeCircle, Latitude: 30, Longitude: -86}, 30, -86, false},
+		{"rectangle: inside", rectangleGeometry(24, -83, 31.5, -77), 28.5, -81.4, true},
+		{"rectangle: on the edge", rectangleGeometry(24, -83, 31.5, -77), 24, -80, true},
+		{"rectangle: west of it", rectangleGeometry(24, -83, 31.5, -77), 28.5, -86, false},
+		{"rectangle: north of it", rectangleGeometry(24, -83, 31.5, -77), 32, -80, false},
+		{"rectangle: across the antimeridian, east of it", rectangleGeometry(-10, 170, 10, -170), 0, -175, true},
+		{"rectangle: across the antimeridian, west of it", rectangleGeometry(-10, 170, 10, -170), 0, 175, true},
+		{"rectangle: across the antimeridian, outside", rectangleGeometry(-10, 170, 10, -170), 0, 0, false},
+		{"rectangle: no properties", &models.Geometry{Type: models.GeometryTypeRectangle}, 0, 0, false},
+		{"polygon: inside", lShape, 0.5, 1.5, true},
+		{"polygon: inside the other arm", lShape, 1.5, 0.5, true},
+		{"polygon: in the notch", lShape, 1.5, 1.5, false},
+		{"polygon: outside", lShape, -0.5, 0.5, false},
+		{"polygon: no properties", &models.Geometry{Type: models.GeometryTypePolygon}, 0.5, 0.5, false},
+		{"unknown type", &models.Geometry{Type: "hexagon"}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := geometryContains(tt.geometry, tt.lat, tt.lng); got != tt.contains {
+				t.Errorf("geometryContains(%.2f, %.2f) = %v, want %v", tt.lat, tt.lng, got, tt.contains)
+			}
+		})
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	triangle := []models.Coordinate{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 4}, {Lat: 4, Lng: 0}}
+	tests := []struct {
+		lat, lng float64
+		inside   bool
+	}{
+		{1, 1, true},
+		{1.9, 1.9, true},
+		{2.1, 2.1, false},
+		{-1, 1, false},
+		{1, -1, false},
+		{5, 5, false},
+	}
+	for _, tt := range tests {
+		if got := pointInPolygon(triangle, tt.lat, tt.lng); got != tt.inside {
+			t.Errorf("pointInPolygon(%.1f, %.1f) = %v, want %v", tt.lat, tt.lng, got, tt.inside)
+		}
+	}
+	if pointInPolygon(nil, 0, 0) {
+		t.Errorf("empty polygon contains a point")
+	}
+}
+
+func TestGeometriesAtAntimeridian(t *testing.T) {
+	pacific := rectangleGeometry(-10, 170, 10, -170)
+	gs := newGeometryTestService(t, pacific, rectangleGeometry(24, -83, 31.5, -77))
+
+	for _, lng := range []float64{170, 179.9, 180, -180, -179.9, -170} {
+		found, err := gs.GeometriesAt(0, lng)
+		if err != nil || len(found) != 1 || found[0].ID != pacific.ID {
+			t.Errorf("GeometriesAt(0, %.1f): %d geometries, %v", lng, len(found), err)
+		}
+	}
+	if found, _ := gs.GeometriesAt(0, 160); len(found) != 0 {
+		t.Errorf("GeometriesAt(0, 160): %d geometries", len(found))
+	}
+}
+
+// newGeometryTestService stores the geometries without center markers, so
+// no marker service is needed
+func newGeometryTestService(t *testing.T, geometries ...*models.Geometry) *GeometryService {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	for _, geometry := range geometries {
+		if err := store.SaveGeometry(geometry); err != nil {
+			t.Fatalf("SaveGeometry: %v", err)
+		}
+	}
+	gs := NewGeometryService(store, nil, nil, NewCoordinateService())
+	if _, err := gs.IndexGeometries(); err != nil {
+		t.Fatalf("IndexGeometries: %v", err)
+	}
+	return gs
+}
+
+func TestUpdateGeometry(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	text := func(v string) *string { return &v }
+	square := []models.Coordinate{{Lat: 10, Lng: 10}, {Lat: 10, Lng: 12}, {Lat: 12, Lng: 12}, {Lat: 12, Lng: 10}}
+
+	tests := []struct {
+		name     string
+		geometry *models.Geometry
+		req      models.UpdateGeometryRequest
+		err      error
+		check    func(t *testing.T, updated *models.Geometry)
+	}{
+		{
+			name:     "circle radius",
+			geometry: circleGeometry(30, -86, 10),
+			req:      models.UpdateGeometryRequest{Radius: float(20), Color: text("#000000")},
+			check: func(t *testing.T, updated *models.Geometry) {
+				if updated.CircleProps.Radius != 20e3 || updated.CircleProps.RadiusKm != 20 || updated.Color != "#000000" {
+					t.Errorf("circle %+v, color %s", updated.CircleProps, updated.Color)
+				}
+			},
+		},
+		{
+			name:     "circle radius in nautical miles",
+			geometry: circleGeometry(30, -86, 10),
+			req:      models.UpdateGeometryRequest{Radius: float(10), Unit: text("nm")},
+			check: func(t *testing.T, updated *models.Geometry) {
+				if updated.CircleProps.Radius != 18520 || updated.CircleProps.Unit != "nm" {
+					t.Errorf("circle %+v", updated.CircleProps)
+				}
+			},
+		},
+		{
+			name:     "circle with points",
+			geometry: circleGeometry(30, -86, 10),
+			req:      models.UpdateGeometryRequest{Points: square, Color: text("#000000")},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "circle with a zero radius",
+			geometry: circleGeometry(30, -86, 10),
+			req:      models.UpdateGeometryRequest{Radius: float(0)},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "circle in miles",
+			geometry: circleGeometry(30, -86, 10),
+			req:      models.UpdateGeometryRequest{Unit: text("mi")},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "polygon points",
+			geometry: polygonGeometry(models.Coordinate{Lat: 0, Lng: 0}, models.Coordinate{Lat: 0, Lng: 1}, models.Coordinate{Lat: 1, Lng: 0}),
+			req:      models.UpdateGeometryRequest{Points: square},
+			check: func(t *testing.T, updated *models.Geometry) {
+				if updated.PolygonProps.Vertices != 4 || updated.Latitude != 11 || updated.Longitude != 11 {
+					t.Errorf("polygon of %d points centred at %.2f, %.2f", updated.PolygonProps.Vertices, updated.Latitude, updated.Longitude)
+				}
+			},
+		},
+		{
+			name:     "polygon with two points",
+			geometry: polygonGeometry(square...),
+			req:      models.UpdateGeometryRequest{Points: square[:2]},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "polygon with a radius",
+			geometry: polygonGeometry(square...),
+			req:      models.UpdateGeometryRequest{Radius: float(5)},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "rectangle bounds",
+			geometry: rectangleGeometry(24, -83, 31.5, -77),
+			req:      models.UpdateGeometryRequest{SouthWest: &models.Coordinate{Lat: 10, Lng: 10}, NorthEast: &models.Coordinate{Lat: 12, Lng: 14}},
+			check: func(t *testing.T, updated *models.Geometry) {
+				if updated.Latitude != 11 || updated.Longitude != 12 || updated.RectangleProps.Bounds[1].Lng != 14 {
+					t.Errorf("rectangle %+v centred at %.2f, %.2f", updated.RectangleProps.Bounds, updated.Latitude, updated.Longitude)
+				}
+			},
+		},
+		{
+			name:     "rectangle with one corner",
+			geometry: rectangleGeometry(24, -83, 31.5, -77),
+			req:      models.UpdateGeometryRequest{SouthWest: &models.Coordinate{Lat: 10, Lng: 10}, Color: text("#000000")},
+			err:      ErrInvalidGeometry,
+		},
+		{
+			name:     "rectangle outside the world",
+			geometry: rectangleGeometry(24, -83, 31.5, -77),
+			req:      models.UpdateGeometryRequest{SouthWest: &models.Coordinate{Lat: 10, Lng: 10}, NorthEast: &models.Coordinate{Lat: 95, Lng: 14}},
+			err:      ErrInvalidCoordinates,
+		},
+		{
+			name:     "generated geometry",
+			geometry: &models.Geometry{ID: uuid.New(), Type: models.GeometryTypeCircle, Source: models.GeometrySourceField306, CircleProps: circleProperties(1000, "km")},
+			req:      models.UpdateGeometryRequest{Radius: float(5)},
+			err:      ErrGeometryReadOnly,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.geometry.Color = "#ffffff"
+			tt.geometry.UpdatedAt = time.Unix(0, 0)
+			before := *tt.geometry
+			gs := newGeometryTestService(t, tt.geometry)
+
+			updated, err := gs.UpdateGeometry(tt.geometry.ID.String(), tt.req)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				// A rejected update leaves the stored geometry as it was
+				stored, _ := gs.GetGeometry(tt.geometry.ID.String())
+				if stored.Color != before.Color || stored.UpdatedAt != before.UpdatedAt || stored.Latitude != before.Latitude {
+					t.Errorf("stored geometry changed by a rejected update")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateGeometry: %v", err)
+			}
+			if !updated.UpdatedAt.After(before.UpdatedAt) {
+				t.Errorf("UpdatedAt not set")
+			}
+			tt.check(t, updated)
+
+			// The stored geometry and the index follow the update
+			stored, err := gs.GetGeometry(tt.geometry.ID.String())
+			if err != nil || stored.UpdatedAt != updated.UpdatedAt {
+				t.Errorf("update not stored: %v", err)
+			}
+			found, err := gs.GeometriesAt(updated.Latitude, updated.Longitude)
+			if err != nil || len(found) != 1 || found[0].ID != updated.ID {
+				t.Errorf("GeometriesAt the updated center: %d geometries, %v", len(found), err)
+			}
+		})
+	}
+
+	t.Run("unknown geometry", func(t *testing.T) {
+		gs := newGeometryTestService(t)
+		if _, err := gs.UpdateGeometry(uuid.NewString(), models.UpdateGeometryRequest{}); !errors.Is(err, ErrGeometryNotFound) {
+			t.Errorf("error = %v, want ErrGeometryNotFound", err)
+		}
+	})
+}
+
+func TestDeleteGeometry(t *testing.T) {
+	drawn := circleGeometry(30, -86, 10)
+	generated := circleGeometry(30, -86, 5)
+	generated.Source = models.GeometrySourceCoordination
+	gs := newGeometryTestService(t, drawn, generated)
+
+	if _, err := gs.DeleteGeometry(generated.ID.String()); !errors.Is(err, ErrGeometryReadOnly) {
+		t.Errorf("deleting a coordination area: error = %v, want ErrGeometryReadOnly", err)
+	}
+	deleted, err := gs.DeleteGeometry(drawn.ID.String())
+	if err != nil || deleted.ID != drawn.ID {
+		t.Fatalf("DeleteGeometry = %v, %v", deleted, err)
+	}
+	if _, err := gs.GetGeometry(drawn.ID.String()); !errors.Is(err, ErrGeometryNotFound) {
+		t.Errorf("deleted geometry still stored: %v", err)
+	}
+	found, err := gs.GeometriesAt(30, -86)
+	if err != nil || len(found) != 1 || found[0].ID != generated.ID {
+		t.Errorf("GeometriesAt after the delete: %d geometries, %v", len(found), err)
+	}
+	if _, err := gs.DeleteGeometry(drawn.ID.String()); !errors.Is(err, ErrGeometryNotFound) {
+		t.Errorf("second delete: error = %v, want ErrGeometryNotFound", err)
+	}
+}
+
+func TestDeleteMarkerGeometries(t *testing.T) {
+	marker, other := uuid.New(), uuid.New()
+	center := rectangleGeometry(24, -83, 31.5, -77)
+	center.MarkerID = &marker
+	area := circleGeometry(28, -81, 30)
+	area.MarkerID, area.Source = &marker, models.GeometrySourceField306
+	kept := circleGeometry(28, -81, 10)
+	kept.MarkerID = &other
+	gs := newGeometryTestService(t, center, area, kept)
+
+	if err := gs.DeleteMarkerGeometries(marker.String()); err != nil {
+		t.Fatalf("DeleteMarkerGeometries: %v", err)
+	}
+	remaining, err := gs.GetAllGeometries()
+	if err != nil || len(remaining) != 1 || remaining[0].ID != kept.ID {
+		t.Errorf("remaining geometries %v, %v", remaining, err)
+	}
+	found, err := gs.GeometriesAt(28, -81)
+	if err != nil || len(found) != 1 || found[0].ID != kept.ID {
+		t.Errorf("GeometriesAt after the delete: %d geometries, %v", len(found), err)
+	}
+	if err := gs.DeleteMarkerGeometries(uuid.NewString()); err != nil {
+		t.Errorf("marker without geometries: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// irac_notes.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+// IRACNoteCatalogue holds the IRAC notes a record may cite. Notes come from
+// the irac_notes table; the geographical scope of a note comes from the
+// reference file because the table has no column for it.
+type IRACNoteCatalogue struct {
+	notes  map[string]models.IRACNote
+	scopes map[string][]string
+}
+
+// geoBounds is a latitude/longitude box in decimal degrees
+type geoBounds struct {
+	South, West, North, East float64
+}
+
+func (b geoBounds) contains(lat, lng float64) bool {
+	return lat >= b.South && lat <= b.North && lng >= b.West && lng <= b.East
+}
+
+// iracNoteScope is a note's geographical scope resolved to states and areas
+type iracNoteScope struct {
+	States []string
+	Bounds []geoBounds
+}
+
+func (s iracNoteScope) contains(lat, lng float64) bool {
+	for _, bounds := range s.Bounds {
+		if bounds.contains(lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// usStateBounds are approximate bounding boxes of the states and DC. They
+// overlap along borders, so scope checks built on them only warn.
+var usStateBounds = map[string]geoBounds{
+	"AK": {51.21, -179.15, 71.39, -129.98}, "AL": {30.14, -88.47, 35.01, -84.89},
+	"AR": {33.00, -94.62, 36.50, -89.64}, "AZ": {31.33, -114.82, 37.00, -109.04},
+	"CA": {32.53, -124.48, 42.01, -114.13}, "CO": {36.99, -109.06, 41.00, -102.04},
+	"CT": {40.98, -73.73, 42.05, -71.79}, "DC": {38.79, -77.12, 38.99, -76.91},
+	"DE": {38.45, -75.79, 39.84, -75.05}, "FL": {24.40, -87.63, 31.00, -79.97},
+	"GA": {30.36, -85.61, 35.00, -80.84}, "HI": {18.91, -178.33, 28.40, -154.81},
+	"IA": {40.38, -96.64, 43.50, -90.14}, "ID": {41.99, -117.24, 49.00, -111.04},
+	"IL": {36.97, -91.51, 42.51, -87.02}, "IN": {37.77, -88.10, 41.76, -84.78},
+	"KS": {36.99, -102.05, 40.00, -94.59}, "KY": {36.50, -89.57, 39.15, -81.96},
+	"LA": {28.93, -94.04, 33.02, -88.82}, "MA": {41.24, -73.51, 42.89, -69.93},
+	"MD": {37.89, -79.49, 39.72, -75.05}, "ME": {43.06, -71.08, 47.46, -66.95},
+	"MI": {41.70, -90.42, 48.31, -82.41}, "MN": {43.50, -97.24, 49.38, -89.49},
+	"MO": {35.99, -95.77, 40.61, -89.10}, "MS": {30.17, -91.66, 35.00, -88.10},
+	"MT": {44.36, -116.05, 49.00, -104.04}, "NC": {33.84, -84.32, 36.59, -75.46},
+	"ND": {45.94, -104.05, 49.00, -96.55}, "NE": {40.00, -104.05, 43.00, -95.31},
+	"NH": {42.70, -72.56, 45.31, -70.61}, "NJ": {38.93, -75.56, 41.36, -73.89},
+	"NM": {31.33, -109.05, 37.00, -103.00}, "NV": {35.00, -120.01, 42.00, -114.04},
+	"NY": {40.50, -79.76, 45.02, -71.86}, "OH": {38.40, -84.82, 41.98, -80.52},
+	"OK": {33.62, -103.00, 37.00, -94.43}, "OR": {41.99, -124.57, 46.29, -116.46},
+	"PA": {39.72, -80.52, 42.27, -74.69}, "RI": {41.15, -71.86, 42.02, -71.12},
+	"SC": {32.03, -83.35, 35.22, -78.54}, "SD": {42.48, -104.06, 45.95, -96.44},
+	"TN": {34.98, -90.31, 36.68, -81.65}, "TX": {25.84, -106.65, 36.50, -93.51},
+	"UT": {37.00, -114.05, 42.00, -109.04}, "VA": {36.54, -83.68, 39.47, -75.24},
+	"VT": {42.73, -73.44, 45.02, -71.46}, "WA": {45.54, -124.85, 49.00, -116.92},
+	"WI": {42.49, -92.89, 47.31, -86.25}, "WV": {37.20, -82.64, 40.64, -77.72},
+	"WY": {40.99, -111.06, 45.01, -104.05},
+}
+
+// iracNamedAreas resolves the scope names in the reference file that are
+// not a state code or a "place, ST" pair
+var iracNamedAreas = map[string]iracNoteScope{
+	"Gulf Area": {
+		States: []string{"TX", "LA", "MS", "AL", "FL"},
+		Bounds: []geoBounds{{18.00, -98.00, 31.00, -80.50}},
+	},
+	"National Radio Quiet Zone": {
+		States: []string{"VA", "WV"},
+		Bounds: []geoBounds{{37.50, -80.50, 39.25, -78.50}},
+	},
+	"Edwards AFB": {
+		States: []string{"CA"},
+		Bounds: []geoBounds{{34.75, -118.15, 35.15, -117.45}},
+	},
+}
+
+// placeInState matches scope entries such as "Fort Meade, MD"
+var placeInState = regexp.MustCompile(`^.+,\s*([A-Z]{2})$`)
+
+// NewIRACNoteCatalogue indexes the notes by code and reads the geographical
+// scopes from the IRAC notes reference file
+func NewIRACNoteCatalogue(notes []models.IRACNote, referencePath string) (*IRACNoteCatalogue, error) {
+	catalogue := &IRACNoteCatalogue{
+		notes:  make(map[string]models.IRACNote, len(notes)),
+		scopes: make(map[string][]string),
+	}
+	for _, note := range notes {
+		catalogue.notes[strings.ToUpper(strings.TrimSpace(note.Code))] = note
+	}
+
+	data, err := os.ReadFile(referencePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IRAC notes reference: %w", err)
+	}
+
+	// Notes are grouped by section; metadata is the only other key
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("failed to parse IRAC notes reference %s: %w", referencePath, err)
+	}
+	for name, raw := range sections {
+		if name == "metadata" {
+			continue
+		}
+		var section map[string]struct {
+			Code         string   `json:"code"`
+			Geographical []string `json:"geographical"`
+		}
+		if err := json.Unmarshal(raw, &section); err != nil {
+			return nil, fmt.Errorf("failed to parse IRAC notes reference section %q: %w", name, err)
+		}
+		for code, entry := range section {
+			if entry.Code != "" {
+				code = entry.Code
+			}
+			if len(entry.Geographical) > 0 {
+				catalogue.scopes[strings.ToUpper(code)] = entry.Geographical
+			}
+		}
+	}
+	return catalogue, nil
+}
+
+// Len returns the number of notes in the catalogue
+func (c *IRACNoteCatalogue) Len() int {
+	return len(c.notes)
+}
+
+// Lookup finds a note by its code
+func (c *IRACNoteCatalogue) Lookup(code string) (models.IRACNote, bool) {
+	note, exists := c.notes[strings.ToUpper(strings.TrimSpace(code))]
+	return note, exists
+}
+
+// scope resolves a note's geographical scope. It reports false when the
+// note has no scope, or one that names an area we can't place, such as a
+// border zone.
+func (c *IRACNoteCatalogue) scope(code string) (iracNoteScope, bool) {
+	names, exists := c.scopes[code]
+	if !exists {
+		return iracNoteScope{}, false
+	}
+
+	var resolved iracNoteScope
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		state := name
+		if match := placeInState.FindStringSubmatch(name); match != nil {
+			state = match[1]
+		}
+
+		if bounds, isState := usStateBounds[state]; isState {
+			resolved.States = append(resolved.States, state)
+			resolved.Bounds = append(resolved.Bounds, bounds)
+			continue
+		}
+		area, known := iracNamedAreas[name]
+		if !known {
+			return iracNoteScope{}, false
+		}
+		resolved.States = append(resolved.States, area.States...)
+		resolved.Bounds = append(resolved.Bounds, area.Bounds...)
+	}
+	return resolved, true
+}
+
+// placement is the field a note belongs in. The table's field_placement
+// wins; otherwise minute notes go in 501 and every other note in 500.
+func (c *IRACNoteCatalogue) placement(note models.IRACNote) string {
+	if note.FieldPlacement > 0 {
+		return strconv.Itoa(note.FieldPlacement)
+	}
+	if strings.EqualFold(note.Category, "Minute") {
+		return "501"
+	}
+	return "500"
+}
+
+// Rules returns the record rules that check IRAC notes against the catalogue
+func (c *IRACNoteCatalogue) Rules() []SFAFRule {
+	return []SFAFRule{
+		{
+			Name:        "irac-note-catalogue",
+			Description: "IRAC note codes in fields 500-504 are in the IRAC notes catalogue",
+			Check:       c.checkKnownNotes,
+		},
+		{
+			Name:        "irac-note-placement",
+			Description: "IRAC notes sit in the field for their category (500, or 501 for minute notes)",
+			Check:       c.checkNotePlacement,
+		},
+		{
+			Name:        "irac-note-scope",
+			Description: "Notes limited to an area match the transmitter state (300) and coordinates (303)",
+			Check:       c.checkNoteScope,
+		},
+	}
+}
+
+// iracNoteEntry is a note code cited by a record
+type iracNoteEntry struct {
+	Code  string
+	Entry models.SFAFEntry
+}
+
+// citedNotes lists the note codes of a record. Field 500 holds nothing but
+// codes; the other note fields only count when the whole value is a code.
+func citedNotes(record *models.SFAF) []iracNoteEntry {
+	var cited []iracNoteEntry
+	for _, entry := range record.Entries {
+		if !containsString(sfafIRACNoteFields, entry.FieldNumber) {
+			continue
+		}
+		code := strings.ToUpper(strings.TrimSpace(entry.Value))
+		if code == "" || (entry.FieldNumber != "500" && !iracNoteCode.MatchString(code)) {
+			continue
+		}
+		cited = append(cited, iracNoteEntry{Code: code, Entry: entry})
+	}
+	return cited
+}
+
+func (c *IRACNoteCatalogue) checkKnownNotes(record *models.SFAF) []models.SFAFValidationIssue {
+	var issues []models.SFAFValidationIssue
+	for _, cited := range citedNotes(record) {
+		if _, exists := c.Lookup(cited.Code); !exists {
+			issues = append(issues, ruleError(fmt.Sprintf("IRAC note %s is not in the IRAC notes catalogue", cited.Code), cited.Entry.FlatKey()))
+		}
+	}
+	return issues
+}
+
+func (c *IRACNoteCatalogue) checkNotePlacement(record *models.SFAF) []models.SFAFValidationIssue {
+	var issues []models.SFAFValidationIssue
+	for _, cited := range citedNotes(record) {
+		note, exists := c.Lookup(cited.Code)
+		if !exists {
+			continue
+		}
+		if field := c.placement(note); field != cited.Entry.FieldNumber {
+			issues = append(issues, ruleError(fmt.Sprintf("%s note %s belongs in field %s, not field %s",
+				strings.ToLower(note.Category), cited.Code, field, cited.Entry.FieldNumber), cited.Entry.FlatKey()))
+		}
+	}
+	return issues
+}
+
+func (c *IRACNoteCatalogue) checkNoteScope(record *models.SFAF) []models.SFAFValidationIssue {
+	state := strings.ToUpper(strings.TrimSpace(record.Value("300")))
+	// Unparseable coordinates are reported by the field format check
+	coord, err := parseCoordinatePair(record.Value("303"))
+	located := err == nil
+
+	var issues []models.SFAFValidationIssue
+	for _, cited := range citedNotes(record) {
+		if _, exists := c.Lookup(cited.Code); !exists {
+			continue
+		}
+		scope, scoped := c.scope(cited.Code)
+		if !scoped {
+			continue
+		}
+
+		area := strings.Join(c.scopes[cited.Code], ", ")
+		key := cited.Entry.FlatKey()
+		// Field 300 may name a neighbouring state for a transmitter near a
+		// border, so a mismatch is reported for review rather than rejected
+		if state != "" && !containsString(scope.States, state) {
+			issues = append(issues, ruleWarning(fmt.Sprintf("IRAC note %s is limited to %s but the transmitter state/country (300) is %s",
+				cited.Code, area, state), key, "field300"))
+			continue
+		}
+		if located && !scope.contains(coord.Lat, coord.Lng) {
+			issues = append(issues, ruleWarning(fmt.Sprintf("IRAC note %s is limited to %s but the transmitter coordinates (303) are outside it",
+				cited.Code, area), key, "field303"))
+		}
+	}
+	return issues
+}
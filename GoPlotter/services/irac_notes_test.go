@@ -0,0 +1,166 @@
+// irac_notes_test.go
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+func newNoteTestCatalogue(t *testing.T) *IRACNoteCatalogue {
+	t.Helper()
+	catalogue, err := NewIRACNoteCatalogue([]models.IRACNote{
+		{Code: "C004", Category: "Coordination"},
+		{Code: "C010", Category: "Coordination"},
+		{Code: "S189", Category: "Special"},
+		{Code: "S388", Category: "Special"},
+		{Code: "M001", Category: "Minute"},
+		{Code: "L116", Category: "Limitation", FieldPlacement: 502},
+	}, "../web/static/references/irac-notes-reference.json")
+	if err != nil {
+		t.Fatalf("NewIRACNoteCatalogue: %v", err)
+	}
+	return catalogue
+}
+
+// noteRecord is a record with a transmitter state (300), coordinates (303)
+// and note entries written as "500 C004"
+func noteRecord(state, coordinates string, notes ...string) *models.SFAF {
+	return sfafRecord(append([]string{"300 " + state, "303 " + coordinates}, notes...)...)
+}
+
+func TestIRACNoteCatalogueLookup(t *testing.T) {
+	catalogue := newNoteTestCatalogue(t)
+	tests := []struct {
+		code   string
+		exists bool
+	}{
+		{"C004", true},
+		{" c004 ", true},
+		{"m001", true},
+		{"C999", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		note, exists := catalogue.Lookup(tt.code)
+		if exists != tt.exists {
+			t.Errorf("Lookup(%q) exists = %v, want %v", tt.code, exists, tt.exists)
+			continue
+		}
+		if exists && note.Code != strings.ToUpper(strings.TrimSpace(tt.code)) {
+			t.Errorf("Lookup(%q) = %s", tt.code, note.Code)
+		}
+	}
+	if catalogue.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", catalogue.Len())
+	}
+}
+
+func TestIRACNoteRules(t *testing.T) {
+	const (
+		eglin   = "302521N0864150W" // Florida
+		houston = "294600N0952200W" // Texas, in the Gulf Area
+		mojave  = "343000N1175000W" // California
+	)
+	catalogue := newNoteTestCatalogue(t)
+	tests := []struct {
+		name   string
+		check  func(*models.SFAF) []models.SFAFValidationIssue
+		record *models.SFAF
+		issues []ruleIssue
+	}{
+		{
+			name:   "catalogue: known notes",
+			check:  catalogue.checkKnownNotes,
+			record: noteRecord("FL", eglin, "500 C004", "500 S189", "501 M001"),
+		},
+		{
+			name:   "catalogue: unknown note",
+			check:  catalogue.checkKnownNotes,
+			record: noteRecord("FL", eglin, "500 C004", "500 C999"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field500/02", "IRAC note C999 is not in the IRAC notes catalogue"}},
+		},
+		{
+			name:   "catalogue: free text outside field 500 is not a code",
+			check:  catalogue.checkKnownNotes,
+			record: noteRecord("FL", eglin, "501 COORDINATE WITH RANGE CONTROL", "503 X12"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field503", "IRAC note X12"}},
+		},
+		{
+			name:   "placement: notes in their fields",
+			check:  catalogue.checkNotePlacement,
+			record: noteRecord("FL", eglin, "500 C004", "501 M001", "502 L116"),
+		},
+		{
+			name:   "placement: minute note in 500",
+			check:  catalogue.checkNotePlacement,
+			record: noteRecord("FL", eglin, "500 M001"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field500", "minute note M001 belongs in field 501, not field 500"}},
+		},
+		{
+			name:   "placement: other notes in 501",
+			check:  catalogue.checkNotePlacement,
+			record: noteRecord("FL", eglin, "501 C004"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field501", "coordination note C004 belongs in field 500, not field 501"}},
+		},
+		{
+			name:   "placement: field_placement overrides the category",
+			check:  catalogue.checkNotePlacement,
+			record: noteRecord("FL", eglin, "500 L116", "504 C010"),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field500", "belongs in field 502, not field 500"},
+				{models.SFAFSeverityError, "field504", "belongs in field 500, not field 504"},
+			},
+		},
+		{
+			name:   "placement: unknown notes are left to the catalogue rule",
+			check:  catalogue.checkNotePlacement,
+			record: noteRecord("FL", eglin, "501 C999"),
+		},
+		{
+			name:   "scope: state and coordinates inside",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("FL", eglin, "500 C004"),
+		},
+		{
+			name:   "scope: state outside warns",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("AL", eglin, "500 C004"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field500", "limited to FL but the transmitter state/country (300) is AL"}},
+		},
+		{
+			name:   "scope: coordinates outside warn",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("FL", mojave, "500 C004"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field500", "transmitter coordinates (303) are outside it"}},
+		},
+		{
+			name:   "scope: named area",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("TX", houston, "500 C010"),
+		},
+		{
+			name:   "scope: place in state",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("VA", "", "500 S388"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field500", "limited to Fort Detrick, MD, Fort Meade, MD, Camp Roberts, CA"}},
+		},
+		{
+			name:   "scope: notes without a scope",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("TX", houston, "500 S189", "500 C999"),
+		},
+		{
+			name:   "scope: no state or coordinates",
+			check:  catalogue.checkNoteScope,
+			record: noteRecord("", "", "500 C004"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRuleIssues(t, tt.check(tt.record), tt.issues)
+		})
+	}
+}
@@ -2,20 +2,42 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"sfaf-plotter/models"
 	"sfaf-plotter/repositories"
+	"sfaf-plotter/spatial"
+	"sort"
 
 	"github.com/google/uuid"
 )
 
+// Parse errors on marker create/update wrap these
+var (
+	ErrInvalidFrequency = errors.New("invalid frequency")
+	ErrInvalidPower     = errors.New("invalid power")
+)
+
 type MarkerService struct {
 	markerRepo    *repositories.MarkerRepository
 	iracNotesRepo *repositories.IRACNotesRepository
 	serialService *SerialService
 	coordService  *CoordinateService
+
+	// index holds marker positions once IndexMarkers has run; until then
+	// spatial queries go to the database alone
+	index *spatial.Index
 }
 
+// nearestStartRadius is the first radius searched for the nearest markers.
+// It grows fourfold until enough are found or it reaches halfCircumference,
+// beyond which no two points lie.
+const nearestStartRadius = 10 * metersPerKm
+
+const halfCircumference = math.Pi * wgs84A
+
 func NewMarkerService(
 	markerRepo *repositories.MarkerRepository,
 	iracNotesRepo *repositories.IRACNotesRepository,
@@ -31,12 +53,19 @@ func NewMarkerService(
 }
 
 func (ms *MarkerService) CreateMarker(req models.CreateMarkerRequest) (*models.MarkerResponse, error) {
+	position, err := ms.coordService.ResolvePosition(req.Position, req.Latitude, req.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	req.Latitude, req.Longitude = position.Lat, position.Lng
+
 	marker := &models.Marker{
 		ID:          uuid.New(),
 		Serial:      ms.serialService.GenerateSerial(),
 		Latitude:    req.Latitude,
 		Longitude:   req.Longitude,
 		Frequency:   req.Frequency,
+		Power:       req.Power,
 		Notes:       req.Notes,
 		MarkerType:  req.MarkerType,
 		IsDraggable: true,
@@ -46,10 +75,18 @@ func (ms *MarkerService) CreateMarker(req models.CreateMarkerRequest) (*models.M
 		marker.MarkerType = "manual"
 	}
 
-	err := ms.markerRepo.Create(marker)
+	if err := marker.ParseFrequency(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFrequency, err)
+	}
+	if err := marker.ParsePower(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPower, err)
+	}
+
+	err = ms.markerRepo.Create(marker)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create marker: %w", err)
 	}
+	ms.indexMarker(marker)
 
 	return &models.MarkerResponse{
 		Success: true,
@@ -71,6 +108,152 @@ func (ms *MarkerService) GetAllMarkers() (*models.MarkersResponse, error) {
 	}, nil
 }
 
+// FindMarkers lists markers matching filter, e.g. a frequency range sorted
+// by frequency or a power class. Near and Within queries fill each marker's
+// Distance from the query point (the geometry's center for Within) and
+// sort nearest first unless another order is asked for.
+func (ms *MarkerService) FindMarkers(filter models.MarkerFilter) (*models.MarkersResponse, error) {
+	origin, err := ms.spatialOrigin(&filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var markers []models.Marker
+	switch {
+	case origin == nil:
+		markers, err = ms.findCandidates(filter)
+	case filter.Near != nil && filter.RadiusM == 0:
+		markers, err = ms.findNearest(filter, *origin)
+	default:
+		unlimited := filter
+		unlimited.Limit = 0
+		markers, err = ms.findCandidates(unlimited)
+		markers = refineMarkers(markers, filter, *origin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markers: %w", err)
+	}
+
+	if origin != nil {
+		if filter.Limit > 0 && len(markers) > filter.Limit {
+			markers = markers[:filter.Limit]
+		}
+		if filter.SortBy == "frequency" {
+			sort.SliceStable(markers, func(i, j int) bool {
+				return markers[i].FrequencyBefore(&markers[j])
+			})
+		}
+	}
+
+	return &models.MarkersResponse{
+		Success: true,
+		Message: "Markers retrieved successfully",
+		Markers: markers,
+	}, nil
+}
+
+// spatialOrigin checks the Near and Within parts of filter, puts the boxes
+// around them first in filter.Bounds so storage can narrow the candidates
+// (the index searches the first box), and
+// returns the point distances are measured from (nil for neither).
+func (ms *MarkerService) spatialOrigin(filter *models.MarkerFilter) (*models.Coordinate, error) {
+	var origin *models.Coordinate
+	if filter.Within != nil {
+		box, ok := geometryBounds(filter.Within)
+		if !ok {
+			return nil, fmt.Errorf("%w: geometry %s has no shape", ErrInvalidGeometry, filter.Within.ID)
+		}
+		filter.Bounds = append([]models.BoundingBox{box}, filter.Bounds...)
+		origin = &models.Coordinate{Lat: filter.Within.Latitude, Lng: filter.Within.Longitude}
+	}
+	if filter.Near != nil {
+		if err := ms.coordService.CheckCoordinate(filter.Near.Lat, filter.Near.Lng); err != nil {
+			return nil, err
+		}
+		switch {
+		case filter.RadiusM > 0:
+			filter.Bounds = append([]models.BoundingBox{radiusBounds(*filter.Near, filter.RadiusM)}, filter.Bounds...)
+		case filter.RadiusM == 0 && filter.Limit > 0:
+			// the nearest markers; findNearest picks the radius
+		default:
+			return nil, fmt.Errorf("%w: near needs a radius greater than zero or a limit", ErrInvalidCoordinates)
+		}
+		origin = filter.Near
+	}
+	if origin == nil && filter.SortBy == "distance" {
+		return nil, fmt.Errorf("%w: sort=distance needs near or within", ErrInvalidCoordinates)
+	}
+	return origin, nil
+}
+
+// findCandidates queries the database, first narrowing the first of
+// filter.Bounds to the markers the index has in it
+func (ms *MarkerService) findCandidates(filter models.MarkerFilter) ([]models.Marker, error) {
+	if ms.index != nil && len(filter.Bounds) > 0 && filter.IDs == nil {
+		ids := ms.index.Search(filter.Bounds[0])
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		filter.IDs = make([]uuid.UUID, len(ids))
+		for i, id := range ids {
+			filter.IDs[i] = uuid.MustParse(id)
+		}
+	}
+	return ms.markerRepo.Find(filter)
+}
+
+// findNearest widens the search around filter.Near until it holds
+// filter.Limit matching markers, and returns all it found nearest first
+func (ms *MarkerService) findNearest(filter models.MarkerFilter, origin models.Coordinate) ([]models.Marker, error) {
+	for radius := nearestStartRadius; ; radius *= 4 {
+		search := filter
+		search.Limit = 0
+		search.RadiusM = math.Min(radius, halfCircumference)
+		box := radiusBounds(origin, search.RadiusM)
+		search.Bounds = append([]models.BoundingBox{box}, filter.Bounds...)
+		last := search.RadiusM == halfCircumference
+
+		// Too few markers in the box at all: no need to ask the database
+		if ms.index != nil && !last && len(ms.index.Search(box)) < filter.Limit {
+			continue
+		}
+
+		candidates, err := ms.findCandidates(search)
+		if err != nil {
+			return nil, err
+		}
+		markers := refineMarkers(candidates, search, origin)
+		if len(markers) >= filter.Limit || last {
+			return markers, nil
+		}
+	}
+}
+
+// refineMarkers keeps the candidates storage returned that really are
+// within the radius or geometry, sets their Distance from origin and orders
+// them nearest first.
+func refineMarkers(markers []models.Marker, filter models.MarkerFilter, origin models.Coordinate) []models.Marker {
+	var kept []models.Marker
+	for _, marker := range markers {
+		position := models.Coordinate{Lat: marker.Latitude, Lng: marker.Longitude}
+		meters := geodesicDistance(origin, position)
+		if filter.Near != nil && meters > filter.RadiusM {
+			continue
+		}
+		if filter.Within != nil && !geometryContains(filter.Within, marker.Latitude, marker.Longitude) {
+			continue
+		}
+		distance := NewDistance(meters)
+		marker.Distance = &distance
+		kept = append(kept, marker)
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Distance.Meters < kept[j].Distance.Meters
+	})
+	return kept
+}
+
 func (ms *MarkerService) GetMarker(id string) (*models.MarkerResponse, error) {
 	markerID, err := uuid.Parse(id)
 	if err != nil {
@@ -98,13 +281,34 @@ func (ms *MarkerService) UpdateMarker(id string, req models.UpdateMarkerRequest)
 	updates := make(map[string]interface{})
 
 	if req.Latitude != nil {
+		if err := ms.coordService.CheckLatitude(*req.Latitude); err != nil {
+			return nil, err
+		}
 		updates["latitude"] = *req.Latitude
 	}
 	if req.Longitude != nil {
+		if err := ms.coordService.CheckLongitude(*req.Longitude); err != nil {
+			return nil, err
+		}
 		updates["longitude"] = *req.Longitude
 	}
 	if req.Frequency != nil {
+		parsed := models.Marker{Frequency: *req.Frequency}
+		if err := parsed.ParseFrequency(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFrequency, err)
+		}
 		updates["frequency"] = *req.Frequency
+		updates["frequency_hz"] = parsed.FrequencyHz
+		updates["frequency_min_hz"] = parsed.FreqMinHz
+		updates["frequency_max_hz"] = parsed.FreqMaxHz
+	}
+	if req.Power != nil {
+		parsed := models.Marker{Power: *req.Power}
+		if err := parsed.ParsePower(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPower, err)
+		}
+		updates["power"] = *req.Power
+		updates["power_watts"] = parsed.PowerWatts
 	}
 	if req.Notes != nil {
 		updates["notes"] = *req.Notes
@@ -126,6 +330,7 @@ func (ms *MarkerService) UpdateMarker(id string, req models.UpdateMarkerRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated marker: %w", err)
 	}
+	ms.indexMarker(marker)
 
 	return &models.MarkerResponse{
 		Success: true,
@@ -144,6 +349,9 @@ func (ms *MarkerService) DeleteMarker(id string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete marker: %w", err)
 	}
+	if ms.index != nil {
+		ms.index.Remove(markerID.String())
+	}
 
 	return nil
 }
@@ -153,10 +361,163 @@ func (ms *MarkerService) DeleteAllMarkers() error {
 	if err != nil {
 		return fmt.Errorf("failed to delete all markers: %w", err)
 	}
+	if ms.index != nil {
+		ms.index.Clear()
+	}
 
 	return nil
 }
 
+// SaveSFAFRecord updates an SFAF record's marker (its frequency and power
+// taken from the record) and rewrites its sfaf_fields rows in one
+// transaction. saveSFAF runs before the commit, like in ApplyImport; if it
+// fails nothing is written.
+func (ms *MarkerService) SaveSFAFRecord(marker *models.Marker, sfaf *models.SFAF, saveSFAF func() error) error {
+	batch := repositories.MarkerBatch{
+		Update: []*models.Marker{marker},
+		SFAFFields: map[uuid.UUID][]models.SFAFField{
+			sfaf.MarkerID: models.SFAFFieldRows(sfaf.MarkerID, sfaf.Entries),
+		},
+	}
+
+	if err := ms.markerRepo.ApplyBatch(batch, saveSFAF); err != nil {
+		return fmt.Errorf("failed to save SFAF fields: %w", err)
+	}
+	ms.indexMarker(marker)
+	return nil
+}
+
+// DeleteSFAFRecord updates the marker of a deleted SFAF record and removes
+// its sfaf_fields rows in one transaction; deleteSFAF runs before the
+// commit, like saveSFAF in SaveSFAFRecord.
+func (ms *MarkerService) DeleteSFAFRecord(marker *models.Marker, deleteSFAF func() error) error {
+	batch := repositories.MarkerBatch{
+		Update:     []*models.Marker{marker},
+		SFAFFields: map[uuid.UUID][]models.SFAFField{marker.ID: nil},
+	}
+
+	if err := ms.markerRepo.ApplyBatch(batch, deleteSFAF); err != nil {
+		return fmt.Errorf("failed to delete SFAF fields: %w", err)
+	}
+	ms.indexMarker(marker)
+	return nil
+}
+
+// GetMarkersBySerial maps each serial to its most recently updated marker
+func (ms *MarkerService) GetMarkersBySerial(serials []string) (map[string]models.Marker, error) {
+	markers, err := ms.markerRepo.GetBySerials(serials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markers by serial: %w", err)
+	}
+
+	bySerial := make(map[string]models.Marker, len(markers))
+	for _, marker := range markers {
+		if _, exists := bySerial[marker.Serial]; !exists {
+			bySerial[marker.Serial] = marker
+		}
+	}
+	return bySerial, nil
+}
+
+// ApplyImport creates, updates and deletes imported markers together with
+// the sfaf_fields rows of their SFAF records in one transaction. saveSFAFs
+// runs before the commit; if it fails no marker is written.
+func (ms *MarkerService) ApplyImport(creates, updates []models.Marker, deletes []uuid.UUID, sfafs []models.SFAF, saveSFAFs func() error) error {
+	batch := repositories.MarkerBatch{
+		Delete:     deletes,
+		SFAFFields: make(map[uuid.UUID][]models.SFAFField, len(sfafs)),
+	}
+	for i := range creates {
+		batch.Create = append(batch.Create, &creates[i])
+	}
+	for i := range updates {
+		batch.Update = append(batch.Update, &updates[i])
+	}
+	for _, sfaf := range sfafs {
+		batch.SFAFFields[sfaf.MarkerID] = models.SFAFFieldRows(sfaf.MarkerID, sfaf.Entries)
+	}
+
+	if err := ms.markerRepo.ApplyBatch(batch, saveSFAFs); err != nil {
+		return fmt.Errorf("failed to import markers: %w", err)
+	}
+
+	for _, marker := range batch.Create {
+		ms.indexMarker(marker)
+	}
+	for _, marker := range batch.Update {
+		ms.indexMarker(marker)
+	}
+	if ms.index != nil {
+		for _, id := range deletes {
+			ms.index.Remove(id.String())
+		}
+	}
+
+	return nil
+}
+
+// IndexMarkers loads every marker position into the spatial index used by
+// bounding box, radius and nearest-marker queries, and returns the number of
+// markers indexed. Markers created, moved or deleted later through the
+// service keep it current.
+func (ms *MarkerService) IndexMarkers() (int, error) {
+	markers, err := ms.markerRepo.GetPositions()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load marker positions: %w", err)
+	}
+
+	index := spatial.NewIndex()
+	for _, marker := range markers {
+		index.Insert(marker.ID.String(), spatial.PointBox(marker.Latitude, marker.Longitude))
+	}
+	ms.index = index
+	return index.Len(), nil
+}
+
+func (ms *MarkerService) indexMarker(marker *models.Marker) {
+	if ms.index != nil {
+		ms.index.Insert(marker.ID.String(), spatial.PointBox(marker.Latitude, marker.Longitude))
+	}
+}
+
+// BackfillParsedValues adds the parsed frequency and power columns if
+// needed, copies raw power (field115) from sfaf_fields onto markers saved
+// before the power column existed, and parses every value not parsed yet.
+// Unparseable values are logged and left empty.
+func (ms *MarkerService) BackfillParsedValues() (int, error) {
+	if err := ms.markerRepo.EnsureParsedColumns(); err != nil {
+		return 0, fmt.Errorf("failed to add parsed columns: %w", err)
+	}
+
+	if _, err := ms.markerRepo.CopyPowerFromSFAFFields(); err != nil {
+		return 0, fmt.Errorf("failed to copy power from SFAF fields: %w", err)
+	}
+
+	markers, err := ms.markerRepo.GetUnparsedMarkers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load markers: %w", err)
+	}
+
+	updated := 0
+	for i := range markers {
+		marker := &markers[i]
+		if err := marker.ParseFrequency(); err != nil {
+			log.Printf("⚠️ Marker %s (%s) frequency: %v", marker.Serial, marker.ID, err)
+		}
+		if err := marker.ParsePower(); err != nil {
+			log.Printf("⚠️ Marker %s (%s) power: %v", marker.Serial, marker.ID, err)
+		}
+		if marker.FrequencyHz == nil && marker.PowerWatts == nil {
+			continue
+		}
+		if err := ms.markerRepo.UpdateParsedColumns(marker); err != nil {
+			return updated, fmt.Errorf("failed to update marker %s: %w", marker.ID, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
 // IRAC Notes management methods
 func (ms *MarkerService) GetIRACNotes() ([]models.IRACNote, error) {
 	return ms.iracNotesRepo.GetAllNotes()
@@ -0,0 +1,373 @@
+// nomination_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sfaf-plotter/emission"
+	"sfaf-plotter/frequency"
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+)
+
+// ErrInvalidNomination wraps the errors of a nomination request that cannot
+// be worked out
+var ErrInvalidNomination = errors.New("invalid nomination request")
+
+// maxNominationChannels bounds the raster of one request
+const maxNominationChannels = 100000
+
+const defaultNominationLimit = 20
+
+// NominationService proposes clear frequencies for a new assignment: raster
+// channels that avoid restricted frequencies and are neither on nor within
+// the guard band of an assignment inside the radius of concern.
+type NominationService struct {
+	storage         storage.Storage
+	markerService   *MarkerService
+	coordService    *CoordinateService
+	conflictService *ConflictService
+	restricted      []models.RestrictedFrequency
+}
+
+func NewNominationService(storage storage.Storage, markerService *MarkerService, coordService *CoordinateService, conflictService *ConflictService, restricted *models.RestrictedFrequencyCatalogue) *NominationService {
+	return &NominationService{
+		storage:         storage,
+		markerService:   markerService,
+		coordService:    coordService,
+		conflictService: conflictService,
+		restricted:      restricted.Frequencies,
+	}
+}
+
+// LoadRestrictedFrequencies reads the restricted frequency data file and
+// parses every entry
+func LoadRestrictedFrequencies(path string) (*models.RestrictedFrequencyCatalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read restricted frequencies: %w", err)
+	}
+
+	var catalogue models.RestrictedFrequencyCatalogue
+	if err := json.Unmarshal(data, &catalogue); err != nil {
+		return nil, fmt.Errorf("failed to parse restricted frequencies %s: %w", path, err)
+	}
+	for i := range catalogue.Frequencies {
+		entry := &catalogue.Frequencies[i]
+		f, err := frequency.Parse(entry.Frequency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid restricted frequency %d in %s: %w", i+1, path, err)
+		}
+		entry.MinHz, entry.MaxHz = f.MinHz, f.MaxHz
+	}
+	return &catalogue, nil
+}
+
+// nominationNeighbour is an active assignment inside the radius
+type nominationNeighbour struct {
+	marker *models.Marker
+	band   models.OccupiedBand
+	label  string
+}
+
+// Nominate ranks the clear channels of a request: those farthest in
+// frequency from the assignments inside the radius come first, and channels
+// with no assignment in the radius at all before any of them.
+func (ns *NominationService) Nominate(req models.NominationRequest) (*models.NominationResult, error) {
+	position, err := ns.coordService.ResolvePosition(req.Position, req.Latitude, req.Longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	bandwidth, err := nominationBandwidth(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Radius <= 0 || math.IsInf(req.Radius, 0) {
+		return nil, fmt.Errorf("%w: radius must be greater than zero", ErrInvalidNomination)
+	}
+	radius, err := DistanceToMeters(req.Radius, req.RadiusUnit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNomination, err)
+	}
+
+	guard := ns.conflictService.Settings().GuardBandHz
+	if req.GuardBandKHz != nil {
+		if *req.GuardBandKHz < 0 || math.IsInf(*req.GuardBandKHz, 0) {
+			return nil, fmt.Errorf("%w: guard band must be zero or more", ErrInvalidNomination)
+		}
+		guard = *req.GuardBandKHz * frequency.KHz
+	}
+
+	from, err := parseNominationDate("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseNominationDate("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidNomination)
+	}
+
+	channels, err := nominationChannels(req, bandwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbours, err := ns.activeAssignments(position, radius, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultNominationLimit
+	}
+
+	result := &models.NominationResult{
+		Position:    position,
+		BandwidthHz: bandwidth,
+		GuardBandHz: guard,
+		Radius:      NewDistance(radius),
+		From:        strings.TrimSpace(req.From),
+		To:          strings.TrimSpace(req.To),
+		Assignments: len(neighbours),
+		Channels:    len(channels),
+		Candidates:  []models.NominationCandidate{},
+	}
+
+	for _, hz := range channels {
+		band := models.OccupiedBand{MinHz: hz - bandwidth/2, MaxHz: hz + bandwidth/2, CenterHz: hz, BandwidthHz: bandwidth}
+		if ns.isRestricted(band) {
+			result.Restricted++
+			continue
+		}
+
+		candidate, clear := nominationCandidate(hz, band, neighbours, guard)
+		if !clear {
+			result.Occupied++
+			continue
+		}
+		result.Candidates = append(result.Candidates, candidate)
+	}
+	result.Clear = len(result.Candidates)
+
+	rankCandidates(result.Candidates)
+	if len(result.Candidates) > limit {
+		result.Candidates = result.Candidates[:limit]
+	}
+	return result, nil
+}
+
+// nominationBandwidth is the necessary bandwidth of the emission designator,
+// or bandwidth_khz without one
+func nominationBandwidth(req models.NominationRequest) (float64, error) {
+	if strings.TrimSpace(req.Emission) != "" {
+		designator, err := emission.Parse(req.Emission)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidNomination, err)
+		}
+		return designator.BandwidthHz, nil
+	}
+	if req.BandwidthKHz < 0 || math.IsInf(req.BandwidthKHz, 0) {
+		return 0, fmt.Errorf("%w: bandwidth must be zero or more", ErrInvalidNomination)
+	}
+	return req.BandwidthKHz * frequency.KHz, nil
+}
+
+// nominationChannels lists the channel frequencies in Hz: the given
+// channels, or the raster points of the band from its lower edge whose
+// occupied band fits inside it
+func nominationChannels(req models.NominationRequest, bandwidth float64) ([]float64, error) {
+	if len(req.Channels) > 0 {
+		if strings.TrimSpace(req.Band) != "" {
+			return nil, fmt.Errorf("%w: give either band or channels, not both", ErrInvalidNomination)
+		}
+		if len(req.Channels) > maxNominationChannels {
+			return nil, fmt.Errorf("%w: more than %d channels", ErrInvalidNomination, maxNominationChannels)
+		}
+		channels := make([]float64, 0, len(req.Channels))
+		for _, value := range req.Channels {
+			f, err := frequency.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidNomination, err)
+			}
+			if f.IsBand() {
+				return nil, fmt.Errorf("%w: channel %q is a band", ErrInvalidNomination, value)
+			}
+			channels = append(channels, f.Hz)
+		}
+		return channels, nil
+	}
+
+	if strings.TrimSpace(req.Band) == "" {
+		return nil, fmt.Errorf("%w: band or channels are required", ErrInvalidNomination)
+	}
+	band, err := frequency.Parse(req.Band)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNomination, err)
+	}
+	if !band.IsBand() {
+		return nil, fmt.Errorf("%w: band %q is a single frequency; use channels", ErrInvalidNomination, req.Band)
+	}
+
+	step := req.ChannelStepKHz * frequency.KHz
+	if step == 0 {
+		step = bandwidth
+	}
+	if step <= 0 || math.IsInf(step, 0) {
+		return nil, fmt.Errorf("%w: channel_step_khz or a bandwidth is required for a band", ErrInvalidNomination)
+	}
+	if (band.MaxHz-band.MinHz)/step >= maxNominationChannels {
+		return nil, fmt.Errorf("%w: the raster has more than %d channels", ErrInvalidNomination, maxNominationChannels)
+	}
+
+	var channels []float64
+	for k := 0; ; k++ {
+		// Millihertz, like parsed frequencies, so raster points print cleanly
+		hz := math.Round((band.MinHz+float64(k)*step)*1e3) / 1e3
+		if hz+bandwidth/2 > band.MaxHz {
+			break
+		}
+		if hz-bandwidth/2 >= band.MinHz {
+			channels = append(channels, hz)
+		}
+	}
+	return channels, nil
+}
+
+func parseNominationDate(name, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: invalid %s date %q (use YYYY-MM-DD)", ErrInvalidNomination, name, value)
+}
+
+// activeAssignments lists the assignments with a valid frequency inside the
+// radius that are active at some time in the window
+func (ns *NominationService) activeAssignments(position models.Coordinate, radius float64, from, to *time.Time) ([]nominationNeighbour, error) {
+	nearby, err := ns.markerService.FindMarkers(models.MarkerFilter{Near: &position, RadiusM: radius})
+	if err != nil {
+		return nil, err
+	}
+
+	var neighbours []nominationNeighbour
+	for i := range nearby.Markers {
+		marker := &nearby.Markers[i]
+		sfaf, err := ns.storage.GetSFAFByMarkerID(marker.ID.String())
+		if err != nil {
+			sfaf = nil
+		}
+		if !activeDuring(sfaf, from, to) {
+			continue
+		}
+
+		band, err := OccupiedBand(marker, sfaf)
+		if err != nil {
+			continue
+		}
+		label := marker.Frequency
+		if sfaf != nil && strings.TrimSpace(sfaf.Value("110")) != "" {
+			label = sfaf.Value("110")
+		}
+		neighbours = append(neighbours, nominationNeighbour{marker: marker, band: band, label: label})
+	}
+	return neighbours, nil
+}
+
+// activeDuring reports whether an assignment's authorization (field107) to
+// expiration (field141) overlaps the window. A missing or unreadable date
+// leaves that end open, and assignments without an SFAF record are always
+// active.
+func activeDuring(sfaf *models.SFAF, from, to *time.Time) bool {
+	if sfaf == nil {
+		return true
+	}
+	if authorized, err := time.Parse("20060102", strings.TrimSpace(sfaf.Value("107"))); err == nil && to != nil && authorized.After(*to) {
+		return false
+	}
+	if expires, err := time.Parse("20060102", strings.TrimSpace(sfaf.Value("141"))); err == nil && from != nil && expires.Before(*from) {
+		return false
+	}
+	return true
+}
+
+func (ns *NominationService) isRestricted(band models.OccupiedBand) bool {
+	for _, restricted := range ns.restricted {
+		if band.MaxHz >= restricted.MinHz && band.MinHz <= restricted.MaxHz {
+			return true
+		}
+	}
+	return false
+}
+
+// nominationCandidate measures a channel against the assignments. It is
+// not clear when an assignment overlaps it or is within the guard band.
+func nominationCandidate(hz float64, band models.OccupiedBand, neighbours []nominationNeighbour, guard float64) (models.NominationCandidate, bool) {
+	candidate := models.NominationCandidate{Frequency: frequency.Format(hz), Hz: hz, Band: band}
+
+	var nearest *nominationNeighbour
+	separation := math.Inf(1)
+	for i := range neighbours {
+		neighbour := &neighbours[i]
+		gap := math.Max(neighbour.band.MinHz-band.MaxHz, band.MinHz-neighbour.band.MaxHz)
+		if gap <= 0 || gap <= guard {
+			return candidate, false
+		}
+		if gap < separation || (gap == separation && neighbour.marker.Distance.Meters < nearest.marker.Distance.Meters) {
+			separation, nearest = gap, neighbour
+		}
+	}
+
+	if nearest != nil {
+		candidate.SeparationHz = &separation
+		candidate.Nearest = &models.NominationNeighbour{
+			MarkerID:  nearest.marker.ID,
+			Serial:    nearest.marker.Serial,
+			Frequency: nearest.label,
+			Band:      nearest.band,
+			Distance:  *nearest.marker.Distance,
+		}
+	}
+	return candidate, true
+}
+
+// rankCandidates puts channels without a neighbour first, then the widest
+// separation, then the nearest neighbour farthest away, then the lowest
+// frequency, and numbers them from 1
+func rankCandidates(candidates []models.NominationCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.SeparationHz == nil) != (b.SeparationHz == nil) {
+			return a.SeparationHz == nil
+		}
+		if a.SeparationHz != nil {
+			if *a.SeparationHz != *b.SeparationHz {
+				return *a.SeparationHz > *b.SeparationHz
+			}
+			if a.Nearest.Distance.Meters != b.Nearest.Distance.Meters {
+				return a.Nearest.Distance.Meters > b.Nearest.Distance.Meters
+			}
+		}
+		return a.Hz < b.Hz
+	})
+	for i := range candidates {
+		candidates[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,285 @@
+// nomination_service_test.go
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"sfaf-plotter/frequency"
+	"sfaf-plotter/models"
+
+	"github.com/google/uuid"
+)
+
+func TestNominationChannels(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       models.NominationRequest
+		bandwidth float64
+		channels  []float64
+	}{
+		{
+			name:      "raster stepped by the bandwidth",
+			req:       models.NominationRequest{Band: "M225-M225.1"},
+			bandwidth: 25e3,
+			channels:  []float64{225.025e6, 225.05e6, 225.075e6},
+		},
+		{
+			name:      "raster with its own step",
+			req:       models.NominationRequest{Band: "M30-M30.1", ChannelStepKHz: 12.5},
+			bandwidth: 25e3,
+			channels:  []float64{30.0125e6, 30.025e6, 30.0375e6, 30.05e6, 30.0625e6, 30.075e6, 30.0875e6},
+		},
+		{
+			name:      "no bandwidth keeps the edges",
+			req:       models.NominationRequest{Band: "K2000-K2010", ChannelStepKHz: 5},
+			bandwidth: 0,
+			channels:  []float64{2e6, 2.005e6, 2.01e6},
+		},
+		{
+			name:      "channel list",
+			req:       models.NominationRequest{Channels: []string{"M225.5", "K4551.5", "G1.2"}},
+			bandwidth: 25e3,
+			channels:  []float64{225.5e6, 4551500, 1.2e9},
+		},
+		{
+			name:      "band narrower than the bandwidth",
+			req:       models.NominationRequest{Band: "M225-M225.01"},
+			bandwidth: 25e3,
+			channels:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channels, err := nominationChannels(tt.req, tt.bandwidth)
+			if err != nil {
+				t.Fatalf("nominationChannels: %v", err)
+			}
+			if !reflect.DeepEqual(channels, tt.channels) {
+				t.Errorf("channels %v, want %v", channels, tt.channels)
+			}
+		})
+	}
+}
+
+func TestNominationChannelsErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       models.NominationRequest
+		bandwidth float64
+	}{
+		{"neither band nor channels", models.NominationRequest{}, 25e3},
+		{"band and channels", models.NominationRequest{Band: "M225-M400", Channels: []string{"M225.5"}}, 25e3},
+		{"single frequency band", models.NominationRequest{Band: "M225"}, 25e3},
+		{"channel that is a band", models.NominationRequest{Channels: []string{"M225-M226"}}, 25e3},
+		{"unreadable channel", models.NominationRequest{Channels: []string{"X1"}}, 25e3},
+		{"no step and no bandwidth", models.NominationRequest{Band: "M225-M400"}, 0},
+		{"raster too long", models.NominationRequest{Band: "M225-M400", ChannelStepKHz: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channels, err := nominationChannels(tt.req, tt.bandwidth)
+			if !errors.Is(err, ErrInvalidNomination) {
+				t.Errorf("nominationChannels = %v, %v, want ErrInvalidNomination", channels, err)
+			}
+		})
+	}
+}
+
+func TestNominationBandwidth(t *testing.T) {
+	tests := []struct {
+		req       models.NominationRequest
+		bandwidth float64
+		valid     bool
+	}{
+		{models.NominationRequest{Emission: "16K0F3E", BandwidthKHz: 25}, 16000, true},
+		{models.NominationRequest{BandwidthKHz: 25}, 25000, true},
+		{models.NominationRequest{}, 0, true},
+		{models.NominationRequest{Emission: "2KXXJ3E"}, 0, false},
+		{models.NominationRequest{BandwidthKHz: -1}, 0, false},
+	}
+
+	for _, tt := range tests {
+		bandwidth, err := nominationBandwidth(tt.req)
+		if (err == nil) != tt.valid || bandwidth != tt.bandwidth {
+			t.Errorf("nominationBandwidth(%+v) = %v, %v, want %v (valid %v)", tt.req, bandwidth, err, tt.bandwidth, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidNomination) {
+			t.Errorf("nominationBandwidth(%+v) error %v does not wrap ErrInvalidNomination", tt.req, err)
+		}
+	}
+}
+
+// neighbour is an assignment at hz with a 25 kHz occupied band, km away
+func neighbour(serial string, hz, km float64) nominationNeighbour {
+	distance := NewDistance(km * 1000)
+	return nominationNeighbour{
+		marker: &models.Marker{ID: uuid.New(), Serial: serial, Distance: &distance},
+		band:   models.OccupiedBand{MinHz: hz - 12.5e3, MaxHz: hz + 12.5e3, CenterHz: hz, BandwidthHz: 25e3},
+		label:  frequency.Format(hz),
+	}
+}
+
+func TestNominationCandidate(t *testing.T) {
+	const guard = 25e3
+	neighbours := []nominationNeighbour{
+		neighbour("AF  000001", 225.5e6, 10),
+		neighbour("AF  000002", 226e6, 40),
+	}
+
+	tests := []struct {
+		name       string
+		hz         float64
+		neighbours []nominationNeighbour
+		clear      bool
+		separation float64
+		nearest    string
+	}{
+		{"no assignments", 225.5e6, nil, true, 0, ""},
+		{"co-channel", 225.5e6, neighbours, false, 0, ""},
+		{"overlapping", 225.51e6, neighbours, false, 0, ""},
+		{"within the guard band", 225.54e6, neighbours, false, 0, ""},
+		{"at the guard band", 225.55e6, neighbours, false, 0, ""},
+		{"beyond the guard band", 225.575e6, neighbours, true, 50e3, "AF  000001"},
+		{"nearer in frequency to the second", 225.9e6, neighbours, true, 75e3, "AF  000002"},
+		{"equally separated takes the nearer assignment", 225.75e6, neighbours, true, 225e3, "AF  000001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			band := models.OccupiedBand{MinHz: tt.hz - 12.5e3, MaxHz: tt.hz + 12.5e3, CenterHz: tt.hz, BandwidthHz: 25e3}
+			candidate, clear := nominationCandidate(tt.hz, band, tt.neighbours, guard)
+			if clear != tt.clear {
+				t.Fatalf("clear = %v, want %v", clear, tt.clear)
+			}
+			if !clear {
+				return
+			}
+			if candidate.Hz != tt.hz || candidate.Frequency != frequency.Format(tt.hz) {
+				t.Errorf("candidate %v %q, want %v", candidate.Hz, candidate.Frequency, tt.hz)
+			}
+			switch {
+			case tt.nearest == "":
+				if candidate.SeparationHz != nil || candidate.Nearest != nil {
+					t.Errorf("candidate with no assignments has separation %v and nearest %+v", candidate.SeparationHz, candidate.Nearest)
+				}
+			case candidate.SeparationHz == nil || candidate.Nearest == nil:
+				t.Errorf("candidate has no separation or nearest assignment")
+			case *candidate.SeparationHz != tt.separation || candidate.Nearest.Serial != tt.nearest:
+				t.Errorf("separation %v from %s, want %v from %s", *candidate.SeparationHz, candidate.Nearest.Serial, tt.separation, tt.nearest)
+			}
+		})
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	candidate := func(hz, separation, km float64) models.NominationCandidate {
+		c := models.NominationCandidate{Hz: hz}
+		if separation > 0 {
+			c.SeparationHz = &separation
+			c.Nearest = &models.NominationNeighbour{Distance: NewDistance(km * 1000)}
+		}
+		return c
+	}
+
+	candidates := []models.NominationCandidate{
+		candidate(1, 50e3, 10),
+		candidate(2, 100e3, 10),
+		candidate(3, 0, 0),
+		candidate(4, 50e3, 30),
+		candidate(5, 100e3, 10),
+		candidate(6, 0, 0),
+	}
+	rankCandidates(candidates)
+
+	var order []float64
+	for i, c := range candidates {
+		order = append(order, c.Hz)
+		if c.Rank != i+1 {
+			t.Errorf("candidate %v ranked %d at position %d", c.Hz, c.Rank, i+1)
+		}
+	}
+	// No neighbour first, then widest separation, then farthest nearest
+	// assignment, then lowest frequency
+	if want := []float64{3, 6, 2, 5, 4, 1}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order %v, want %v", order, want)
+	}
+}
+
+func TestActiveDuring(t *testing.T) {
+	date := func(value string) *time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return &d
+	}
+	record := func(authorized, expires string) *models.SFAF {
+		sfaf := &models.SFAF{}
+		sfaf.SetFields(map[string]string{"field107": authorized, "field141": expires})
+		return sfaf
+	}
+
+	tests := []struct {
+		name     string
+		sfaf     *models.SFAF
+		from, to *time.Time
+		active   bool
+	}{
+		{"no SFAF record", nil, date("2026-01-01"), date("2026-12-31"), true},
+		{"no window", record("20250101", "20251231"), nil, nil, true},
+		{"inside the window", record("20260301", "20260601"), date("2026-01-01"), date("2026-12-31"), true},
+		{"covering the window", record("20200101", "20300101"), date("2026-01-01"), date("2026-12-31"), true},
+		{"authorized after the window", record("20270101", "20280101"), date("2026-01-01"), date("2026-12-31"), false},
+		{"expired before the window", record("20200101", "20251231"), date("2026-01-01"), date("2026-12-31"), false},
+		{"expiring on the first day", record("20200101", "20260101"), date("2026-01-01"), nil, true},
+		{"authorized on the last day", record("20261231", ""), nil, date("2026-12-31"), true},
+		{"no dates", record("", ""), date("2026-01-01"), date("2026-12-31"), true},
+		{"unreadable dates", record("SOON", "LATER"), date("2026-01-01"), date("2026-12-31"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := activeDuring(tt.sfaf, tt.from, tt.to); got != tt.active {
+				t.Errorf("activeDuring = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+func TestIsRestricted(t *testing.T) {
+	catalogue, err := LoadRestrictedFrequencies("../web/static/references/restricted-frequencies.json")
+	if err != nil {
+		t.Fatalf("LoadRestrictedFrequencies: %v", err)
+	}
+	if len(catalogue.Frequencies) == 0 {
+		t.Fatalf("no restricted frequencies loaded")
+	}
+
+	ns := &NominationService{restricted: []models.RestrictedFrequency{
+		{Frequency: "M121.5", MinHz: 121.5e6, MaxHz: 121.5e6},
+		{Frequency: "M406-M406.1", MinHz: 406e6, MaxHz: 406.1e6},
+	}}
+	tests := []struct {
+		name       string
+		hz         float64
+		restricted bool
+	}{
+		{"on a restricted frequency", 121.5e6, true},
+		{"occupied band covering it", 121.51e6, true},
+		{"clear of it", 121.55e6, false},
+		{"inside a restricted band", 406.05e6, true},
+		{"overlapping a band edge", 405.99e6, true},
+		{"above a band", 406.2e6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			band := models.OccupiedBand{MinHz: tt.hz - 12.5e3, MaxHz: tt.hz + 12.5e3, CenterHz: tt.hz, BandwidthHz: 25e3}
+			if got := ns.isRestricted(band); got != tt.restricted {
+				t.Errorf("isRestricted(%v) = %v, want %v", tt.hz, got, tt.restricted)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// sfaf_catalogue.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+var catalogueFieldNumber = regexp.MustCompile(`^\d{3}$`)
+
+var catalogueFormats = map[string]bool{
+	models.SFAFFormatText:        true,
+	models.SFAFFormatCode:        true,
+	models.SFAFFormatNumeric:     true,
+	models.SFAFFormatDate:        true,
+	models.SFAFFormatFrequency:   true,
+	models.SFAFFormatEmission:    true,
+	models.SFAFFormatPower:       true,
+	models.SFAFFormatCoordinates: true,
+	models.SFAFFormatRadius:      true,
+}
+
+// LoadSFAFFieldCatalogue reads and checks the MCEB Pub 7 field catalogue.
+// The catalogue is data so it can follow publication changes without a
+// rebuild; the server only needs a restart.
+func LoadSFAFFieldCatalogue(path string) (*models.SFAFFieldCatalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SFAF field catalogue: %w", err)
+	}
+
+	var catalogue models.SFAFFieldCatalogue
+	if err := json.Unmarshal(data, &catalogue); err != nil {
+		return nil, fmt.Errorf("failed to parse SFAF field catalogue %s: %w", path, err)
+	}
+	if err := checkSFAFFieldCatalogue(&catalogue); err != nil {
+		return nil, fmt.Errorf("invalid SFAF field catalogue %s: %w", path, err)
+	}
+	return &catalogue, nil
+}
+
+func checkSFAFFieldCatalogue(catalogue *models.SFAFFieldCatalogue) error {
+	if strings.TrimSpace(catalogue.Version) == "" {
+		return fmt.Errorf("missing version")
+	}
+	if len(catalogue.Fields) == 0 {
+		return fmt.Errorf("no fields defined")
+	}
+
+	seen := make(map[string]bool, len(catalogue.Fields))
+	for i := range catalogue.Fields {
+		spec := &catalogue.Fields[i]
+		if !catalogueFieldNumber.MatchString(spec.Number) {
+			return fmt.Errorf("field %d: number %q must be three digits", i+1, spec.Number)
+		}
+		if seen[spec.Number] {
+			return fmt.Errorf("field%s is defined more than once", spec.Number)
+		}
+		seen[spec.Number] = true
+
+		if spec.Format == "" {
+			spec.Format = models.SFAFFormatText
+		}
+		if !catalogueFormats[spec.Format] {
+			return fmt.Errorf("field%s: unknown format %q", spec.Number, spec.Format)
+		}
+		if spec.Format == models.SFAFFormatCode && len(spec.AllowedValues) == 0 {
+			return fmt.Errorf("field%s: code fields need allowed_values", spec.Number)
+		}
+		if spec.MaxLength < 0 || spec.MaxOccurrences < 0 {
+			return fmt.Errorf("field%s: limits must not be negative", spec.Number)
+		}
+		if spec.MaxOccurrences == 0 {
+			spec.MaxOccurrences = 1
+		}
+		for _, transaction := range spec.RequiredFor {
+			switch strings.ToUpper(transaction) {
+			case models.SFAFTransactionNew, models.SFAFTransactionModify,
+				models.SFAFTransactionDelete, models.SFAFTransactionRenewal:
+			default:
+				return fmt.Errorf("field%s: unknown transaction type %q in required_for", spec.Number, transaction)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+// sfaf_compliance.go
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sfaf-plotter/models"
+)
+
+// MCEB Pub 7 compliance checks, ported from the browser's sfafCompliance.js
+// so that API clients and imports get the same verdicts as the form.
+var sfafComplianceRules = []SFAFRule{
+	{
+		Name:        "field-formats",
+		Description: "Field-specific MCEB Pub 7 formats beyond the catalogue format class",
+		Check:       checkFieldFormats,
+	},
+	{
+		Name:        "conditional-required",
+		Description: "New records carry 144, 716 and 803, and Air Force records carry 701",
+		Check:       checkConditionalRequired,
+	},
+	{
+		Name:        "classified-records",
+		Description: "Classified records (005 C, S or T) list their unclassified fields in 015",
+		Check:       checkClassifiedRecord,
+	},
+	{
+		Name:        "emission-groups",
+		Description: "Each emission group has a station class (113), emission designator (114) and power (115)",
+		Check:       checkEmissionGroups,
+	},
+	{
+		Name:        "outside-usp",
+		Description: "Assignments outside the US&P name a unified command (201)",
+		Check:       checkUnifiedCommand,
+	},
+	{
+		Name:        "function-identifiers",
+		Description: "New records have a major function identifier (511); 511 and 512 use Annex G values",
+		Check:       checkFunctionIdentifiers,
+	},
+}
+
+var (
+	// AAAAYYNNNN: agency code (up to four letters, space padded), year and sequence
+	serialNumberPattern   = regexp.MustCompile(`^[A-Z][A-Z ]{0,3}\d{2}\d{4}$`)
+	compactPowerPattern   = regexp.MustCompile(`^([WKMG])(\d+(?:\.\d+)?)$`)
+	compactDMSPattern     = regexp.MustCompile(`^(\d{2,3})(\d{2})(\d{2})(?:\.\d+)?([NSEW])(\d{3})(\d{2})(\d{2})(?:\.\d+)?([NSEW])$`)
+	compactDecimalPattern = regexp.MustCompile(`^(\d{1,3}\.\d{5,})([NSEW])(\d{1,3}\.\d{5,})([NSEW])$`)
+	nomenclaturePattern   = regexp.MustCompile(`^[A-Z],AN/[A-Z]{3}-\d+(\([A-Z]+\))?$`)
+	certificationPattern  = regexp.MustCompile(`^[A-Z]/[A-Z] \d{2}/\d{5}$`)
+	antennaGainPattern    = regexp.MustCompile(`^-?\d{1,3}(\.\d)?$`)
+	orientationPattern    = regexp.MustCompile(`^(ND|\d{1,3}|[NSEW])$`)
+	timeCodePattern       = regexp.MustCompile(`^([1-4]H(24|X|N|J|T)|[1-4]|\d{3,4})$`)
+	antennaLocationChars  = regexp.MustCompile(`^[A-Za-z0-9\s\-.]+$`)
+)
+
+var antennaPolarizations = []string{"V", "H", "C", "L", "R"}
+
+var classifiedLevels = []string{"C", "S", "T"}
+
+// usGeographicCodes are the field300/400 codes inside the US&P
+var usGeographicCodes = []string{
+	// 50 US States and DC
+	"AK", "AL", "AR", "AZ", "CA", "CO", "CT", "DC", "DE", "FL",
+	"GA", "HI", "IA", "ID", "IL", "IN", "KS", "KY", "LA", "MA",
+	"MD", "ME", "MI", "MN", "MO", "MS", "MT", "NC", "ND", "NE",
+	"NH", "NJ", "NM", "NV", "NY", "OH", "OK", "OR", "PA", "RI",
+	"SC", "SD", "TN", "TX", "UT", "VA", "VT", "WA", "WI", "WV", "WY",
+
+	// US Territories and possessions
+	"PR", "VI", "GUM", "SMA", "MRA", "MDW", "PLM", "WAK", "JON",
+	"US", "USA", "USP",
+}
+
+// foreignGeographicCodes are the common international codes of Annex C
+var foreignGeographicCodes = []string{"CAN", "MEX", "GBR", "FRA", "DEU", "JPN", "AUS"}
+
+// Major function identifiers (field511) from MCEB Pub 7 Annex G
+var majorFunctionIdentifiers = []string{
+	"AIR OPERATIONS", "TACTICAL OPERATIONS", "TRAINING", "COMMUNICATIONS",
+	"INTELLIGENCE", "MEDICAL", "LAW ENFORCEMENT", "RANGE OPERATIONS",
+	"SUSTAINING OPERATIONS", "SPACE OPERATIONS", "EMERGENCY SERVICES",
+	"COMMAND AND CONTROL", "DATA LINK", "SPECIAL OPERATIONS",
+	"DOMESTIC SUPPORT OPERATIONS", "OTHER OPERATIONS",
+}
+
+// Intermediate function identifiers (field512) from MCEB Pub 7 Annex G
+var intermediateFunctionIdentifiers = []string{
+	// Air Operations
+	"FLIGHT OPERATIONS", "FLIGHT TEST", "FORWARD AIR CONTROL POST",
+	"GCA", "PILOT-TO-DISPATCHER", "PILOT-TO-METRO", "PILOT-TO-PILOT",
+	"RAMP CONTROL", "REFUELING", "SHIP/AIR OPERATIONS", "AIR DEFENSE",
+	"AIR DEFENSE WARNING", "AIR DEFENSE / INTERCEPT", "AIR FORCE ONE",
+	"AIR FORCE SPECIAL OPERATIONS", "AIR ROUTE SURVEILLANCE RADAR",
+	"AIR TRAFFIC CONTROL", "AIR/AIR COMMUNICATIONS", "AIR/GROUND/AIR COMMUNICATIONS",
+	"AIRBORNE COMMAND CENTER", "AIRCRAFT", "AIRPORT SURVEILLANCE RADAR",
+	"APPROACH CONTROL", "ARMY AVIATION",
+
+	// Training
+	"TRAINING", "INSTRUCTOR/STUDENT TRAINING", "EXERCISE", "EXPERIMENTAL",
+	"SIMULATOR", "AERO CLUB", "EDUCATION",
+
+	// Tactical Operations
+	"TACTICAL OPERATIONS", "GROUND OPERATIONS", "SEA OPERATIONS",
+	"SPECIAL OPERATIONS", "PSYCHOLOGICAL OPERATIONS", "FIRE SUPPORT",
+	"INFANTRY", "GROUND INTERDICTION", "ARTILLERY", "MISSILE",
+	"SPECIAL FORCES", "RANGER UNITS", "NAVY SPECIAL OPERATIONS",
+	"NAVAL GUNFIRE SUPPORT", "TARGET ACQUISITION", "TARGET SCORING", "TARGET",
+
+	// Communications
+	"COMMUNICATIONS", "SATELLITE COMMUNICATIONS", "RADIO RELAY", "MICROWAVE",
+	"MILSTAR", "FLTSATCOM", "GLOBAL", "MARS", "AFSATCOM", "DSCS",
+	"LEASAT", "SPITFIRE", "TROJAN SPIRIT", "MSE", "TACTS",
+	"IONOSPHERIC SOUNDER", "ISYSCON", "GCCS", "MICROWAVE DATA LINK",
+
+	// Intelligence
+	"INTELLIGENCE", "SURVEILLANCE", "RECONNAISSANCE", "SURVEILLANCE/RECONNAISSANCE",
+	"ACS", "AHFEWS", "ARL", "TRACKWOLF", "TRAILBLAZER", "TEAMMATE",
+
+	// Security/Law Enforcement
+	"LAW ENFORCEMENT", "SECURITY FORCE", "MILITARY POLICE", "SHORE PATROL",
+	"FIRE", "HAZMAT", "CID", "DIS", "NCIS", "OSI", "SCOPE SHIELD",
+	"SPEED MEASUREMENT SYSTEMS", "SURVEILLANCE SYSTEMS", "TETHERED AEROSTAT RADAR",
+	"WEAPONS STORAGE PROTECTION", "ALARM SYSTEMS", "DISASTER PLANNING", "EOD",
+	"ANTI-TERRORISM", "CIVIL DISTURBANCES", "COUNTER DRUG", "PROJECT COTHEN",
+	"SPECIAL SECURITY OPERATIONS",
+
+	// Emergency Services
+	"EMERGENCY SERVICES", "WARNING SYSTEM", "CONSEQUENCE MANAGEMENT", "CBR",
+	"CIVIL SUPPORT TEAM", "ENVIRONMENTAL CLEANUP", "FEMA",
+	"HAZARDOUS MATERIAL RELEASE", "TECHNICAL ESCORT UNIT", "MUTUAL AID",
+
+	// Weather/Environmental
+	"WEATHER", "WEATHER RADAR", "WIND PROFILER", "AMSS", "ASOS", "AWOS",
+	"GOES", "IMETS", "NEXRAD", "RADIOSONDE", "SAWDS",
+
+	// Range Operations
+	"RANGE OPERATIONS", "RANGE CONTROL", "RDTE SUPPORT", "TEST AND MEASUREMENT",
+	"TEST RANGE TIMING", "TEST RANGE", "RDMS", "OCCS SUPPORT",
+
+	// Sustaining Operations
+	"SUSTAINING OPERATIONS", "FLEET SUPPORT", "PUBLIC WORKS", "NATURAL RESOURCES",
+	"RESOURCES CONSERVATION", "SAFETY", "LOCKS AND DAMS", "HYDROLOGIC",
+	"METEOROLOGICAL", "SEISMIC", "NAVAIDS", "NAVIGATION RADAR", "CIVIL ENGINEERING",
+	"CIVIL WORKS", "CONSTRUCTION", "INDUSTRIAL CONTROLS", "PRIME BEEF",
+	"RED HORSE", "SEABEES", "UTILITIES", "WILDLIFE PRESERVATION",
+	"NAVAIDS CONTROLS", "REMOTE BARRIER CONTROL SYSTEMS", "RUNWAY LIGHTING CONTROL",
+
+	// Space Operations
+	"SPACE OPERATIONS", "GPS", "SHUTTLE", "NASA",
+	"SGLS", "ARTS", "TELEMETRY", "TELECOMMAND", "UAV",
+
+	// Logistics
+	"LOGISTICS", "MAINTENANCE", "MUNITIONS", "POL", "RESUPPLY",
+	"INVENTORY/INVENTORY CONTROLS", "SUPPLY AND LOGISTICS", "SHIPYARD",
+	"TRANSPORTATION", "TAXI", "AMPS", "CSSCS", "MTS", "RF TAGS",
+
+	// Global Operations
+	"WORLDWIDE", "CONUS", "NATO", "OTHER OPERATIONS", "SPECIAL PROJECTS",
+	"HAARP", "SURVEY", "DTSS", "ETRAC",
+}
+
+// sfafFieldCheck validates one field value; key is its flat key
+type sfafFieldCheck func(key, value string) []models.SFAFValidationIssue
+
+var sfafFieldChecks = map[string]sfafFieldCheck{
+	"102": checkSerialNumber,
+	"115": checkPowerPrefix,
+	"130": checkTimeCode,
+	"131": checkPercentTime,
+	"140": checkDateRange,
+	"141": checkDateRange,
+	"142": checkDateRange,
+	"143": checkDateRange,
+	"300": checkGeographicCode,
+	"301": checkAntennaLocation,
+	"303": checkCoordinateRanges,
+	"340": checkNomenclature,
+	"343": checkCertification,
+	"357": checkAntennaGain,
+	"362": checkOrientation,
+	"363": checkPolarization,
+	"400": checkGeographicCode,
+	"401": checkAntennaLocation,
+	"403": checkCoordinateRanges,
+	"440": checkNomenclature,
+	"443": checkCertification,
+	"457": checkAntennaGain,
+	"462": checkOrientation,
+	"463": checkPolarization,
+}
+
+func checkFieldFormats(record *models.SFAF) []models.SFAFValidationIssue {
+	var issues []models.SFAFValidationIssue
+	for _, entry := range record.Entries {
+		check, exists := sfafFieldChecks[entry.FieldNumber]
+		value := strings.TrimSpace(entry.Value)
+		if !exists || value == "" {
+			continue
+		}
+		issues = append(issues, check(entry.FlatKey(), value)...)
+	}
+	return issues
+}
+
+func checkSerialNumber(key, value string) []models.SFAFValidationIssue {
+	if !serialNumberPattern.MatchString(strings.ToUpper(value)) {
+		return []models.SFAFValidationIssue{ruleWarning("agency serial number should follow the AAAAYYNNNN pattern (agency code of up to four letters padded with spaces, year, sequence; e.g. AF  240001)", key)}
+	}
+	return nil
+}
+
+// checkPowerPrefix enforces the unit ranges; the format itself is checked
+// by the power parser
+func checkPowerPrefix(key, value string) []models.SFAFValidationIssue {
+	match := compactPowerPattern.FindStringSubmatch(strings.ToUpper(value))
+	if match == nil {
+		return nil
+	}
+	number, _ := strconv.ParseFloat(match[2], 64)
+
+	switch match[1] {
+	case "W":
+		if number >= 1000 {
+			return []models.SFAFValidationIssue{ruleWarning("power of 1000 W or more should use the K prefix (e.g. K1)", key)}
+		}
+	case "K":
+		if number < 1 || number >= 1000 {
+			return []models.SFAFValidationIssue{ruleError("K prefix is only valid for 1 to 999.99999 kW (use W below 1 kW, M from 1000 kW)", key)}
+		}
+	case "M":
+		if number < 1 || number >= 1000 {
+			return []models.SFAFValidationIssue{ruleError("M prefix is only valid for 1 to 999.99999 MW (use K below 1 MW, G from 1000 MW)", key)}
+		}
+	}
+	return nil
+}
+
+func checkTimeCode(key, value string) []models.SFAFValidationIssue {
+	if !timeCodePattern.MatchString(strings.ToUpper(value)) {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid time code %q (expected e.g. 1H24, 2HX or 3HN)", value), key)}
+	}
+	return nil
+}
+
+func checkPercentTime(key, value string) []models.SFAFValidationIssue {
+	percent, err := strconv.Atoi(value)
+	if err != nil || percent < 1 || percent > 99 {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid percent time %q (expected 1-99)", value), key)}
+	}
+	return nil
+}
+
+// checkDateRange flags plausible but unusual years; invalid dates are
+// reported by the catalogue date format
+func checkDateRange(key, value string) []models.SFAFValidationIssue {
+	date, err := time.Parse("20060102", value)
+	if err != nil {
+		return nil
+	}
+	if date.Year() < 1990 || date.Year() > time.Now().Year()+50 {
+		return []models.SFAFValidationIssue{ruleWarning(fmt.Sprintf("date outside the typical range: %d", date.Year()), key)}
+	}
+	return nil
+}
+
+func checkGeographicCode(key, value string) []models.SFAFValidationIssue {
+	code := strings.ToUpper(value)
+	if !containsString(usGeographicCodes, code) && !containsString(foreignGeographicCodes, code) {
+		return []models.SFAFValidationIssue{ruleWarning(fmt.Sprintf("geographic code %s is not in the common Annex C list; verify it is correct", code), key)}
+	}
+	return nil
+}
+
+func checkAntennaLocation(key, value string) []models.SFAFValidationIssue {
+	if !antennaLocationChars.MatchString(value) {
+		return []models.SFAFValidationIssue{ruleError("antenna location may only use letters, digits, spaces, hyphens and periods", key)}
+	}
+	return nil
+}
+
+func checkCoordinateRanges(key, value string) []models.SFAFValidationIssue {
+	compact := strings.ToUpper(strings.Join(strings.Fields(value), ""))
+
+	// The record format is compact; the parser does the range checks
+	if !compactDMSPattern.MatchString(compact) && !compactDecimalPattern.MatchString(compact) {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid coordinates %q (expected DDMMSSNDDDMMSSW)", value), key)}
+	}
+	if _, err := parseCoordinatePair(compact); err != nil {
+		return []models.SFAFValidationIssue{ruleError(err.Error(), key)}
+	}
+	return nil
+}
+
+func checkNomenclature(key, value string) []models.SFAFValidationIssue {
+	if !nomenclaturePattern.MatchString(value) {
+		return []models.SFAFValidationIssue{ruleWarning("equipment nomenclature should follow the military format Type,AN/XXX-NNN(Variant)", key)}
+	}
+	return nil
+}
+
+func checkCertification(key, value string) []models.SFAFValidationIssue {
+	if !certificationPattern.MatchString(value) {
+		return []models.SFAFValidationIssue{ruleWarning("equipment certification should follow the military format X/Y NN/NNNNN (e.g. J/F 12/11171)", key)}
+	}
+	return nil
+}
+
+func checkAntennaGain(key, value string) []models.SFAFValidationIssue {
+	if !antennaGainPattern.MatchString(value) {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid antenna gain %q (expected dB, e.g. 0, 3.5 or -10)", value), key)}
+	}
+	if gain, _ := strconv.ParseFloat(value, 64); gain < -50 || gain > 100 {
+		return []models.SFAFValidationIssue{ruleWarning("antenna gain outside the typical range (-50 to +100 dB)", key)}
+	}
+	return nil
+}
+
+func checkOrientation(key, value string) []models.SFAFValidationIssue {
+	orientation := strings.ToUpper(value)
+	if !orientationPattern.MatchString(orientation) {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid antenna orientation %q (expected ND, degrees or N/S/E/W)", value), key)}
+	}
+	if degrees, err := strconv.Atoi(orientation); err == nil && degrees > 360 {
+		return []models.SFAFValidationIssue{ruleError("antenna orientation must be 0-360 degrees from true north", key)}
+	}
+	return nil
+}
+
+func checkPolarization(key, value string) []models.SFAFValidationIssue {
+	if !containsString(antennaPolarizations, strings.ToUpper(value)) {
+		return []models.SFAFValidationIssue{ruleError(fmt.Sprintf("invalid antenna polarization %q (expected V, H, C, L or R)", value), key)}
+	}
+	return nil
+}
+
+// isNewSFAFRecord reports whether a record is a new assignment (field010 N
+// or no transaction type)
+func isNewSFAFRecord(record *models.SFAF) bool {
+	transaction := strings.ToUpper(strings.TrimSpace(record.Value("010")))
+	return transaction == "" || transaction == models.SFAFTransactionNew
+}
+
+func checkConditionalRequired(record *models.SFAF) []models.SFAFValidationIssue {
+	if !isNewSFAFRecord(record) {
+		return nil
+	}
+
+	var issues []models.SFAFValidationIssue
+	for _, required := range []struct{ number, label string }{
+		{"144", "approval authority indicator"},
+		{"716", "usage code"},
+		{"803", "requestor data POC"},
+	} {
+		if !hasSFAFValue(record, required.number) {
+			issues = append(issues, ruleError(fmt.Sprintf("%s (%s) is required for DoD assignments", required.label, required.number), "field"+required.number))
+		}
+	}
+
+	if strings.EqualFold(strings.TrimSpace(record.Value("200")), "USAF") && !hasSFAFValue(record, "701") {
+		issues = append(issues, ruleError("frequency action officer (701) is required for Air Force assignments", "field701", "field200"))
+	}
+	return issues
+}
+
+func checkClassifiedRecord(record *models.SFAF) []models.SFAFValidationIssue {
+	classification := strings.ToUpper(strings.TrimSpace(record.Value("005")))
+	if containsString(classifiedLevels, classification) && !hasSFAFValue(record, "015") {
+		return []models.SFAFValidationIssue{ruleError(
+			fmt.Sprintf("classified record (005 %s) requires the unclassified data fields (015)", classification),
+			"field015", "field005")}
+	}
+	return nil
+}
+
+func checkEmissionGroups(record *models.SFAF) []models.SFAFValidationIssue {
+	groups := make(map[int]map[string]bool)
+	highest := 0
+	for _, entry := range record.Entries {
+		switch entry.FieldNumber {
+		case "113", "114", "115":
+		default:
+			continue
+		}
+		if strings.TrimSpace(entry.Value) == "" {
+			continue
+		}
+		if groups[entry.Occurrence] == nil {
+			groups[entry.Occurrence] = make(map[string]bool)
+		}
+		groups[entry.Occurrence][entry.FieldNumber] = true
+		if entry.Occurrence > highest {
+			highest = entry.Occurrence
+		}
+	}
+
+	var issues []models.SFAFValidationIssue
+	for occurrence := 1; occurrence <= highest; occurrence++ {
+		var missing, keys []string
+		for _, number := range []string{"113", "114", "115"} {
+			key := models.SFAFEntry{FieldNumber: number, Occurrence: occurrence}.FlatKey()
+			keys = append(keys, key)
+			if !groups[occurrence][number] {
+				missing = append(missing, number)
+			}
+		}
+		if len(missing) > 0 {
+			issues = append(issues, ruleError(
+				fmt.Sprintf("emission group %d is incomplete: missing %s", occurrence, strings.Join(missing, ", ")),
+				keys...))
+		}
+	}
+	return issues
+}
+
+func checkUnifiedCommand(record *models.SFAF) []models.SFAFValidationIssue {
+	if hasSFAFValue(record, "201") {
+		return nil
+	}
+
+	for _, number := range []string{"300", "400"} {
+		code := strings.ToUpper(strings.TrimSpace(record.Value(number)))
+		if code != "" && !containsString(usGeographicCodes, code) {
+			return []models.SFAFValidationIssue{ruleError(
+				fmt.Sprintf("unified command (201) is required for assignments outside the US&P (%s %s)", number, code),
+				"field201", "field"+number)}
+		}
+	}
+	return nil
+}
+
+func checkFunctionIdentifiers(record *models.SFAF) []models.SFAFValidationIssue {
+	var issues []models.SFAFValidationIssue
+
+	major := strings.ToUpper(strings.TrimSpace(record.Value("511")))
+	intermediate := strings.ToUpper(strings.TrimSpace(record.Value("512")))
+
+	switch {
+	case major == "":
+		if isNewSFAFRecord(record) {
+			issues = append(issues, ruleError("major function identifier (511) is required for DoD assignments", "field511"))
+		}
+	case !containsString(majorFunctionIdentifiers, major):
+		issues = append(issues, ruleWarning(fmt.Sprintf("major function identifier %q is not in the Annex G list; verify it is correct", major), "field511"))
+	}
+
+	switch {
+	case intermediate == "":
+		if major != "" {
+			issues = append(issues, ruleWarning("intermediate function identifier (512) is recommended for a complete functional description", "field512", "field511"))
+		}
+	case !containsString(intermediateFunctionIdentifiers, intermediate):
+		issues = append(issues, ruleWarning(fmt.Sprintf("intermediate function identifier %q is not in the Annex G list; verify it is correct", intermediate), "field512"))
+	}
+
+	return issues
+}
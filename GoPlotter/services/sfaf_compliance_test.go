@@ -0,0 +1,184 @@
+// sfaf_compliance_test.go
+package services
+
+import (
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+func TestCheckSerialNumber(t *testing.T) {
+	tests := []struct {
+		serial string
+		valid  bool
+	}{
+		{"AF  240001", true},
+		{"AF240001", true},
+		{"USAF240001", true},
+		{"N   240001", true},
+		{"af  240001", true},
+		{"AF   240001", false}, // padded past four characters
+		{"AF  24001", false},
+		{"AF  2400001", false},
+		{"240001", false},
+		{"AF  24000A", false},
+	}
+	for _, tt := range tests {
+		issues := checkSerialNumber("field102", tt.serial)
+		if valid := len(issues) == 0; valid != tt.valid {
+			t.Errorf("checkSerialNumber(%q) valid = %v, want %v (%v)", tt.serial, valid, tt.valid, issues)
+		}
+		for _, issue := range issues {
+			if issue.Severity != models.SFAFSeverityWarning {
+				t.Errorf("checkSerialNumber(%q) severity = %s", tt.serial, issue.Severity)
+			}
+		}
+	}
+}
+
+func TestComplianceRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  func(*models.SFAF) []models.SFAFValidationIssue
+		record *models.SFAF
+		issues []ruleIssue
+	}{
+		{
+			name:   "conditional: complete new record",
+			check:  checkConditionalRequired,
+			record: sfafRecord("144 Y", "200 USAF", "701 A01", "716 1", "803 SMITH, JOHN, 555-1234"),
+		},
+		{
+			name:   "conditional: new record without 144, 716 and 803",
+			check:  checkConditionalRequired,
+			record: sfafRecord("010 N", "200 USN"),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field144", "approval authority indicator (144) is required"},
+				{models.SFAFSeverityError, "field716", "usage code (716) is required"},
+				{models.SFAFSeverityError, "field803", "requestor data POC (803) is required"},
+			},
+		},
+		{
+			name:   "conditional: modification records",
+			check:  checkConditionalRequired,
+			record: sfafRecord("010 M", "200 USAF"),
+		},
+		{
+			name:   "conditional: Air Force record without 701",
+			check:  checkConditionalRequired,
+			record: sfafRecord("144 Y", "200 usaf", "716 1", "803 SMITH"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field701", "frequency action officer (701) is required for Air Force assignments"}},
+		},
+		{
+			name:   "conditional: other agencies need no 701",
+			check:  checkConditionalRequired,
+			record: sfafRecord("144 Y", "200 USA", "716 1", "803 SMITH"),
+		},
+		{
+			name:   "classified: unclassified record",
+			check:  checkClassifiedRecord,
+			record: sfafRecord("005 UE"),
+		},
+		{
+			name:   "classified: secret record without 015",
+			check:  checkClassifiedRecord,
+			record: sfafRecord("005 S"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field015", "classified record (005 S) requires the unclassified data fields (015)"}},
+		},
+		{
+			name:   "classified: confidential record with 015",
+			check:  checkClassifiedRecord,
+			record: sfafRecord("005 c", "015 110,113,114"),
+		},
+		{
+			name:   "emission: complete groups",
+			check:  checkEmissionGroups,
+			record: sfafRecord("113 FX", "114 16K0F3E", "115 W20", "113 MO", "114 3K00J3E", "115 W5"),
+		},
+		{
+			name:   "emission: no groups",
+			check:  checkEmissionGroups,
+			record: sfafRecord("110 M225.5"),
+		},
+		{
+			name:   "emission: incomplete groups",
+			check:  checkEmissionGroups,
+			record: sfafRecord("113 FX", "114 16K0F3E", "113 MO", "114 "),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field113", "emission group 1 is incomplete: missing 115"},
+				{models.SFAFSeverityError, "field113/02", "emission group 2 is incomplete: missing 114, 115"},
+			},
+		},
+		{
+			name:   "emission: group without a station class",
+			check:  checkEmissionGroups,
+			record: sfafRecord("113 FX", "114 16K0F3E", "115 W20", "114 3K00J3E", "115 W5"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field113/02", "emission group 2 is incomplete: missing 113"}},
+		},
+		{
+			name:   "outside US&P: state",
+			check:  checkUnifiedCommand,
+			record: sfafRecord("300 FL", "400 GUM"),
+		},
+		{
+			name:   "outside US&P: transmitter without 201",
+			check:  checkUnifiedCommand,
+			record: sfafRecord("300 DEU"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field201", "outside the US&P (300 DEU)"}},
+		},
+		{
+			name:   "outside US&P: receiver without 201",
+			check:  checkUnifiedCommand,
+			record: sfafRecord("300 FL", "400 mex"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field201", "outside the US&P (400 MEX)"}},
+		},
+		{
+			name:   "outside US&P: with unified command",
+			check:  checkUnifiedCommand,
+			record: sfafRecord("201 EUCOM", "300 DEU"),
+		},
+		{
+			name:   "function: Annex G values",
+			check:  checkFunctionIdentifiers,
+			record: sfafRecord("511 AIR OPERATIONS", "512 flight test"),
+		},
+		{
+			name:   "function: new record without 511",
+			check:  checkFunctionIdentifiers,
+			record: sfafRecord("010 N"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field511", "major function identifier (511) is required"}},
+		},
+		{
+			name:   "function: modification without 511",
+			check:  checkFunctionIdentifiers,
+			record: sfafRecord("010 M"),
+		},
+		{
+			name:   "function: 511 without 512",
+			check:  checkFunctionIdentifiers,
+			record: sfafRecord("511 TRAINING"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field512", "intermediate function identifier (512) is recommended"}},
+		},
+		{
+			name:   "function: values outside Annex G",
+			check:  checkFunctionIdentifiers,
+			record: sfafRecord("511 PARTY PLANNING", "512 CATERING"),
+			issues: []ruleIssue{
+				{models.SFAFSeverityWarning, "field511", `major function identifier "PARTY PLANNING" is not in the Annex G list`},
+				{models.SFAFSeverityWarning, "field512", `intermediate function identifier "CATERING" is not in the Annex G list`},
+			},
+		},
+		{
+			name:   "field formats: serial number",
+			check:  checkFieldFormats,
+			record: sfafRecord("102 AF  24001", "102 "),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field102", "AAAAYYNNNN pattern"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRuleIssues(t, tt.check(tt.record), tt.issues)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// sfaf_export.go
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sfaf-plotter/frequency"
+	"sfaf-plotter/models"
+
+	"github.com/google/uuid"
+)
+
+// unsafeFileNameChars are replaced when a serial is used as a file name
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FindSFAFs returns the stored SFAF records matching filter, ordered by
+// serial (field102) and then ID so exports are stable.
+func (ss *SFAFService) FindSFAFs(filter models.SFAFExportFilter) ([]*models.SFAF, error) {
+	all, err := ss.storage.GetAllSFAFs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SFAF records: %w", err)
+	}
+
+	// Marker type and position live on the marker, not the SFAF record
+	var markers map[uuid.UUID]models.Marker
+	if filter.MarkerType != "" || filter.Bounds != nil {
+		// Only the markers in the box, through the spatial index
+		var markerFilter models.MarkerFilter
+		if filter.Bounds != nil {
+			markerFilter.Bounds = []models.BoundingBox{*filter.Bounds}
+		}
+		response, err := ss.markerService.FindMarkers(markerFilter)
+		if err != nil {
+			return nil, err
+		}
+		markers = make(map[uuid.UUID]models.Marker, len(response.Markers))
+		for _, marker := range response.Markers {
+			markers[marker.ID] = marker
+		}
+	}
+
+	var matched []*models.SFAF
+	for _, sfaf := range all {
+		sfaf.EnsureEntries()
+		if matchesSFAFExportFilter(sfaf, filter, markers) {
+			matched = append(matched, sfaf)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		si, sj := matched[i].Value("field102"), matched[j].Value("field102")
+		if si != sj {
+			return si < sj
+		}
+		return matched[i].ID.String() < matched[j].ID.String()
+	})
+
+	return matched, nil
+}
+
+func matchesSFAFExportFilter(sfaf *models.SFAF, filter models.SFAFExportFilter, markers map[uuid.UUID]models.Marker) bool {
+	if filter.Agency != "" && !strings.EqualFold(strings.TrimSpace(sfaf.Value("field200")), filter.Agency) {
+		return false
+	}
+	if filter.State != "" && !strings.EqualFold(strings.TrimSpace(sfaf.Value("field300")), filter.State) {
+		return false
+	}
+
+	if markers != nil {
+		marker, exists := markers[sfaf.MarkerID]
+		if !exists {
+			return false
+		}
+		if filter.MarkerType != "" && !strings.EqualFold(marker.MarkerType, filter.MarkerType) {
+			return false
+		}
+		if filter.Bounds != nil && !filter.Bounds.Contains(marker.Latitude, marker.Longitude) {
+			return false
+		}
+	}
+
+	if filter.MinFreqMHz != nil || filter.MaxFreqMHz != nil {
+		f, err := frequency.Parse(sfaf.Value("field110"))
+		if err != nil {
+			return false
+		}
+		minHz, maxHz := 0.0, math.Inf(1)
+		if filter.MinFreqMHz != nil {
+			minHz = *filter.MinFreqMHz * frequency.MHz
+		}
+		if filter.MaxFreqMHz != nil {
+			maxHz = *filter.MaxFreqMHz * frequency.MHz
+		}
+		if !f.Overlaps(minHz, maxHz) {
+			return false
+		}
+	}
+
+	if filter.From != nil || filter.To != nil {
+		date := sfafAssignmentDate(sfaf)
+		if filter.From != nil && date.Before(*filter.From) {
+			return false
+		}
+		if filter.To != nil && date.After(*filter.To) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sfafAssignmentDate is the record's field107 date (YYYYMMDD), falling back
+// to when the record was created.
+func sfafAssignmentDate(sfaf *models.SFAF) time.Time {
+	if date, err := time.Parse("20060102", strings.TrimSpace(sfaf.Value("field107"))); err == nil {
+		return date
+	}
+	return sfaf.CreatedAt
+}
+
+// WriteSFAFExport streams records to w in the given format. For zip exports
+// every record becomes its own file written in recordFormat.
+func (ss *SFAFService) WriteSFAFExport(w io.Writer, records []*models.SFAF, format, recordFormat models.SFAFExportFormat) error {
+	switch format {
+	case models.SFAFExportText:
+		entries := make([][]models.SFAFEntry, len(records))
+		for i, sfaf := range records {
+			entries[i] = sfaf.Entries
+		}
+		return WriteSFAFRecords(w, entries)
+	case models.SFAFExportCSV:
+		return ss.writeSFAFCSV(w, records)
+	case models.SFAFExportJSON:
+		return writeSFAFJSON(w, records)
+	case models.SFAFExportXML:
+		return writeSFAFXML(w, records)
+	case models.SFAFExportZip:
+		return ss.writeSFAFZip(w, records, recordFormat)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// SFAFExportContentType returns the MIME type and file extension for format
+func SFAFExportContentType(format models.SFAFExportFormat) (string, string) {
+	switch format {
+	case models.SFAFExportJSON:
+		return "application/json", "json"
+	case models.SFAFExportCSV:
+		return "text/csv", "csv"
+	case models.SFAFExportXML:
+		return "application/xml", "xml"
+	case models.SFAFExportZip:
+		return "application/zip", "zip"
+	default:
+		return "text/plain; charset=utf-8", "txt"
+	}
+}
+
+// writeSFAFCSV writes one row per field occurrence
+func (ss *SFAFService) writeSFAFCSV(w io.Writer, records []*models.SFAF) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"sfaf_id", "marker_id", "serial", "field", "occurrence", "label", "value"}); err != nil {
+		return err
+	}
+
+	for _, sfaf := range records {
+		serial := sfaf.Value("field102")
+		for _, entry := range sfaf.Entries {
+			label := ss.fieldDefs["field"+entry.FieldNumber].Label
+			row := []string{
+				sfaf.ID.String(), sfaf.MarkerID.String(), serial,
+				entry.FieldNumber, strconv.Itoa(entry.Occurrence), label, entry.Value,
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSFAFJSON streams a JSON array without holding the whole export in memory
+func writeSFAFJSON(w io.Writer, records []*models.SFAF) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[")
+	for i, sfaf := range records {
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		data, err := json.Marshal(sfaf)
+		if err != nil {
+			return err
+		}
+		bw.Write(data)
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+func writeSFAFXML(w io.Writer, records []*models.SFAF) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header + "<sfafs>\n")
+
+	for _, sfaf := range records {
+		fmt.Fprintf(bw, "  <sfaf id=\"%s\" marker_id=\"%s\">\n", sfaf.ID, sfaf.MarkerID)
+		for _, entry := range sfaf.Entries {
+			fmt.Fprintf(bw, "    <field number=\"%s\" occurrence=\"%d\">", entry.FieldNumber, entry.Occurrence)
+			if err := xml.EscapeText(bw, []byte(entry.Value)); err != nil {
+				return err
+			}
+			bw.WriteString("</field>\n")
+		}
+		bw.WriteString("  </sfaf>\n")
+	}
+
+	bw.WriteString("</sfafs>\n")
+	return bw.Flush()
+}
+
+// writeSFAFZip writes one file per record, named after its serial (or ID)
+func (ss *SFAFService) writeSFAFZip(w io.Writer, records []*models.SFAF, recordFormat models.SFAFExportFormat) error {
+	if recordFormat == "" {
+		recordFormat = models.SFAFExportText
+	}
+	if recordFormat == models.SFAFExportZip {
+		return fmt.Errorf("unsupported record format: %s", recordFormat)
+	}
+	_, extension := SFAFExportContentType(recordFormat)
+
+	zw := zip.NewWriter(w)
+	used := make(map[string]int)
+
+	for _, sfaf := range records {
+		base := unsafeFileNameChars.ReplaceAllString(strings.TrimSpace(sfaf.Value("field102")), "_")
+		if strings.Trim(base, "._") == "" {
+			base = sfaf.ID.String()
+		}
+		used[base]++
+		if used[base] > 1 {
+			base = fmt.Sprintf("%s_%d", base, used[base])
+		}
+
+		file, err := zw.Create(base + "." + extension)
+		if err != nil {
+			return err
+		}
+		if err := ss.WriteSFAFExport(file, []*models.SFAF{sfaf}, recordFormat, ""); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// exportSFAFRecord renders a single record in format
+func (ss *SFAFService) exportSFAFRecord(sfaf *models.SFAF, format models.SFAFExportFormat) ([]byte, error) {
+	if format == models.SFAFExportJSON {
+		return json.MarshalIndent(sfaf, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	if err := ss.WriteSFAFExport(&buf, []*models.SFAF{sfaf}, format, ""); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
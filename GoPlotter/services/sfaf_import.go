@@ -0,0 +1,435 @@
+// sfaf_import.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"sfaf-plotter/models"
+
+	"github.com/google/uuid"
+)
+
+// sfafImportItem is one parsed record of an import and how it lines up with
+// the existing assignment carrying the same agency serial (field102).
+type sfafImportItem struct {
+	report   models.SFAFImportRecord
+	marker   *models.Marker
+	sfaf     *models.SFAF
+	previous *models.SFAF // stored SFAF the record would replace or delete
+}
+
+func (item *sfafImportItem) skip(reason string) {
+	item.report.Status = models.SFAFImportSkipped
+	item.report.Reason = reason
+}
+
+func (item *sfafImportItem) conflict(reason string) {
+	item.report.Status = models.SFAFImportConflict
+	item.report.Reason = reason
+}
+
+// sfafImportBatch is what an import writes: the markers it creates, updates
+// and deletes, the SFAF records it saves and the items behind them.
+type sfafImportBatch struct {
+	creates, updates []models.Marker
+	deletes          []uuid.UUID
+	sfafs            []models.SFAF
+	applied          []*sfafImportItem
+}
+
+// planSFAFImport parses an SFAF file and matches every record to existing
+// assignments WITHOUT saving anything.
+func (ss *SFAFService) planSFAFImport(file io.Reader, filename string) ([]*sfafImportItem, error) {
+	items, serials, err := ss.readSFAFImport(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]models.Marker{}
+	if len(serials) > 0 {
+		existing, err = ss.markerService.GetMarkersBySerial(serials)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ss.matchSFAFImport(items, existing)
+	return items, nil
+}
+
+// readSFAFImport parses the records of an SFAF file and returns them with
+// the serials to look up. Records are validated like the form (catalogue
+// field checks, then the default rule set) and skipped when they fail.
+func (ss *SFAFService) readSFAFImport(file io.Reader, filename string) ([]*sfafImportItem, []string, error) {
+	records, err := ParseSFAFRecords(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	items := make([]*sfafImportItem, 0, len(records))
+	var serials []string
+
+	for i, record := range records {
+		fields := record.Fields()
+		item := &sfafImportItem{
+			report: models.SFAFImportRecord{
+				Record:      i + 1,
+				StartLine:   record.StartLine,
+				EndLine:     record.EndLine,
+				Serial:      strings.TrimSpace(fields["field102"]),
+				Transaction: strings.ToUpper(strings.TrimSpace(fields["field010"])),
+			},
+		}
+		items = append(items, item)
+
+		switch item.report.Transaction {
+		case "", models.SFAFTransactionNew, models.SFAFTransactionModify, models.SFAFTransactionRenewal:
+		case models.SFAFTransactionDelete:
+			// Delete records only need their serial; they may omit field303
+			if item.report.Serial == "" {
+				item.skip("delete record has no serial (field102)")
+			} else {
+				serials = append(serials, item.report.Serial)
+			}
+			continue
+		default:
+			item.skip(fmt.Sprintf("unknown transaction type (field010) %q", item.report.Transaction))
+			continue
+		}
+
+		marker, sfaf, err := ss.processSingleSFAFRecord(record.Entries)
+		if err != nil {
+			item.skip(err.Error())
+			continue
+		}
+
+		// The same checks as the form and POST /api/sfaf/validate
+		validation, err := ss.ValidateFieldsWithRuleSet(sfaf.Fields, DefaultSFAFRuleSet)
+		if err != nil {
+			return nil, nil, err
+		}
+		item.report.Issues = validation.Issues
+		if !validation.IsValid {
+			item.skip("failed validation: " + validationMessages(validation))
+			continue
+		}
+
+		item.marker, item.sfaf = marker, sfaf
+		if item.report.Serial != "" {
+			serials = append(serials, item.report.Serial)
+		}
+	}
+
+	return items, serials, nil
+}
+
+// matchSFAFImport lines read records up with the existing assignments,
+// keyed by serial. The transaction type in field010 decides what a record
+// may do: N creates, M and R modify an existing assignment, D deletes one.
+// Records without field010 create or update depending on whether their
+// serial is already assigned.
+func (ss *SFAFService) matchSFAFImport(items []*sfafImportItem, existing map[string]models.Marker) {
+	seen := make(map[string]int)
+	for _, item := range items {
+		if item.report.Status != "" {
+			continue
+		}
+
+		serial := item.report.Serial
+		if serial != "" {
+			if first, duplicate := seen[serial]; duplicate {
+				item.skip(fmt.Sprintf("duplicate serial %s (also record %d)", serial, first))
+				continue
+			}
+			seen[serial] = item.report.Record
+		}
+
+		current, matched := existing[serial]
+		matched = matched && serial != ""
+
+		switch item.report.Transaction {
+		case models.SFAFTransactionDelete:
+			if !matched {
+				item.conflict(fmt.Sprintf("delete record has no existing assignment with serial %s", serial))
+				continue
+			}
+			ss.matchDeletedAssignment(item, current)
+
+		case models.SFAFTransactionModify, models.SFAFTransactionRenewal:
+			if !matched {
+				item.conflict(fmt.Sprintf("modify record has no existing assignment with serial %s", serial))
+				continue
+			}
+			ss.matchExistingAssignment(item, current)
+
+		default:
+			if !matched {
+				item.report.Match = models.SFAFMatchNew
+				item.report.MarkerID = &item.marker.ID
+				continue
+			}
+			ss.matchExistingAssignment(item, current)
+
+			if item.report.Transaction == models.SFAFTransactionNew && item.report.Match == models.SFAFMatchModified {
+				item.conflict(fmt.Sprintf("new record but serial %s is already assigned with different data", serial))
+			}
+		}
+	}
+}
+
+// matchExistingAssignment re-points an import item at the marker (and SFAF)
+// it would update and records the field-by-field diff.
+func (ss *SFAFService) matchExistingAssignment(item *sfafImportItem, current models.Marker) {
+	item.marker.ID = current.ID
+	item.marker.MarkerType = current.MarkerType
+	item.marker.IsDraggable = current.IsDraggable
+	item.marker.CreatedAt = current.CreatedAt
+	item.sfaf.MarkerID = current.ID
+
+	var before []models.SFAFEntry
+	if previous, err := ss.storage.GetSFAFByMarkerID(current.ID.String()); err == nil {
+		item.previous = previous
+		item.sfaf.ID = previous.ID
+		item.sfaf.CreatedAt = previous.CreatedAt
+		before = previous.Entries
+	}
+
+	item.report.MarkerID = &current.ID
+	item.report.Changes = models.DiffSFAFEntries(before, item.sfaf.Entries)
+	if len(item.report.Changes) == 0 {
+		item.report.Match = models.SFAFMatchUnchanged
+	} else {
+		item.report.Match = models.SFAFMatchModified
+	}
+}
+
+// matchDeletedAssignment points a D record at the assignment it removes
+func (ss *SFAFService) matchDeletedAssignment(item *sfafImportItem, current models.Marker) {
+	item.marker = &current
+	if previous, err := ss.storage.GetSFAFByMarkerID(current.ID.String()); err == nil {
+		item.previous = previous
+	}
+
+	item.report.MarkerID = &current.ID
+	item.report.Match = models.SFAFMatchDelete
+}
+
+// PreviewSFAFImport reports what importing a file would do: each record is
+// "new", "unchanged" or "modified" relative to the assignment with the same
+// field102 serial (with a field-level diff), "delete" for D records, or a
+// conflict when its transaction type does not fit. Nothing is written.
+func (ss *SFAFService) PreviewSFAFImport(file io.Reader, filename string) ([]models.SFAFImportRecord, error) {
+	items, err := ss.planSFAFImport(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if item.report.Status == "" && (item.report.Match == models.SFAFMatchNew || item.report.Match == models.SFAFMatchModified) {
+			ss.checkImportConflicts(item)
+		}
+	}
+	return collectImportReport(items), nil
+}
+
+// ImportSFAFFile imports an SFAF file as one unit: new records create
+// markers, modified records update the matching assignment, delete records
+// remove it, and unchanged or conflicting records are skipped. accepted
+// limits the import to the listed 1-based record numbers (from a preview);
+// nil accepts every record. If saving fails nothing is kept and the returned
+// result still carries the report.
+func (ss *SFAFService) ImportSFAFFile(file io.Reader, filename string, accepted map[int]bool) (*models.SFAFImportResult, error) {
+	items, err := ss.planSFAFImport(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := newSFAFImportBatch(items, accepted)
+	result := &models.SFAFImportResult{
+		Markers:     []models.Marker{},
+		SFAFRecords: batch.sfafs,
+		DeletedIDs:  batch.deletes,
+	}
+
+	if len(batch.applied) > 0 {
+		if err := ss.commitSFAFImport(batch); err != nil {
+			for _, item := range batch.applied {
+				item.skip("import rolled back: " + err.Error())
+			}
+			result.SFAFRecords = nil
+			result.DeletedIDs = nil
+			result.Records = collectImportReport(items)
+			countImportReport(result)
+			return result, err
+		}
+	}
+
+	ss.syncImportedAuthorizationAreas(batch.applied)
+
+	// Checked after saving so records of the same file are compared too
+	for _, item := range batch.applied {
+		if item.report.Status == models.SFAFImportImported || item.report.Status == models.SFAFImportUpdated {
+			ss.checkImportConflicts(item)
+		}
+	}
+
+	result.Markers = append(append(result.Markers, batch.creates...), batch.updates...)
+	result.Records = collectImportReport(items)
+	countImportReport(result)
+	return result, nil
+}
+
+// newSFAFImportBatch sets the status of every matched item and collects the
+// writes of those that apply. accepted limits them to the listed record
+// numbers; nil accepts every record.
+func newSFAFImportBatch(items []*sfafImportItem, accepted map[int]bool) sfafImportBatch {
+	var batch sfafImportBatch
+	for _, item := range items {
+		switch {
+		case item.report.Status != "":
+			continue
+		case accepted != nil && !accepted[item.report.Record]:
+			item.skip("rejected in preview")
+			continue
+		case item.report.Match == models.SFAFMatchUnchanged:
+			item.skip("unchanged from existing assignment")
+			continue
+		case item.report.Match == models.SFAFMatchDelete:
+			item.report.Status = models.SFAFImportDeleted
+			batch.deletes = append(batch.deletes, item.marker.ID)
+			batch.applied = append(batch.applied, item)
+			continue
+		case item.report.Match == models.SFAFMatchModified:
+			item.report.Status = models.SFAFImportUpdated
+			batch.updates = append(batch.updates, *item.marker)
+		default:
+			item.report.Status = models.SFAFImportImported
+			batch.creates = append(batch.creates, *item.marker)
+		}
+
+		batch.sfafs = append(batch.sfafs, *item.sfaf)
+		batch.applied = append(batch.applied, item)
+	}
+	return batch
+}
+
+// commitSFAFImport saves markers (database) and SFAF records (storage) as one
+// unit. The storage writes happen inside the marker transaction; if the
+// commit still fails, the stored SFAF records are put back as they were.
+func (ss *SFAFService) commitSFAFImport(batch sfafImportBatch) error {
+	var retired []string
+	for _, item := range batch.applied {
+		if item.report.Status == models.SFAFImportDeleted && item.previous != nil {
+			retired = append(retired, item.previous.ID.String())
+		}
+	}
+
+	sfafsSaved := false
+	err := ss.markerService.ApplyImport(batch.creates, batch.updates, batch.deletes, batch.sfafs, func() error {
+		sfafs := make([]*models.SFAF, len(batch.sfafs))
+		for i := range batch.sfafs {
+			sfafs[i] = &batch.sfafs[i]
+		}
+		if len(sfafs) > 0 {
+			if err := ss.storage.SaveSFAFs(sfafs); err != nil {
+				return fmt.Errorf("failed to save SFAF records: %w", err)
+			}
+		}
+		sfafsSaved = true
+
+		if len(retired) > 0 {
+			if err := ss.storage.DeleteSFAFs(retired); err != nil {
+				return fmt.Errorf("failed to delete SFAF records: %w", err)
+			}
+		}
+		return nil
+	})
+	if err == nil || !sfafsSaved {
+		return err
+	}
+
+	var restore []*models.SFAF
+	var remove []string
+	for _, item := range batch.applied {
+		switch {
+		case item.previous != nil:
+			restore = append(restore, item.previous)
+		case item.sfaf != nil:
+			remove = append(remove, item.sfaf.ID.String())
+		}
+	}
+	if len(restore) > 0 {
+		if rbErr := ss.storage.SaveSFAFs(restore); rbErr != nil {
+			log.Printf("❌ Failed to restore SFAF records after import rollback: %v", rbErr)
+		}
+	}
+	if len(remove) > 0 {
+		if rbErr := ss.storage.DeleteSFAFs(remove); rbErr != nil {
+			log.Printf("❌ Failed to remove SFAF records after import rollback: %v", rbErr)
+		}
+	}
+
+	return err
+}
+
+// syncImportedAuthorizationAreas creates, updates or removes the field306
+// circles of committed import records in one batch. The import itself has
+// already been saved, so a failure is only logged.
+func (ss *SFAFService) syncImportedAuthorizationAreas(applied []*sfafImportItem) {
+	markers := make([]models.Marker, 0, len(applied))
+	radii := make([]string, 0, len(applied))
+	for _, item := range applied {
+		radius := "" // deleted assignments lose their circle
+		if item.report.Status != models.SFAFImportDeleted {
+			radius = item.sfaf.Value("field306")
+		}
+		markers = append(markers, *item.marker)
+		radii = append(radii, radius)
+	}
+
+	if err := ss.geometryService.SyncAuthorizationAreas(markers, radii); err != nil {
+		log.Printf("❌ Authorization areas not updated for %d imported records: %v", len(applied), err)
+	}
+}
+
+// checkImportConflicts reports the stored assignments an imported record may
+// interfere with. A failed check is logged rather than failing the import.
+func (ss *SFAFService) checkImportConflicts(item *sfafImportItem) {
+	report, err := ss.conflictService.CheckAssignment(item.marker, item.sfaf, ss.conflictService.Settings())
+	if err != nil {
+		if !errors.Is(err, ErrNoFrequency) {
+			log.Printf("❌ Frequency conflicts not checked for record %d (%s): %v", item.report.Record, item.report.Serial, err)
+		}
+		return
+	}
+	item.report.FrequencyConflicts = report.Conflicts
+}
+
+func collectImportReport(items []*sfafImportItem) []models.SFAFImportRecord {
+	report := make([]models.SFAFImportRecord, len(items))
+	for i, item := range items {
+		report[i] = item.report
+	}
+	return report
+}
+
+func countImportReport(result *models.SFAFImportResult) {
+	for _, record := range result.Records {
+		switch record.Status {
+		case models.SFAFImportImported:
+			result.Imported++
+		case models.SFAFImportUpdated:
+			result.Updated++
+		case models.SFAFImportDeleted:
+			result.Deleted++
+		case models.SFAFImportConflict:
+			result.Conflicts++
+		default:
+			result.Skipped++
+		}
+	}
+}
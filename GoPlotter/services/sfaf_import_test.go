@@ -0,0 +1,294 @@
+// sfaf_import_test.go
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+	"sfaf-plotter/storage"
+)
+
+func newImportTestService(t *testing.T) *SFAFService {
+	t.Helper()
+	store, err := storage.NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	catalogue, err := LoadSFAFFieldCatalogue("../web/static/references/sfaf-field-catalogue.json")
+	if err != nil {
+		t.Fatalf("LoadSFAFFieldCatalogue: %v", err)
+	}
+	iracNotes, err := NewIRACNoteCatalogue(nil, "../web/static/references/irac-notes-reference.json")
+	if err != nil {
+		t.Fatalf("NewIRACNoteCatalogue: %v", err)
+	}
+	return NewSFAFService(store, NewCoordinateService(), nil, nil, nil, nil, catalogue, iracNotes)
+}
+
+// importRecord is a record that passes validation, with an optional
+// transaction type and any extra field lines
+func importRecord(serial, transaction, frequency string, extra ...string) string {
+	lines := []string{"005.  UE"}
+	if transaction != "" {
+		lines = append(lines, "010.  "+transaction)
+	}
+	if serial != "" {
+		lines = append(lines, "102.  "+serial)
+	}
+	lines = append(lines,
+		"110.  "+frequency, "113.  FX", "114.  16K0F3E", "115.  W20", "144.  Y", "200.  USAF",
+		"300.  FL", "301.  EGLIN", "303.  302521N0864150W", "511.  AIR OPERATIONS",
+		"701.  A01", "716.  1", "803.  SMITH, JOHN, 555-1234")
+	return strings.Join(append(lines, extra...), "\n") + "\n"
+}
+
+// storeAssignment saves a record as an existing assignment and returns its
+// marker for the serial lookup
+func storeAssignment(t *testing.T, ss *SFAFService, text string) models.Marker {
+	t.Helper()
+	records, err := ParseSFAFRecords(strings.NewReader(text))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("ParseSFAFRecords: %d records, %v", len(records), err)
+	}
+	marker, sfaf, err := ss.processSingleSFAFRecord(records[0].Entries)
+	if err != nil {
+		t.Fatalf("processSingleSFAFRecord: %v", err)
+	}
+	if err := ss.storage.SaveSFAF(sfaf); err != nil {
+		t.Fatalf("SaveSFAF: %v", err)
+	}
+	return *marker
+}
+
+type importOutcome struct {
+	status models.SFAFImportStatus
+	match  models.SFAFImportMatch
+	reason string // substring of the reason
+}
+
+func TestMatchSFAFImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   []string // existing assignments
+		input    []string // records of the imported file
+		outcomes []importOutcome
+	}{
+		{
+			name:     "unassigned serial without transaction type is new",
+			input:    []string{importRecord("AF  000001", "", "M225.5")},
+			outcomes: []importOutcome{{match: models.SFAFMatchNew}},
+		},
+		{
+			name:     "record without a serial",
+			input:    []string{importRecord("", "", "M225.5")},
+			outcomes: []importOutcome{{status: models.SFAFImportSkipped, reason: "failed validation: field102"}},
+		},
+		{
+			name:     "same data is unchanged",
+			stored:   []string{importRecord("AF  000001", "", "M225.5")},
+			input:    []string{importRecord("AF  000001", "", "M225.5")},
+			outcomes: []importOutcome{{match: models.SFAFMatchUnchanged}},
+		},
+		{
+			name:     "other data is modified",
+			stored:   []string{importRecord("AF  000001", "", "M225.5")},
+			input:    []string{importRecord("AF  000001", "", "M230")},
+			outcomes: []importOutcome{{match: models.SFAFMatchModified}},
+		},
+		{
+			name:     "N record for an assigned serial with the same data",
+			stored:   []string{importRecord("AF  000001", "N", "M225.5")},
+			input:    []string{importRecord("AF  000001", "N", "M225.5")},
+			outcomes: []importOutcome{{match: models.SFAFMatchUnchanged}},
+		},
+		{
+			name:     "N record for an assigned serial with other data",
+			stored:   []string{importRecord("AF  000001", "N", "M225.5")},
+			input:    []string{importRecord("AF  000001", "N", "M230")},
+			outcomes: []importOutcome{{status: models.SFAFImportConflict, match: models.SFAFMatchModified, reason: "already assigned"}},
+		},
+		{
+			name:   "M and R records modify",
+			stored: []string{importRecord("AF  000001", "", "M225.5"), importRecord("AF  000002", "", "M225.5")},
+			input:  []string{importRecord("AF  000001", "M", "M230"), importRecord("AF  000002", "R", "M225.5")},
+			outcomes: []importOutcome{
+				{match: models.SFAFMatchModified},
+				{match: models.SFAFMatchModified},
+			},
+		},
+		{
+			name:     "M record without an assignment",
+			input:    []string{importRecord("AF  000001", "M", "M230")},
+			outcomes: []importOutcome{{status: models.SFAFImportConflict, reason: "modify record has no existing assignment"}},
+		},
+		{
+			name:   "D records",
+			stored: []string{importRecord("AF  000001", "", "M225.5")},
+			input:  []string{"010.  D\n102.  AF  000001\n", "010.  D\n102.  AF  000009\n", "005.  UE\n010.  D\n"},
+			outcomes: []importOutcome{
+				{match: models.SFAFMatchDelete},
+				{status: models.SFAFImportConflict, reason: "delete record has no existing assignment"},
+				{status: models.SFAFImportSkipped, reason: "no serial"},
+			},
+		},
+		{
+			name:     "unknown transaction type",
+			input:    []string{importRecord("AF  000001", "X", "M225.5")},
+			outcomes: []importOutcome{{status: models.SFAFImportSkipped, reason: "unknown transaction type"}},
+		},
+		{
+			name:  "duplicate serial in the file",
+			input: []string{importRecord("AF  000001", "", "M225.5"), importRecord("AF  000001", "", "M230")},
+			outcomes: []importOutcome{
+				{match: models.SFAFMatchNew},
+				{status: models.SFAFImportSkipped, reason: "duplicate serial AF  000001 (also record 1)"},
+			},
+		},
+		{
+			name: "invalid records are skipped",
+			input: []string{
+				"005.  UE\n102.  AF  000001\n110.  M225.5\n",
+				strings.Replace(importRecord("AF  000002", "", "M225.5"), "113.  FX\n", "", 1),
+				importRecord("AF  000003", "", "M225.5", "500.  S189", "500/02.  S189"),
+				strings.Replace(importRecord("AF  000004", "", "M225.5"), "114.  16K0F3E", "114.  2KXXJ3E", 1),
+			},
+			outcomes: []importOutcome{
+				{status: models.SFAFImportSkipped, reason: "field303"},
+				{status: models.SFAFImportSkipped, reason: "failed validation: field113"},
+				{status: models.SFAFImportSkipped, reason: "IRAC note S189 is repeated"},
+				{status: models.SFAFImportSkipped, reason: "failed validation: field114"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := newImportTestService(t)
+			existing := make(map[string]models.Marker)
+			for _, text := range tt.stored {
+				marker := storeAssignment(t, ss, text)
+				existing[marker.Serial] = marker
+			}
+
+			items, serials, err := ss.readSFAFImport(strings.NewReader(strings.Join(tt.input, "\n")), "test.txt")
+			if err != nil {
+				t.Fatalf("readSFAFImport: %v", err)
+			}
+			for _, serial := range serials {
+				if serial == "" {
+					t.Errorf("blank serial to look up")
+				}
+			}
+			ss.matchSFAFImport(items, existing)
+
+			if len(items) != len(tt.outcomes) {
+				t.Fatalf("%d records, want %d", len(items), len(tt.outcomes))
+			}
+			for i, item := range items {
+				want := tt.outcomes[i]
+				got := item.report
+				if got.Status != want.status || got.Match != want.match || !strings.Contains(got.Reason, want.reason) {
+					t.Errorf("record %d: status %q, match %q, reason %q; want %q, %q, %q",
+						i+1, got.Status, got.Match, got.Reason, want.status, want.match, want.reason)
+					continue
+				}
+
+				switch got.Match {
+				case models.SFAFMatchNew:
+					if got.MarkerID == nil || *got.MarkerID != item.marker.ID || item.sfaf.MarkerID != item.marker.ID {
+						t.Errorf("record %d: new record not tied to its own marker", i+1)
+					}
+				case models.SFAFMatchUnchanged, models.SFAFMatchModified, models.SFAFMatchDelete:
+					current := existing[got.Serial]
+					if got.MarkerID == nil || *got.MarkerID != current.ID || item.marker.ID != current.ID || item.previous == nil {
+						t.Errorf("record %d: not matched to the assignment with serial %s", i+1, got.Serial)
+					}
+					if got.Match != models.SFAFMatchDelete && (item.sfaf.ID != item.previous.ID || item.sfaf.MarkerID != current.ID) {
+						t.Errorf("record %d: SFAF would not replace the stored one", i+1)
+					}
+					if (got.Match == models.SFAFMatchModified) != (len(got.Changes) > 0) {
+						t.Errorf("record %d: match %q with %d changes", i+1, got.Match, len(got.Changes))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewSFAFImportBatch(t *testing.T) {
+	stored := []string{
+		importRecord("AF  000002", "", "M225.5"),
+		importRecord("AF  000003", "", "M225.5"),
+		importRecord("AF  000004", "", "M225.5"),
+	}
+	input := []string{
+		importRecord("AF  000001", "", "M225.5"), // 1: new
+		importRecord("AF  000002", "M", "M230"),  // 2: modified
+		importRecord("AF  000003", "", "M225.5"), // 3: unchanged
+		"010.  D\n102.  AF  000004\n",            // 4: delete
+		importRecord("AF  000005", "M", "M230"),  // 5: conflict
+	}
+
+	tests := []struct {
+		name                      string
+		accepted                  map[int]bool
+		statuses                  []models.SFAFImportStatus
+		creates, updates, deletes int
+	}{
+		{
+			name: "every record",
+			statuses: []models.SFAFImportStatus{models.SFAFImportImported, models.SFAFImportUpdated,
+				models.SFAFImportSkipped, models.SFAFImportDeleted, models.SFAFImportConflict},
+			creates: 1, updates: 1, deletes: 1,
+		},
+		{
+			name:     "accepted records only",
+			accepted: map[int]bool{2: true, 3: true, 5: true},
+			statuses: []models.SFAFImportStatus{models.SFAFImportSkipped, models.SFAFImportUpdated,
+				models.SFAFImportSkipped, models.SFAFImportSkipped, models.SFAFImportConflict},
+			updates: 1,
+		},
+		{
+			name:     "empty accept list",
+			accepted: map[int]bool{},
+			statuses: []models.SFAFImportStatus{models.SFAFImportSkipped, models.SFAFImportSkipped,
+				models.SFAFImportSkipped, models.SFAFImportSkipped, models.SFAFImportConflict},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := newImportTestService(t)
+			existing := make(map[string]models.Marker)
+			for _, text := range stored {
+				marker := storeAssignment(t, ss, text)
+				existing[marker.Serial] = marker
+			}
+			items, _, err := ss.readSFAFImport(strings.NewReader(strings.Join(input, "\n")), "test.txt")
+			if err != nil {
+				t.Fatalf("readSFAFImport: %v", err)
+			}
+			ss.matchSFAFImport(items, existing)
+
+			batch := newSFAFImportBatch(items, tt.accepted)
+			if len(batch.creates) != tt.creates || len(batch.updates) != tt.updates || len(batch.deletes) != tt.deletes {
+				t.Errorf("%d creates, %d updates, %d deletes; want %d, %d, %d",
+					len(batch.creates), len(batch.updates), len(batch.deletes), tt.creates, tt.updates, tt.deletes)
+			}
+			if len(batch.sfafs) != tt.creates+tt.updates || len(batch.applied) != tt.creates+tt.updates+tt.deletes {
+				t.Errorf("%d SFAF records and %d applied items for %d creates, %d updates and %d deletes",
+					len(batch.sfafs), len(batch.applied), tt.creates, tt.updates, tt.deletes)
+			}
+
+			var got []string
+			for _, item := range items {
+				got = append(got, string(item.report.Status))
+			}
+			if want := fmt.Sprint(tt.statuses); fmt.Sprint(got) != want {
+				t.Errorf("statuses %v, want %v", got, want)
+			}
+		})
+	}
+}
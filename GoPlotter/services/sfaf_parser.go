@@ -0,0 +1,131 @@
+// sfaf_parser.go
+package services
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+// SFAF field lines look like "005.  UE", "103/02.  I9387048" or "520.  TEXT".
+// The period must be followed by a space or the end of the line, so wrapped
+// text such as "123.45 MHZ" stays a continuation.
+var sfafFieldLinePattern = regexp.MustCompile(`^(\d{3})(?:/(\d{1,3}))?\.(?:\s+(.*))?$`)
+
+// ParsedSFAFRecord is one record read from an SFAF text file, with entries in
+// file order, together with the (1-based) line range it came from.
+type ParsedSFAFRecord struct {
+	Entries   []models.SFAFEntry
+	StartLine int
+	EndLine   int
+}
+
+// Fields returns the flat compatibility view of the record.
+func (r ParsedSFAFRecord) Fields() map[string]string {
+	return models.SFAFFieldsFromEntries(r.Entries)
+}
+
+// ParseSFAFRecords reads every record in an SFAF text file. Records are
+// separated by blank lines, "---" or "END". A field line for an occurrence
+// the record already has, usually an unsuffixed repeat, takes the field's
+// next free occurrence: "500.  S189" then "500.  C010" is 500/01 and 500/02.
+// Any line that does not start a field continues the previous one, so
+// multi-line 502/520 text and wrapped values of any other field are joined
+// with a single space.
+func ParseSFAFRecords(file io.Reader) ([]ParsedSFAFRecord, error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var records []ParsedSFAFRecord
+	var current ParsedSFAFRecord
+	var currentEntry *models.SFAFEntry
+	index := make(map[string]int) // flat key -> position in current.Entries
+
+	flush := func() {
+		entries := current.Entries[:0]
+		for _, entry := range current.Entries {
+			if entry.Value != "" {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) > 0 {
+			current.Entries = entries
+			records = append(records, current)
+		}
+		current = ParsedSFAFRecord{}
+		currentEntry = nil
+		index = make(map[string]int)
+	}
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line == "---" || line == "END" {
+			flush()
+			continue
+		}
+
+		if match := sfafFieldLinePattern.FindStringSubmatch(line); match != nil {
+			occurrence := 1
+			if match[2] != "" {
+				occurrence, _ = strconv.Atoi(match[2])
+			}
+			if occurrence < 1 {
+				occurrence = 1
+			}
+
+			value := strings.TrimSpace(match[3])
+			if value == "$" {
+				value = ""
+			}
+
+			if current.StartLine == 0 {
+				current.StartLine = lineNumber
+			}
+			current.EndLine = lineNumber
+
+			entry := models.SFAFEntry{FieldNumber: match[1], Occurrence: occurrence, Value: value}
+			for {
+				// A repeated field line is the field's next occurrence
+				if _, exists := index[entry.FlatKey()]; !exists {
+					break
+				}
+				entry.Occurrence++
+			}
+
+			index[entry.FlatKey()] = len(current.Entries)
+			current.Entries = append(current.Entries, entry)
+			currentEntry = &current.Entries[len(current.Entries)-1]
+			continue
+		}
+
+		// Continuation line; text before the first field (headers) is ignored
+		if currentEntry != nil && line != "$" {
+			currentEntry.Value = joinSFAFContinuation(currentEntry.Value, line)
+			current.EndLine = lineNumber
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+	return records, nil
+}
+
+func joinSFAFContinuation(existing, next string) string {
+	if existing == "" {
+		return next
+	}
+	if next == "" {
+		return existing
+	}
+	return existing + " " + next
+}
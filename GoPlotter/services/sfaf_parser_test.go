@@ -0,0 +1,116 @@
+// sfaf_parser_test.go
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+func TestParseSFAFRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		records [][]models.SFAFEntry
+	}{
+		{
+			name:  "single record",
+			input: "005.  UE\n102.  AF  014589\n110.  M225.5\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "005", Occurrence: 1, Value: "UE"},
+				{FieldNumber: "102", Occurrence: 1, Value: "AF  014589"},
+				{FieldNumber: "110", Occurrence: 1, Value: "M225.5"},
+			}},
+		},
+		{
+			name:  "suffixed occurrences",
+			input: "500.  S189\n500/02.  C010\n500/03.  S362\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "500", Occurrence: 1, Value: "S189"},
+				{FieldNumber: "500", Occurrence: 2, Value: "C010"},
+				{FieldNumber: "500", Occurrence: 3, Value: "S362"},
+			}},
+		},
+		{
+			name:  "repeated unsuffixed field line starts the next occurrence",
+			input: "500.  S189\n500.  C010\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "500", Occurrence: 1, Value: "S189"},
+				{FieldNumber: "500", Occurrence: 2, Value: "C010"},
+			}},
+		},
+		{
+			name:  "repeat after a suffixed occurrence takes the next free one",
+			input: "500/02.  C010\n500.  S189\n500.  S362\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "500", Occurrence: 2, Value: "C010"},
+				{FieldNumber: "500", Occurrence: 1, Value: "S189"},
+				{FieldNumber: "500", Occurrence: 3, Value: "S362"},
+			}},
+		},
+		{
+			name:  "continuation lines are joined",
+			input: "520.  FIRST LINE\n      SECOND LINE\n005.  UE\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "520", Occurrence: 1, Value: "FIRST LINE SECOND LINE"},
+				{FieldNumber: "005", Occurrence: 1, Value: "UE"},
+			}},
+		},
+		{
+			name:  "numeric continuation lines",
+			input: "520.  TUNED TO\n      123.45 MHZ WHEN\n300.5 KM FROM SITE\n005.  UE\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "520", Occurrence: 1, Value: "TUNED TO 123.45 MHZ WHEN 300.5 KM FROM SITE"},
+				{FieldNumber: "005", Occurrence: 1, Value: "UE"},
+			}},
+		},
+		{
+			name:  "field line without a value",
+			input: "005.  UE\n144.\n110.\tM225.5\n",
+			records: [][]models.SFAFEntry{{
+				{FieldNumber: "005", Occurrence: 1, Value: "UE"},
+				{FieldNumber: "110", Occurrence: 1, Value: "M225.5"},
+			}},
+		},
+		{
+			name:  "separators, headers and deletions",
+			input: "HEADER TEXT\n005.  UE\n144.  $\n---\n005.  UA\n\nEND\n",
+			records: [][]models.SFAFEntry{
+				{{FieldNumber: "005", Occurrence: 1, Value: "UE"}},
+				{{FieldNumber: "005", Occurrence: 1, Value: "UA"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseSFAFRecords(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseSFAFRecords: %v", err)
+			}
+			var got [][]models.SFAFEntry
+			for _, record := range records {
+				got = append(got, record.Entries)
+			}
+			if !reflect.DeepEqual(got, tt.records) {
+				t.Errorf("got %+v, want %+v", got, tt.records)
+			}
+		})
+	}
+}
+
+func TestParseSFAFRecordsLineRange(t *testing.T) {
+	records, err := ParseSFAFRecords(strings.NewReader("005.  UE\n110.  M225\n\n\n005.  UA\n"))
+	if err != nil {
+		t.Fatalf("ParseSFAFRecords: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].StartLine != 1 || records[0].EndLine != 2 || records[1].StartLine != 5 || records[1].EndLine != 5 {
+		t.Errorf("line ranges %d-%d and %d-%d, want 1-2 and 5-5",
+			records[0].StartLine, records[0].EndLine, records[1].StartLine, records[1].EndLine)
+	}
+}
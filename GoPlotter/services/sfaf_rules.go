@@ -0,0 +1,217 @@
+// sfaf_rules.go
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"sfaf-plotter/models"
+)
+
+// SFAFRule checks relationships between the fields of a whole record.
+// Check returns its findings; the engine fills in the rule name.
+type SFAFRule struct {
+	Name        string
+	Description string
+	Check       func(record *models.SFAF) []models.SFAFValidationIssue
+}
+
+// Rule set names. The default set runs on create, update and import.
+const (
+	DefaultSFAFRuleSet    = "mceb-pub7"
+	CrossFieldSFAFRuleSet = "cross-field"
+	IRACNotesSFAFRuleSet  = "irac-notes"
+)
+
+var sfafCrossFieldRules = []SFAFRule{
+	{
+		Name:        "transmitter-location",
+		Description: "Transmitter coordinates (303) need the state/country (300) and antenna location (301)",
+		Check:       checkTransmitterLocation,
+	},
+	{
+		Name:        "receiver-location",
+		Description: "Receiver state/country (400), antenna location (401) and coordinates (403) are given together",
+		Check:       checkReceiverLocation,
+	},
+	{
+		Name:        "review-after-authorization",
+		Description: "Review date (142) comes after the authorization date (107)",
+		Check:       checkReviewDate,
+	},
+	{
+		Name:        "unique-irac-notes",
+		Description: "An IRAC note code appears only once in fields 500-504",
+		Check:       checkUniqueIRACNotes,
+	},
+}
+
+// newSFAFRuleSets builds a service's rule sets. The IRAC note rules need
+// the note catalogue, so they are left out while it is empty; the
+// coordination note rule needs the coordination areas.
+func newSFAFRuleSets(iracNotes *IRACNoteCatalogue, coordination *CoordinationService) map[string][]SFAFRule {
+	defaultRules := append(append([]SFAFRule{}, sfafCrossFieldRules...), sfafComplianceRules...)
+	ruleSets := map[string][]SFAFRule{
+		CrossFieldSFAFRuleSet: sfafCrossFieldRules,
+	}
+
+	var iracRules []SFAFRule
+	if iracNotes != nil && iracNotes.Len() > 0 {
+		iracRules = append(iracRules, iracNotes.Rules()...)
+	}
+	if coordination != nil {
+		iracRules = append(iracRules, coordination.Rules()...)
+	}
+	if len(iracRules) > 0 {
+		defaultRules = append(defaultRules, iracRules...)
+		ruleSets[IRACNotesSFAFRuleSet] = iracRules
+	}
+	ruleSets[DefaultSFAFRuleSet] = defaultRules
+	return ruleSets
+}
+
+// iracNoteCode matches note codes such as L2, C010 or S189
+var iracNoteCode = regexp.MustCompile(`^[A-Z]\d{1,3}$`)
+
+// sfafIRACNoteFields are the fields that can carry IRAC note codes
+var sfafIRACNoteFields = []string{"500", "501", "502", "503", "504"}
+
+// SFAFRuleSets lists the names of the service's rule sets
+func (ss *SFAFService) SFAFRuleSets() []string {
+	names := make([]string, 0, len(ss.ruleSets))
+	for name := range ss.ruleSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EvaluateSFAFRules runs every rule of the named rule set over a record and
+// returns the errors and warnings in rule order.
+func (ss *SFAFService) EvaluateSFAFRules(ruleSet string, record *models.SFAF) ([]models.SFAFValidationIssue, error) {
+	rules, exists := ss.ruleSets[ruleSet]
+	if !exists {
+		return nil, fmt.Errorf("unknown SFAF rule set %q", ruleSet)
+	}
+
+	record.EnsureEntries()
+
+	var issues []models.SFAFValidationIssue
+	for _, rule := range rules {
+		for _, issue := range rule.Check(record) {
+			issue.Rule = rule.Name
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+func ruleError(message string, fields ...string) models.SFAFValidationIssue {
+	return models.SFAFValidationIssue{Severity: models.SFAFSeverityError, Fields: fields, Message: message}
+}
+
+func ruleWarning(message string, fields ...string) models.SFAFValidationIssue {
+	return models.SFAFValidationIssue{Severity: models.SFAFSeverityWarning, Fields: fields, Message: message}
+}
+
+// hasSFAFValue reports whether a record has a non-blank field value
+func hasSFAFValue(record *models.SFAF, fieldNumber string) bool {
+	return strings.TrimSpace(record.Value(fieldNumber)) != ""
+}
+
+func checkTransmitterLocation(record *models.SFAF) []models.SFAFValidationIssue {
+	if !hasSFAFValue(record, "303") {
+		return nil
+	}
+
+	var issues []models.SFAFValidationIssue
+	if !hasSFAFValue(record, "300") {
+		issues = append(issues, ruleError("transmitter coordinates (303) require the transmitter state/country (300)", "field300", "field303"))
+	}
+	if !hasSFAFValue(record, "301") {
+		issues = append(issues, ruleError("transmitter coordinates (303) require the transmitter antenna location (301)", "field301", "field303"))
+	}
+	return issues
+}
+
+func checkReceiverLocation(record *models.SFAF) []models.SFAFValidationIssue {
+	state, location, coords := hasSFAFValue(record, "400"), hasSFAFValue(record, "401"), hasSFAFValue(record, "403")
+	if !state && !location && !coords {
+		if hasSFAFValue(record, "406") {
+			return []models.SFAFValidationIssue{ruleError("receiver radius (406) requires receiver coordinates (403)", "field406", "field403")}
+		}
+		return nil
+	}
+
+	var issues []models.SFAFValidationIssue
+	if !state {
+		issues = append(issues, ruleError("receiver location requires the receiver state/country (400)", "field400", "field401", "field403"))
+	}
+	if !location {
+		issues = append(issues, ruleError("receiver location requires the receiver antenna location (401)", "field401", "field400", "field403"))
+	}
+	if !coords {
+		if hasSFAFValue(record, "406") {
+			issues = append(issues, ruleError("receiver radius (406) requires receiver coordinates (403)", "field406", "field403"))
+		} else {
+			// Area receivers are described by 400/401 alone
+			issues = append(issues, ruleWarning("receiver has no coordinates (403); only valid for an area receiver", "field403", "field401"))
+		}
+	}
+	return issues
+}
+
+func checkReviewDate(record *models.SFAF) []models.SFAFValidationIssue {
+	// Unparseable dates are reported by the field format check
+	authorized, err := time.Parse("20060102", strings.TrimSpace(record.Value("107")))
+	if err != nil {
+		return nil
+	}
+	review, err := time.Parse("20060102", strings.TrimSpace(record.Value("142")))
+	if err != nil {
+		return nil
+	}
+
+	if !review.After(authorized) {
+		return []models.SFAFValidationIssue{ruleError(
+			fmt.Sprintf("review date (142) %s must be after the authorization date (107) %s",
+				review.Format("2006-01-02"), authorized.Format("2006-01-02")),
+			"field142", "field107")}
+	}
+	return nil
+}
+
+func checkUniqueIRACNotes(record *models.SFAF) []models.SFAFValidationIssue {
+	first := make(map[string]string)
+	var issues []models.SFAFValidationIssue
+
+	for _, entry := range record.Entries {
+		if !containsString(sfafIRACNoteFields, entry.FieldNumber) {
+			continue
+		}
+		code := strings.ToUpper(strings.TrimSpace(entry.Value))
+		if !iracNoteCode.MatchString(code) {
+			continue
+		}
+
+		key := entry.FlatKey()
+		if previous, repeated := first[code]; repeated {
+			issues = append(issues, ruleError(fmt.Sprintf("IRAC note %s is repeated (also in %s)", code, previous), key, previous))
+			continue
+		}
+		first[code] = key
+	}
+	return issues
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+// sfaf_rules_test.go
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+// sfafRecord is a record made of entries written as "303 302521N0864150W".
+// Repeated field numbers become further occurrences.
+func sfafRecord(entries ...string) *models.SFAF {
+	var parsed []models.SFAFEntry
+	occurrences := make(map[string]int)
+	for _, entry := range entries {
+		field, value, _ := strings.Cut(entry, " ")
+		occurrences[field]++
+		parsed = append(parsed, models.SFAFEntry{FieldNumber: field, Occurrence: occurrences[field], Value: value})
+	}
+	record := &models.SFAF{}
+	record.SetEntries(parsed)
+	return record
+}
+
+type ruleIssue struct {
+	severity string
+	field    string // first flat key of the issue
+	message  string // substring of the message
+}
+
+func checkRuleIssues(t *testing.T, issues []models.SFAFValidationIssue, want []ruleIssue) {
+	t.Helper()
+	if len(issues) != len(want) {
+		t.Fatalf("%d issues %v, want %d", len(issues), issues, len(want))
+	}
+	for i, issue := range issues {
+		if issue.Severity != want[i].severity || len(issue.Fields) == 0 || issue.Fields[0] != want[i].field ||
+			!strings.Contains(issue.Message, want[i].message) {
+			t.Errorf("issue %d: %s %v %q; want %s %s %q", i+1, issue.Severity, issue.Fields, issue.Message,
+				want[i].severity, want[i].field, want[i].message)
+		}
+	}
+}
+
+func TestCrossFieldRules(t *testing.T) {
+	const coords = "302521N0864150W"
+	tests := []struct {
+		name   string
+		check  func(*models.SFAF) []models.SFAFValidationIssue
+		record *models.SFAF
+		issues []ruleIssue
+	}{
+		{
+			name:   "transmitter: complete",
+			check:  checkTransmitterLocation,
+			record: sfafRecord("300 FL", "301 EGLIN", "303 "+coords),
+		},
+		{
+			name:   "transmitter: coordinates alone",
+			check:  checkTransmitterLocation,
+			record: sfafRecord("303 " + coords),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field300", "require the transmitter state/country (300)"},
+				{models.SFAFSeverityError, "field301", "require the transmitter antenna location (301)"},
+			},
+		},
+		{
+			name:   "transmitter: no antenna location",
+			check:  checkTransmitterLocation,
+			record: sfafRecord("300 FL", "303 "+coords),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field301", "(301)"}},
+		},
+		{
+			name:   "transmitter: blank coordinates need nothing",
+			check:  checkTransmitterLocation,
+			record: sfafRecord("300 FL", "303  "),
+		},
+		{
+			name:   "receiver: none",
+			check:  checkReceiverLocation,
+			record: sfafRecord("300 FL"),
+		},
+		{
+			name:   "receiver: complete",
+			check:  checkReceiverLocation,
+			record: sfafRecord("400 FL", "401 EGLIN", "403 "+coords, "406 30"),
+		},
+		{
+			name:   "receiver: radius alone",
+			check:  checkReceiverLocation,
+			record: sfafRecord("406 30"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field406", "receiver radius (406) requires receiver coordinates (403)"}},
+		},
+		{
+			name:   "receiver: coordinates alone",
+			check:  checkReceiverLocation,
+			record: sfafRecord("403 " + coords),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field400", "requires the receiver state/country (400)"},
+				{models.SFAFSeverityError, "field401", "requires the receiver antenna location (401)"},
+			},
+		},
+		{
+			name:   "receiver: area receiver",
+			check:  checkReceiverLocation,
+			record: sfafRecord("400 FL", "401 EGLIN"),
+			issues: []ruleIssue{{models.SFAFSeverityWarning, "field403", "only valid for an area receiver"}},
+		},
+		{
+			name:   "receiver: radius without coordinates",
+			check:  checkReceiverLocation,
+			record: sfafRecord("400 FL", "401 EGLIN", "406 30"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field406", "requires receiver coordinates (403)"}},
+		},
+		{
+			name:   "review: after authorization",
+			check:  checkReviewDate,
+			record: sfafRecord("107 20240101", "142 20290101"),
+		},
+		{
+			name:   "review: same day",
+			check:  checkReviewDate,
+			record: sfafRecord("107 20240101", "142 20240101"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field142", "review date (142) 2024-01-01 must be after the authorization date (107) 2024-01-01"}},
+		},
+		{
+			name:   "review: before authorization",
+			check:  checkReviewDate,
+			record: sfafRecord("107 20240101", "142 20231231"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field142", "2023-12-31 must be after"}},
+		},
+		{
+			name:   "review: no authorization date",
+			check:  checkReviewDate,
+			record: sfafRecord("142 20231231"),
+		},
+		{
+			name:   "review: unparseable date",
+			check:  checkReviewDate,
+			record: sfafRecord("107 20240101", "142 2023"),
+		},
+		{
+			name:   "notes: all different",
+			check:  checkUniqueIRACNotes,
+			record: sfafRecord("500 S189", "500 C004", "501 M001", "502 SEE S189"),
+		},
+		{
+			name:   "notes: repeated across fields",
+			check:  checkUniqueIRACNotes,
+			record: sfafRecord("500 S189", "503 s189"),
+			issues: []ruleIssue{{models.SFAFSeverityError, "field503", "IRAC note S189 is repeated (also in field500)"}},
+		},
+		{
+			name:   "notes: repeated occurrences",
+			check:  checkUniqueIRACNotes,
+			record: sfafRecord("500 S189", "500 S189", "500 S189"),
+			issues: []ruleIssue{
+				{models.SFAFSeverityError, "field500/02", "also in field500"},
+				{models.SFAFSeverityError, "field500/03", "also in field500"},
+			},
+		},
+		{
+			name:   "notes: repeated free text",
+			check:  checkUniqueIRACNotes,
+			record: sfafRecord("501 COORDINATE WITH RANGE", "502 COORDINATE WITH RANGE"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRuleIssues(t, tt.check(tt.record), tt.issues)
+		})
+	}
+}
+
+func TestSFAFRuleSets(t *testing.T) {
+	ss := newImportTestService(t)
+	if got, want := ss.SFAFRuleSets(), []string{CrossFieldSFAFRuleSet, DefaultSFAFRuleSet}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SFAFRuleSets() = %v, want %v", got, want)
+	}
+
+	names := func(rules []SFAFRule) []string {
+		var names []string
+		for _, rule := range rules {
+			names = append(names, rule.Name)
+		}
+		return names
+	}
+	defaultRules := names(ss.ruleSets[DefaultSFAFRuleSet])
+	for _, name := range names(sfafCrossFieldRules) {
+		if !containsString(defaultRules, name) {
+			t.Errorf("default rule set has no %s rule", name)
+		}
+	}
+	if len(defaultRules) != len(sfafCrossFieldRules)+len(sfafComplianceRules) {
+		t.Errorf("default rule set has %d rules, want %d", len(defaultRules), len(sfafCrossFieldRules)+len(sfafComplianceRules))
+	}
+
+	// The cross-field set reports only its own rules, with their names
+	record := sfafRecord("303 302521N0864150W")
+	issues, err := ss.EvaluateSFAFRules(CrossFieldSFAFRuleSet, record)
+	if err != nil {
+		t.Fatalf("EvaluateSFAFRules: %v", err)
+	}
+	for _, issue := range issues {
+		if !containsString(names(sfafCrossFieldRules), issue.Rule) {
+			t.Errorf("cross-field set reported rule %q", issue.Rule)
+		}
+	}
+	if _, err := ss.EvaluateSFAFRules("mceb-pub8", record); err == nil {
+		t.Errorf("unknown rule set evaluated")
+	}
+}
+
+// TestSFAFRuleSetSelection checks that create, update and import all run
+// the default rule set, using a review date before the authorization date
+func TestSFAFRuleSetSelection(t *testing.T) {
+	const reviewError = "review date (142) 2023-01-01 must be after the authorization date (107)"
+	valid := importRecord("AF  000001", "", "M225.5", "107.  20240101", "142.  20290101")
+	invalid := importRecord("AF  000002", "", "M225.5", "107.  20240101", "142.  20230101")
+	fieldsOf := func(t *testing.T, ss *SFAFService, text string) map[string]string {
+		records, err := ParseSFAFRecords(strings.NewReader(text))
+		if err != nil || len(records) != 1 {
+			t.Fatalf("ParseSFAFRecords: %d records, %v", len(records), err)
+		}
+		_, sfaf, err := ss.processSingleSFAFRecord(records[0].Entries)
+		if err != nil {
+			t.Fatalf("processSingleSFAFRecord: %v", err)
+		}
+		return sfaf.Fields
+	}
+
+	t.Run("create", func(t *testing.T) {
+		ss := newImportTestService(t)
+		_, err := ss.CreateSFAF(models.CreateSFAFRequest{MarkerID: "8c8ab4d4-7b53-4a8e-9c55-3a4f0b1c2d3e", Fields: fieldsOf(t, ss, invalid)})
+		if !errors.Is(err, ErrSFAFValidation) || !strings.Contains(err.Error(), reviewError) {
+			t.Errorf("CreateSFAF error = %v", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ss := newImportTestService(t)
+		marker := storeAssignment(t, ss, valid)
+		stored, err := ss.storage.GetSFAFByMarkerID(marker.ID.String())
+		if err != nil {
+			t.Fatalf("GetSFAFByMarkerID: %v", err)
+		}
+		_, err = ss.UpdateSFAF(stored.ID.String(), models.UpdateSFAFRequest{Fields: fieldsOf(t, ss, invalid)})
+		if !errors.Is(err, ErrSFAFValidation) || !strings.Contains(err.Error(), reviewError) {
+			t.Errorf("UpdateSFAF error = %v", err)
+		}
+		if current, err := ss.storage.GetSFAF(stored.ID.String()); err != nil || current.Value("142") != "20290101" {
+			t.Errorf("stored record changed by a rejected update")
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		ss := newImportTestService(t)
+		items, _, err := ss.readSFAFImport(strings.NewReader(valid+"\n"+invalid), "test.txt")
+		if err != nil {
+			t.Fatalf("readSFAFImport: %v", err)
+		}
+		if len(items) != 2 || items[0].report.Status == models.SFAFImportSkipped {
+			t.Fatalf("valid record skipped: %+v", items)
+		}
+		if got := items[1].report; got.Status != models.SFAFImportSkipped || !strings.Contains(got.Reason, reviewError) {
+			t.Errorf("invalid record: status %q, reason %q", got.Status, got.Reason)
+		}
+		found := false
+		for _, issue := range items[1].report.Issues {
+			found = found || issue.Rule == "review-after-authorization"
+		}
+		if !found {
+			t.Errorf("import report has no review-after-authorization issue")
+		}
+	})
+}
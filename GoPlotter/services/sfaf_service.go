@@ -2,12 +2,15 @@
 package services
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"sfaf-plotter/emission"
+	"sfaf-plotter/frequency"
 	"sfaf-plotter/models"
+	"sfaf-plotter/power"
 	"sfaf-plotter/storage"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,114 +18,94 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrSFAFValidation wraps the errors of a record that failed validation
+var ErrSFAFValidation = errors.New("SFAF validation failed")
+
 type SFAFService struct {
-	storage      storage.Storage
-	coordService *CoordinateService
-	fieldDefs    map[string]models.SFAFFormDefinition
+	storage         storage.Storage
+	coordService    *CoordinateService
+	markerService   *MarkerService
+	geometryService *GeometryService
+	conflictService *ConflictService
+	catalogue       *models.SFAFFieldCatalogue
+	fieldDefs       map[string]models.SFAFFormDefinition
+	ruleSets        map[string][]SFAFRule
 }
 
-func (ss *SFAFService) ImportSFAFFile(file io.Reader, filename string) ([]models.Marker, []models.SFAF, error) {
-	scanner := bufio.NewScanner(file)
-	var allMarkers []models.Marker
-	var allSfafRecords []models.SFAF
-
-	// Parse multiple SFAF records in one file
-	currentSfafData := make(map[string]string)
-	currentField := ""
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Empty line or "---" might indicate new record
-		if line == "" || line == "---" || line == "END" {
-			// Process current record if it has coordinates
-			if coords := currentSfafData["field303"]; coords != "" {
-				marker, sfaf := ss.processSingleSFAFRecord(currentSfafData)
-				if marker != nil && sfaf != nil {
-					allMarkers = append(allMarkers, *marker)
-					allSfafRecords = append(allSfafRecords, *sfaf)
-				}
-			}
-			// Reset for next record
-			currentSfafData = make(map[string]string)
-			currentField = ""
-			continue
-		}
+// Helper method to process a single SFAF record WITHOUT saving
+func (ss *SFAFService) processSingleSFAFRecord(entries []models.SFAFEntry) (*models.Marker, *models.SFAF, error) {
+	sfafData := models.SFAFFieldsFromEntries(entries)
 
-		// Parse fields (same logic as before)
-		if strings.Contains(line, ".") && !strings.HasPrefix(line, " ") {
-			parts := strings.SplitN(line, ".", 2)
-			if len(parts) == 2 {
-				fieldID := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-
-				currentField = "field" + fieldID
-
-				if value != "" && value != "$" {
-					if existing, exists := currentSfafData[currentField]; exists && (fieldID == "502" || fieldID == "520") {
-						currentSfafData[currentField] = existing + " " + value
-					} else {
-						currentSfafData[currentField] = value
-					}
-				}
-			}
-		} else if currentField != "" && (strings.HasPrefix(currentField, "field502") || strings.HasPrefix(currentField, "field520")) {
-			if line != "$" {
-				currentSfafData[currentField] += " " + line
-			}
-		}
+	coords := sfafData["field303"]
+	if coords == "" {
+		return nil, nil, fmt.Errorf("missing transmitter coordinates (field303)")
 	}
 
-	// Process final record
-	if coords := currentSfafData["field303"]; coords != "" {
-		marker, sfaf := ss.processSingleSFAFRecord(currentSfafData)
-		if marker != nil && sfaf != nil {
-			allMarkers = append(allMarkers, *marker)
-			allSfafRecords = append(allSfafRecords, *sfaf)
-		}
+	coord, err := ss.coordService.ParseCoordinates(coords)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid field303 %q: %v", coords, err)
 	}
 
-	return allMarkers, allSfafRecords, nil
-}
-
-// Helper method to process a single SFAF record WITHOUT saving
-func (ss *SFAFService) processSingleSFAFRecord(sfafData map[string]string) (*models.Marker, *models.SFAF) {
-	coords := sfafData["field303"]
-	if coords != "" {
-		lat, lng, err := ss.parseCoordinates(coords)
-		if err == nil {
-			// Create marker object (don't save yet)
-			marker := models.Marker{
-				ID:          uuid.New(),
-				Serial:      sfafData["field102"],
-				Latitude:    lat,
-				Longitude:   lng,
-				Frequency:   sfafData["field110"], // Keep full frequency
-				Notes:       ss.buildComprehensiveNotes(sfafData),
-				MarkerType:  "imported",
-				IsDraggable: false,
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
-			}
-
-			// Create SFAF object (don't save yet)
-			sfaf := models.SFAF{
-				ID:        uuid.New(),
-				MarkerID:  marker.ID, // Use the SAME ID as the marker
-				Fields:    sfafData,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
+	// Create marker object (don't save yet)
+	marker := models.Marker{
+		ID:          uuid.New(),
+		Serial:      sfafData["field102"],
+		Latitude:    coord.Lat,
+		Longitude:   coord.Lng,
+		Frequency:   sfafData["field110"], // Keep full frequency
+		Power:       sfafData["field115"],
+		Notes:       ss.buildComprehensiveNotes(sfafData),
+		MarkerType:  "imported",
+		IsDraggable: false,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
 
-			return &marker, &sfaf
+	if err := marker.ParseFrequency(); err != nil {
+		return nil, nil, fmt.Errorf("invalid field110 %q: %v", marker.Frequency, err)
+	}
+	if err := marker.ParsePower(); err != nil {
+		return nil, nil, fmt.Errorf("invalid field115 %q: %v", marker.Power, err)
+	}
+	if radius := sfafData["field306"]; radius != "" {
+		if _, _, err := ParseAuthorizedRadius(radius); err != nil {
+			return nil, nil, fmt.Errorf("invalid field306: %v", err)
 		}
 	}
 
-	return nil, nil
+	// Create SFAF object (don't save yet)
+	sfaf := models.SFAF{
+		ID:        uuid.New(),
+		MarkerID:  marker.ID, // Use the SAME ID as the marker
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	sfaf.SetEntries(entries)
+
+	return &marker, &sfaf, nil
 }
 
-func (ss *SFAFService) GetCoordinateFormats(lat, lng float64) models.CoordinateResponse {
-	return ss.coordService.GetAllFormats(lat, lng)
+func (ss *SFAFService) GetCoordinateFormats(lat, lng float64, opts models.CoordinateFormatOptions) models.CoordinateResponse {
+	return ss.coordService.GetAllFormats(lat, lng, opts)
+}
+
+// CoordinateFormatOptions picks the precision for a record's coordinates.
+// An explicit seconds precision wins; otherwise field303 is written back
+// with the seconds precision it was imported with.
+func (ss *SFAFService) CoordinateFormatOptions(sfaf *models.SFAF, precision, mgrsPrecision string) (models.CoordinateFormatOptions, error) {
+	var opts models.CoordinateFormatOptions
+	var err error
+	if opts.MGRSPrecision, err = ss.coordService.ParseMGRSPrecision(mgrsPrecision); err != nil {
+		return opts, err
+	}
+
+	switch {
+	case strings.TrimSpace(precision) != "":
+		opts.SecondsPrecision, err = ss.coordService.ParseDMSPrecision(precision)
+	case sfaf != nil:
+		opts.SecondsPrecision = ss.coordService.DMSPrecisionOf(sfaf.Value("303"))
+	}
+	return opts, err
 }
 
 func (ss *SFAFService) CreateSFAFWithoutValidation(req models.CreateSFAFRequest) (*models.SFAF, error) {
@@ -135,16 +118,17 @@ func (ss *SFAFService) CreateSFAFWithoutValidation(req models.CreateSFAFRequest)
 	sfaf := &models.SFAF{
 		ID:        uuid.New(), // ✅ Direct UUID
 		MarkerID:  markerUUID, // ✅ Converted UUID variable
-		Fields:    req.Fields,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	ss.applyRequestEntries(sfaf, req.Entries, req.Fields)
 
 	// Save to storage
-	if err := ss.storage.SaveSFAF(sfaf); err != nil {
+	if err := ss.saveSFAF(sfaf, nil); err != nil {
 		return nil, err
 	}
 
+	ss.syncAuthorizationArea(sfaf)
 	return sfaf, nil
 }
 
@@ -155,13 +139,13 @@ func (ss *SFAFService) processSingleSFAF(sfafData map[string]string) ([]models.M
 
 	coords := sfafData["field303"]
 	if coords != "" {
-		lat, lng, err := ss.parseCoordinates(coords)
+		coord, err := ss.coordService.ParseCoordinates(coords)
 		if err == nil {
 			marker := models.Marker{
 				ID:          uuid.New(),
 				Serial:      sfafData["field102"],
-				Latitude:    lat,
-				Longitude:   lng,
+				Latitude:    coord.Lat,
+				Longitude:   coord.Lng,
 				Frequency:   sfafData["field110"], // Keep FULL frequency with K prefix
 				Notes:       ss.buildComprehensiveNotes(sfafData),
 				MarkerType:  "imported",
@@ -173,10 +157,10 @@ func (ss *SFAFService) processSingleSFAF(sfafData map[string]string) ([]models.M
 			sfaf := &models.SFAF{
 				ID:        uuid.New(),
 				MarkerID:  marker.ID,
-				Fields:    sfafData,
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
+			sfaf.SetFields(sfafData)
 
 			if err := ss.storage.SaveSFAF(sfaf); err == nil {
 				markers = append(markers, marker)
@@ -188,69 +172,6 @@ func (ss *SFAFService) processSingleSFAF(sfafData map[string]string) ([]models.M
 	return markers, sfafRecords
 }
 
-func (ss *SFAFService) parseCoordinates(coords string) (float64, float64, error) {
-	// Parse format: DDMMSSXDDDMMSSZ (e.g., 302521N0864150W)
-	if len(coords) < 15 {
-		return 0, 0, fmt.Errorf("invalid coordinate format: %s", coords)
-	}
-
-	// Extract latitude: DDMMSS + N/S (first 7 chars)
-	latStr := coords[:6]  // DDMMSS
-	latDir := coords[6:7] // N or S
-
-	// Extract longitude: DDDMMSS + E/W (remaining chars)
-	lngStr := coords[7:14]  // DDDMMSS (note: 7 digits for longitude)
-	lngDir := coords[14:15] // E or W
-
-	fmt.Printf("🔍 Parsing coords: %s -> lat:%s%s lng:%s%s\n", coords, latStr, latDir, lngStr, lngDir)
-
-	lat, err := ss.dmsToDecimal(latStr, latDir)
-	if err != nil {
-		return 0, 0, fmt.Errorf("latitude error: %v", err)
-	}
-
-	lng, err := ss.dmsToDecimal(lngStr, lngDir)
-	if err != nil {
-		return 0, 0, fmt.Errorf("longitude error: %v", err)
-	}
-
-	fmt.Printf("🌍 Final coordinates: lat=%.6f, lng=%.6f\n", lat, lng)
-	return lat, lng, nil
-}
-
-func (ss *SFAFService) dmsToDecimal(dmsStr, direction string) (float64, error) {
-	if len(dmsStr) < 6 {
-		return 0, fmt.Errorf("invalid DMS format: %s", dmsStr)
-	}
-
-	var degrees, minutes, seconds float64
-
-	if len(dmsStr) == 6 {
-		// Latitude format: DDMMSS (2 digits degrees)
-		degrees, _ = strconv.ParseFloat(dmsStr[:2], 64)
-		minutes, _ = strconv.ParseFloat(dmsStr[2:4], 64)
-		seconds, _ = strconv.ParseFloat(dmsStr[4:6], 64)
-	} else if len(dmsStr) == 7 {
-		// Longitude format: DDDMMSS (3 digits degrees)
-		degrees, _ = strconv.ParseFloat(dmsStr[:3], 64)
-		minutes, _ = strconv.ParseFloat(dmsStr[3:5], 64)
-		seconds, _ = strconv.ParseFloat(dmsStr[5:7], 64)
-	} else {
-		return 0, fmt.Errorf("invalid DMS length: %s", dmsStr)
-	}
-
-	fmt.Printf("   DMS parts: %s -> D:%.0f M:%.0f S:%.0f Dir:%s\n", dmsStr, degrees, minutes, seconds, direction)
-
-	decimal := degrees + (minutes / 60.0) + (seconds / 3600.0)
-
-	if direction == "S" || direction == "W" {
-		decimal = -decimal
-	}
-
-	fmt.Printf("   Result: %.6f\n", decimal)
-	return decimal, nil
-}
-
 // Update your existing extractFrequency method or add if it doesn't exist
 func (ss *SFAFService) extractFrequency(freqField string) string {
 	return freqField
@@ -284,14 +205,21 @@ func (ss *SFAFService) buildComprehensiveNotes(sfafData map[string]string) strin
 	return strings.Join(notes, " | ")
 }
 
-func NewSFAFService(storage storage.Storage, coordService *CoordinateService) *SFAFService {
+func NewSFAFService(storage storage.Storage, coordService *CoordinateService, markerService *MarkerService, geometryService *GeometryService, conflictService *ConflictService, coordinationService *CoordinationService, catalogue *models.SFAFFieldCatalogue, iracNotes *IRACNoteCatalogue) *SFAFService {
 	service := &SFAFService{
-		storage:      storage,
-		coordService: coordService,
-		fieldDefs:    make(map[string]models.SFAFFormDefinition),
+		storage:         storage,
+		coordService:    coordService,
+		markerService:   markerService,
+		geometryService: geometryService,
+		conflictService: conflictService,
+		catalogue:       catalogue,
+		fieldDefs:       make(map[string]models.SFAFFormDefinition, len(catalogue.Fields)),
+		ruleSets:        newSFAFRuleSets(iracNotes, coordinationService),
 	}
 
-	service.initializeFieldDefinitions()
+	for _, spec := range catalogue.Fields {
+		service.fieldDefs["field"+spec.Number] = spec.FormDefinition()
+	}
 	return service
 }
 
@@ -307,11 +235,11 @@ func (ss *SFAFService) AutoPopulateFromMarker(marker *models.Marker) map[string]
 	}
 
 	if marker.Frequency != "" {
-		fields["field400"] = marker.Frequency
+		fields["field110"] = marker.Frequency
 	}
 
 	if marker.Serial != "" {
-		fields["field101"] = marker.Serial
+		fields["field102"] = marker.Serial
 	}
 
 	return fields
@@ -321,87 +249,177 @@ func (ss *SFAFService) GetFieldDefinitions() map[string]models.SFAFFormDefinitio
 	return ss.fieldDefs
 }
 
-// Validate SFAF fields
+// GetFieldCatalogue returns the field catalogue the definitions were built from
+func (ss *SFAFService) GetFieldCatalogue() *models.SFAFFieldCatalogue {
+	return ss.catalogue
+}
+
+// Validate SFAF fields against the field catalogue. Requirements depend on
+// the transaction type in field010 (a record without one is new). Fields
+// the catalogue doesn't know are accepted as they are. The default rule set
+// then checks the record as a whole.
 func (ss *SFAFService) ValidateFields(fields map[string]string) models.ValidationResult {
+	result, err := ss.ValidateFieldsWithRuleSet(fields, DefaultSFAFRuleSet)
+	if err != nil {
+		log.Printf("❌ SFAF rules not evaluated: %v", err)
+	}
+	return result
+}
+
+// ValidateFieldsWithRuleSet validates fields like ValidateFields but checks
+// the whole record with the named rule set.
+func (ss *SFAFService) ValidateFieldsWithRuleSet(fields map[string]string, ruleSet string) (models.ValidationResult, error) {
 	result := models.ValidationResult{
-		IsValid: true,
-		Errors:  make(map[string]string),
-		Fields:  make(map[string]models.SFAFFormDefinition),
+		IsValid:  true,
+		Errors:   make(map[string]string),
+		Warnings: make(map[string]string),
+		Fields:   make(map[string]models.SFAFFormDefinition),
+	}
+
+	transaction := strings.ToUpper(strings.TrimSpace(fields["field010"]))
+	if transaction == "" {
+		transaction = models.SFAFTransactionNew
 	}
 
-	// Check all defined fields
 	for fieldID, fieldDef := range ss.fieldDefs {
-		value, exists := fields[fieldID]
+		value := fields[fieldID]
 
 		// Copy field definition and add current value
 		resultField := fieldDef
 		resultField.Validation = value
 		result.Fields[fieldID] = resultField
 
-		// Validate required fields
-		if fieldDef.Required && (!exists || strings.TrimSpace(value) == "") {
+		if fieldDef.IsRequiredFor(transaction) && strings.TrimSpace(value) == "" {
 			result.IsValid = false
-			result.Errors[fieldID] = fmt.Sprintf("%s is required", fieldDef.Label)
+			result.Errors[fieldID] = fmt.Sprintf("%s is required for %s records", fieldDef.Label, transaction)
+		}
+	}
+
+	occurrences := make(map[string]int)
+	for key, value := range fields {
+		fieldNumber, occurrence, err := models.ParseSFAFFlatKey(key)
+		if err != nil {
+			continue
+		}
+		fieldDef, exists := ss.fieldDefs["field"+fieldNumber]
+		if !exists || strings.TrimSpace(value) == "" {
 			continue
 		}
 
-		// Validate field-specific rules
-		if exists && value != "" {
-			if err := ss.validateField(fieldID, value, fieldDef); err != nil {
-				result.IsValid = false
-				result.Errors[fieldID] = err.Error()
-			}
+		if occurrence > occurrences[fieldNumber] {
+			occurrences[fieldNumber] = occurrence
+		}
+		if err := ss.validateField("field"+fieldNumber, value, fieldDef); err != nil {
+			result.IsValid = false
+			result.Errors[key] = err.Error()
 		}
 	}
 
-	return result
+	for fieldNumber, highest := range occurrences {
+		fieldDef := ss.fieldDefs["field"+fieldNumber]
+		if fieldDef.MaxOccurrences > 0 && highest > fieldDef.MaxOccurrences {
+			result.IsValid = false
+			result.Errors["field"+fieldNumber] = fmt.Sprintf("%s allows at most %d occurrences (found occurrence %d)",
+				fieldDef.Label, fieldDef.MaxOccurrences, highest)
+		}
+	}
+
+	record := &models.SFAF{}
+	record.SetFields(fields)
+	issues, err := ss.EvaluateSFAFRules(ruleSet, record)
+	if err != nil {
+		return result, err
+	}
+	addRuleIssues(&result, issues)
+
+	return result, nil
+}
+
+// addRuleIssues records rule findings in a validation result. Each message
+// is also listed under the first field it concerns, after any field error.
+func addRuleIssues(result *models.ValidationResult, issues []models.SFAFValidationIssue) {
+	for _, issue := range issues {
+		result.Issues = append(result.Issues, issue)
+
+		messages := result.Warnings
+		if issue.Severity == models.SFAFSeverityError {
+			result.IsValid = false
+			messages = result.Errors
+		}
+		if len(issue.Fields) == 0 {
+			continue
+		}
+		if existing := messages[issue.Fields[0]]; existing != "" {
+			messages[issue.Fields[0]] = existing + "; " + issue.Message
+		} else {
+			messages[issue.Fields[0]] = issue.Message
+		}
+	}
 }
 
-// Validate individual field
+// validationError summarises a failed validation
+func validationError(result models.ValidationResult) error {
+	return fmt.Errorf("%w: %s", ErrSFAFValidation, validationMessages(result))
+}
+
+// validationMessages lists the errors of a validation, ordered by field
+func validationMessages(result models.ValidationResult) string {
+	keys := make([]string, 0, len(result.Errors))
+	for key := range result.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, len(keys))
+	for i, key := range keys {
+		messages[i] = fmt.Sprintf("%s: %s", key, result.Errors[key])
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate individual field against its catalogue length and format class
 func (ss *SFAFService) validateField(fieldID, value string, fieldDef models.SFAFFormDefinition) error {
+	value = strings.TrimSpace(value)
+	if fieldDef.MaxLength > 0 && len(value) > fieldDef.MaxLength {
+		return fmt.Errorf("%s must be at most %d characters", fieldDef.Label, fieldDef.MaxLength)
+	}
+
 	switch fieldDef.FieldType {
-	case "number":
+	case models.SFAFFormatNumeric:
 		if _, err := strconv.ParseFloat(value, 64); err != nil {
 			return fmt.Errorf("%s must be a valid number", fieldDef.Label)
 		}
-	case "email":
-		if !strings.Contains(value, "@") {
-			return fmt.Errorf("%s must be a valid email address", fieldDef.Label)
+	case models.SFAFFormatDate:
+		if _, err := time.Parse("20060102", value); err != nil {
+			return fmt.Errorf("%s must be a valid date (YYYYMMDD)", fieldDef.Label)
 		}
-	case "date":
-		if _, err := time.Parse("2006-01-02", value); err != nil {
-			return fmt.Errorf("%s must be a valid date (YYYY-MM-DD)", fieldDef.Label)
+	case models.SFAFFormatFrequency:
+		if _, err := frequency.Parse(value); err != nil {
+			return fmt.Errorf("%s: %v", fieldDef.Label, err)
 		}
-	}
-
-	// Field-specific validation
-	switch fieldID {
-	case "field303", "field403": // Coordinate fields
-		if !ss.isValidCoordinateFormat(value) {
-			return fmt.Errorf("invalid coordinate format (expected: DDMMSSXDDDMMSSZ)")
+	case models.SFAFFormatEmission:
+		if _, err := emission.Parse(value); err != nil {
+			return fmt.Errorf("%s: %v", fieldDef.Label, err)
 		}
-	case "field306": // Authorization radius
-		if !ss.isValidRadiusFormat(value) {
-			return fmt.Errorf("invalid radius format (expected: number optionally followed by B or T)")
+	case models.SFAFFormatPower:
+		if _, err := power.Parse(value); err != nil {
+			return fmt.Errorf("%s: %v", fieldDef.Label, err)
 		}
-	case "field400", "field401": // Frequency fields
-		if val, err := strconv.ParseFloat(value, 64); err != nil || val <= 0 {
-			return fmt.Errorf("frequency must be a positive number")
+	case models.SFAFFormatCoordinates:
+		if _, err := ss.coordService.ParseCoordinates(value); err != nil {
+			return fmt.Errorf("%s: %v", fieldDef.Label, err)
 		}
-	}
-
-	// Validate select options
-	if fieldDef.FieldType == "select" && len(fieldDef.Options) > 0 {
-		valid := false
+	case models.SFAFFormatRadius:
+		if !ss.isValidRadiusFormat(value) {
+			return fmt.Errorf("%s: invalid radius format (expected: number optionally followed by B or T)", fieldDef.Label)
+		}
+	case models.SFAFFormatCode:
 		for _, option := range fieldDef.Options {
-			if value == option {
-				valid = true
-				break
+			if strings.EqualFold(value, option) {
+				return nil
 			}
 		}
-		if !valid {
-			return fmt.Errorf("invalid option for %s", fieldDef.Label)
-		}
+		return fmt.Errorf("%s must be one of %s", fieldDef.Label, strings.Join(fieldDef.Options, ", "))
 	}
 
 	return nil
@@ -409,12 +427,6 @@ func (ss *SFAFService) validateField(fieldID, value string, fieldDef models.SFAF
 
 // Create SFAF
 func (ss *SFAFService) CreateSFAF(req models.CreateSFAFRequest) (*models.SFAF, error) {
-	// Validate fields first
-	validation := ss.ValidateFields(req.Fields)
-	if !validation.IsValid {
-		return nil, fmt.Errorf("validation failed")
-	}
-
 	// Convert string MarkerID to UUID - THIS IS THE FIX
 	markerUUID, err := uuid.Parse(req.MarkerID)
 	if err != nil {
@@ -424,68 +436,171 @@ func (ss *SFAFService) CreateSFAF(req models.CreateSFAFRequest) (*models.SFAF, e
 	sfaf := &models.SFAF{
 		ID:        uuid.New(), // ✅ Generates new UUID value
 		MarkerID:  markerUUID, // ✅ Uses converted UUID value
-		Fields:    req.Fields,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	ss.applyRequestEntries(sfaf, req.Entries, req.Fields)
+
+	// Validate fields first
+	validation := ss.ValidateFields(sfaf.Fields)
+	if !validation.IsValid {
+		return nil, validationError(validation)
+	}
 
 	// Save to storage
-	if err := ss.storage.SaveSFAF(sfaf); err != nil {
+	if err := ss.saveSFAF(sfaf, nil); err != nil {
 		return nil, err
 	}
 
+	ss.syncAuthorizationArea(sfaf)
 	return sfaf, nil
 }
 
 // Update SFAF
 func (ss *SFAFService) UpdateSFAF(sfafID string, req models.UpdateSFAFRequest) (*models.SFAF, error) {
 	// Get existing SFAF
-	sfaf, err := ss.storage.GetSFAF(sfafID)
+	if len(req.Entries) == 0 && req.Fields == nil {
+		return nil, fmt.Errorf("fields or entries are required")
+	}
+
+	previous, err := ss.storage.GetSFAF(sfafID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update fields
-	sfaf.Fields = req.Fields
+	// Update a copy so the stored record stays as it was until saved
+	updated := *previous
+	sfaf := &updated
+	ss.applyRequestEntries(sfaf, req.Entries, req.Fields)
 	sfaf.UpdatedAt = time.Now()
 
 	// Validate updated fields
 	validation := ss.ValidateFields(sfaf.Fields)
 	if !validation.IsValid {
-		return nil, fmt.Errorf("validation failed")
+		return nil, validationError(validation)
 	}
 
 	// Save updated SFAF
-	if err := ss.storage.SaveSFAF(sfaf); err != nil {
+	if err := ss.saveSFAF(sfaf, previous); err != nil {
 		return nil, err
 	}
 
+	ss.syncAuthorizationArea(sfaf)
 	return sfaf, nil
 }
 
-// Helper validation functions
-func (ss *SFAFService) isValidCoordinateFormat(coord string) bool {
-	// Basic validation for coordinate format like "302521N0864150W"
-	if len(coord) < 13 || len(coord) > 15 {
-		return false
+// saveSFAF saves a record to storage and its sfaf_fields rows as one unit,
+// like commitSFAFImport: the storage write happens inside the database
+// transaction, and if the commit still fails the stored record is put back
+// to previous (or removed when it is new).
+func (ss *SFAFService) saveSFAF(sfaf, previous *models.SFAF) error {
+	marker, err := ss.assignmentMarker(sfaf)
+	if err != nil {
+		return err
+	}
+
+	sfafSaved := false
+	err = ss.markerService.SaveSFAFRecord(marker, sfaf, func() error {
+		if err := ss.storage.SaveSFAF(sfaf); err != nil {
+			return fmt.Errorf("failed to save SFAF record: %w", err)
+		}
+		sfafSaved = true
+		return nil
+	})
+	if err == nil || !sfafSaved {
+		return err
 	}
 
-	// Check for valid direction letters
-	hasValidDir := strings.ContainsAny(coord, "NSEW")
-	return hasValidDir
+	if previous != nil {
+		if rbErr := ss.storage.SaveSFAF(previous); rbErr != nil {
+			log.Printf("❌ Failed to restore SFAF %s after rollback: %v", sfaf.ID, rbErr)
+		}
+	} else if rbErr := ss.storage.DeleteSFAF(sfaf.ID.String()); rbErr != nil {
+		log.Printf("❌ Failed to remove SFAF %s after rollback: %v", sfaf.ID, rbErr)
+	}
+	return err
+}
+
+// syncAuthorizationArea updates the field306 circle of a saved record's
+// marker. The record is already stored, so failures are only logged.
+func (ss *SFAFService) syncAuthorizationArea(sfaf *models.SFAF) {
+	markerResp, err := ss.markerService.GetMarker(sfaf.MarkerID.String())
+	if err != nil {
+		log.Printf("❌ Authorization area not updated for SFAF %s: %v", sfaf.ID, err)
+		return
+	}
+
+	if _, err := ss.geometryService.SyncAuthorizationArea(*markerResp.Marker, sfaf.Value("field306")); err != nil {
+		log.Printf("❌ Authorization area not updated for marker %s: %v", sfaf.MarkerID, err)
+	}
 }
 
+// assignmentMarker returns a record's marker with its raw and parsed
+// frequency and power taken from field110 and field115, so frequency and
+// power filters match what the record says. A record without one of them
+// leaves the marker's own value.
+func (ss *SFAFService) assignmentMarker(sfaf *models.SFAF) (*models.Marker, error) {
+	markerResp, err := ss.markerService.GetMarker(sfaf.MarkerID.String())
+	if err != nil {
+		return nil, fmt.Errorf("marker %s of SFAF %s: %w", sfaf.MarkerID, sfaf.ID, err)
+	}
+
+	marker := *markerResp.Marker
+	if value := strings.TrimSpace(sfaf.Value("110")); value != "" {
+		marker.Frequency = value
+		if err := marker.ParseFrequency(); err != nil {
+			return nil, fmt.Errorf("%w: field110: %v", ErrSFAFValidation, err)
+		}
+	}
+	if value := strings.TrimSpace(sfaf.Value("115")); value != "" {
+		marker.Power = value
+		if err := marker.ParsePower(); err != nil {
+			return nil, fmt.Errorf("%w: field115: %v", ErrSFAFValidation, err)
+		}
+	}
+	return &marker, nil
+}
+
+// releasedMarker is the marker of a record about to be deleted, without the
+// frequency and power saveSFAF copied onto it from fields 110 and 115
+func (ss *SFAFService) releasedMarker(sfaf *models.SFAF) (*models.Marker, error) {
+	markerResp, err := ss.markerService.GetMarker(sfaf.MarkerID.String())
+	if err != nil {
+		return nil, fmt.Errorf("marker %s of SFAF %s: %w", sfaf.MarkerID, sfaf.ID, err)
+	}
+
+	marker := *markerResp.Marker
+	if value := strings.TrimSpace(sfaf.Value("110")); value != "" && value == marker.Frequency {
+		marker.Frequency = ""
+		marker.ParseFrequency()
+	}
+	if value := strings.TrimSpace(sfaf.Value("115")); value != "" && value == marker.Power {
+		marker.Power = ""
+		marker.ParsePower()
+	}
+	return &marker, nil
+}
+
+// Helper validation functions
 func (ss *SFAFService) isValidRadiusFormat(radius string) bool {
 	if radius == "" {
 		return true // Optional field
 	}
 
-	// Remove B or T suffix
-	cleanRadius := strings.TrimRight(radius, "BTbt")
-	_, err := strconv.ParseFloat(cleanRadius, 64)
+	_, _, err := ParseAuthorizedRadius(radius)
 	return err == nil
 }
 
+// applyRequestEntries sets the record from request entries when present,
+// falling back to the flat field map older clients send.
+func (ss *SFAFService) applyRequestEntries(sfaf *models.SFAF, entries []models.SFAFEntry, fields map[string]string) {
+	if len(entries) > 0 {
+		sfaf.SetEntries(entries)
+		return
+	}
+	sfaf.SetFields(fields)
+}
+
 func (ss *SFAFService) GetSFAFByMarkerID(markerID string) (*models.SFAF, error) {
 	log.Printf("🔍 Looking for SFAF data for marker: %s", markerID)
 
@@ -506,358 +621,43 @@ func (ss *SFAFService) ExportSFAF(sfafID string, format models.SFAFExportFormat)
 	if err != nil {
 		return nil, err
 	}
+	sfaf.EnsureEntries()
 
-	switch format {
-	case models.SFAFExportJSON:
-		return ss.exportToJSON(sfaf)
-	case models.SFAFExportCSV:
-		return ss.exportToCSV(sfaf)
-	case models.SFAFExportXML:
-		return ss.exportToXML(sfaf)
-	default:
-		return nil, fmt.Errorf("unsupported export format: %s", format)
-	}
+	return ss.exportSFAFRecord(sfaf, format)
 }
 
-// Export helper functions
-func (ss *SFAFService) exportToJSON(sfaf *models.SFAF) ([]byte, error) {
-	// Create structured export data
-	exportData := map[string]interface{}{
-		"sfaf_id":    sfaf.ID,
-		"marker_id":  sfaf.MarkerID,
-		"created_at": sfaf.CreatedAt,
-		"updated_at": sfaf.UpdatedAt,
-		"fields":     sfaf.Fields,
-		"field_defs": ss.fieldDefs,
+// DeleteSFAF removes a record with its sfaf_fields rows and clears the
+// frequency and power it gave its marker, in one transaction like saveSFAF.
+// If the transaction fails after the record was deleted, it is put back.
+func (ss *SFAFService) DeleteSFAF(id string) error {
+	sfaf, err := ss.storage.GetSFAF(id)
+	if err != nil {
+		return err
 	}
-
-	return []byte(fmt.Sprintf("%+v", exportData)), nil
-}
-
-func (ss *SFAFService) exportToCSV(sfaf *models.SFAF) ([]byte, error) {
-	csv := "Field,Label,Value\n"
-	for fieldID, value := range sfaf.Fields {
-		if fieldDef, exists := ss.fieldDefs[fieldID]; exists {
-			csv += fmt.Sprintf("%s,%s,%s\n", fieldID, fieldDef.Label, value)
-		}
+	marker, err := ss.releasedMarker(sfaf)
+	if err != nil {
+		return err
 	}
-	return []byte(csv), nil
-}
 
-func (ss *SFAFService) exportToXML(sfaf *models.SFAF) ([]byte, error) {
-	xml := "<sfaf>\n"
-	for fieldID, value := range sfaf.Fields {
-		if fieldDef, exists := ss.fieldDefs[fieldID]; exists {
-			xml += fmt.Sprintf("  <%s label=\"%s\">%s</%s>\n", fieldID, fieldDef.Label, value, fieldID)
+	sfafDeleted := false
+	err = ss.markerService.DeleteSFAFRecord(marker, func() error {
+		if err := ss.storage.DeleteSFAF(id); err != nil {
+			return fmt.Errorf("failed to delete SFAF record: %w", err)
+		}
+		sfafDeleted = true
+		return nil
+	})
+	if err != nil {
+		if sfafDeleted {
+			if rbErr := ss.storage.SaveSFAF(sfaf); rbErr != nil {
+				log.Printf("❌ Failed to restore SFAF %s after rollback: %v", sfaf.ID, rbErr)
+			}
 		}
+		return err
 	}
-	xml += "</sfaf>"
-	return []byte(xml), nil
-}
 
-func (ss *SFAFService) DeleteSFAF(id string) error {
-	return ss.storage.DeleteSFAF(id)
-}
-
-// Initialize complete SFAF field definitions based on MCEBPub7.csv
-func (ss *SFAFService) initializeFieldDefinitions() {
-	ss.fieldDefs = map[string]models.SFAFFormDefinition{
-		// 100 Series - Agency Information
-		"field100": {
-			FieldNumber: "field100", Label: "Agency Code", Required: true, FieldType: "select",
-			Options: []string{"DOD", "DHS", "DOJ", "NASA", "NOAA", "FAA", "FCC", "Other"},
-			Help:    "Federal agency requesting frequency assignment",
-		},
-		"field101": {
-			FieldNumber: "field101", Label: "Agency Serial Number", Required: true, FieldType: "text",
-			Help: "Unique identifier assigned by requesting agency",
-		},
-		"field102": {
-			FieldNumber: "field102", Label: "Date of Application", Required: true, FieldType: "date",
-			Help: "Date application was submitted",
-		},
-		"field103": {
-			FieldNumber: "field103", Label: "Expiration Date", Required: false, FieldType: "date",
-			Help: "Requested expiration date for assignment",
-		},
-		"field104": {
-			FieldNumber: "field104", Label: "Previous Assignment", Required: false, FieldType: "text",
-			Help: "Reference to previous related assignment",
-		},
-
-		// 200 Series - System Information
-		"field200": {
-			FieldNumber: "field200", Label: "System Name", Required: true, FieldType: "text",
-			Help: "Name or designation of the radio system",
-		},
-		"field201": {
-			FieldNumber: "field201", Label: "System Type", Required: true, FieldType: "select",
-			Options: []string{"Fixed", "Mobile", "Portable", "Aeronautical", "Maritime", "Satellite"},
-			Help:    "Type of radio system",
-		},
-		"field202": {
-			FieldNumber: "field202", Label: "Service Type", Required: true, FieldType: "select",
-			Options: []string{"Government Fixed", "Government Mobile", "Radiolocation", "Radionavigation", "Satellite", "Broadcasting"},
-			Help:    "ITU radio service classification",
-		},
-		"field203": {
-			FieldNumber: "field203", Label: "Purpose", Required: true, FieldType: "textarea",
-			Help: "Detailed description of system purpose and mission",
-		},
-
-		// 300 Series - Location Information
-		"field300": {
-			FieldNumber: "field300", Label: "State/Country", Required: true, FieldType: "select",
-			Options: []string{"AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "FL", "GA", "HI", "ID", "IL", "IN", "IA", "KS", "KY", "LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH", "NJ", "NM", "NY", "NC", "ND", "OH", "OK", "OR", "PA", "RI", "SC", "SD", "TN", "TX", "UT", "VT", "VA", "WA", "WV", "WI", "WY"},
-			Help:    "State or country where transmitter is located",
-		},
-		"field301": {
-			FieldNumber: "field301", Label: "Antenna Location", Required: true, FieldType: "text",
-			Help: "Physical description of antenna location",
-		},
-		"field302": {
-			FieldNumber: "field302", Label: "Site Name", Required: false, FieldType: "text",
-			Help: "Name of the site or facility",
-		},
-		"field303": {
-			FieldNumber: "field303", Label: "Antenna Coordinates", Required: true, FieldType: "text",
-			Help: "Format: DDMMSSXDDDMMSSZ (e.g., 302521N0864150W)",
-		},
-		"field304": {
-			FieldNumber: "field304", Label: "Ground Elevation (m)", Required: false, FieldType: "number",
-			Help: "Antenna site elevation above mean sea level in meters",
-		},
-		"field305": {
-			FieldNumber: "field305", Label: "Antenna Height AGL (m)", Required: false, FieldType: "number",
-			Help: "Antenna height above ground level in meters",
-		},
-		"field306": {
-			FieldNumber: "field306", Label: "Authorization Radius (km)", Required: false, FieldType: "text",
-			Help: "Coordination radius in kilometers (append B for basic, T for tactical)",
-		},
-		"field307": {
-			FieldNumber: "field307", Label: "Area of Operation", Required: false, FieldType: "textarea",
-			Help: "Geographic description of operational area",
-		},
-
-		// 400 Series - Technical Parameters
-		"field400": {
-			FieldNumber: "field400", Label: "Frequency (MHz)", Required: true, FieldType: "number",
-			Help: "Operating frequency in megahertz",
-		},
-		"field401": {
-			FieldNumber: "field401", Label: "Alternate Frequency (MHz)", Required: false, FieldType: "number",
-			Help: "Backup or alternate operating frequency",
-		},
-		"field402": {
-			FieldNumber: "field402", Label: "Power (Watts)", Required: false, FieldType: "number",
-			Help: "Transmitter power output in watts",
-		},
-		"field403": {
-			FieldNumber: "field403", Label: "ERP (Watts)", Required: false, FieldType: "number",
-			Help: "Effective radiated power in watts",
-		},
-		"field404": {
-			FieldNumber: "field404", Label: "Emission Designator", Required: false, FieldType: "text",
-			Help: "ITU emission designation (e.g., 16K0F3E)",
-		},
-		"field405": {
-			FieldNumber: "field405", Label: "Bandwidth (kHz)", Required: false, FieldType: "number",
-			Help: "Occupied bandwidth in kilohertz",
-		},
-		"field406": {
-			FieldNumber: "field406", Label: "Modulation", Required: false, FieldType: "select",
-			Options: []string{"AM", "FM", "PM", "DSB", "SSB", "CW", "FSK", "PSK", "QAM", "OFDM", "Digital"},
-			Help:    "Type of modulation employed",
-		},
-		"field407": {
-			FieldNumber: "field407", Label: "Tolerance (Hz)", Required: false, FieldType: "number",
-			Help: "Frequency tolerance in hertz",
-		},
-		"field408": {
-			FieldNumber: "field408", Label: "Stability", Required: false, FieldType: "text",
-			Help: "Frequency stability specification",
-		},
-		"field409": {
-			FieldNumber: "field409", Label: "Spurious Emissions", Required: false, FieldType: "text",
-			Help: "Spurious emission compliance standard",
-		},
-
-		// 500 Series - Equipment Information
-		"field500": {
-			FieldNumber: "field500", Label: "Transmitter Make", Required: false, FieldType: "text",
-			Help: "Manufacturer of transmitter equipment",
-		},
-		"field501": {
-			FieldNumber: "field501", Label: "Transmitter Model", Required: false, FieldType: "text",
-			Help: "Model number of transmitter",
-		},
-		"field502": {
-			FieldNumber: "field502", Label: "Transmitter S/N", Required: false, FieldType: "text",
-			Help: "Serial number of transmitter",
-		},
-		"field503": {
-			FieldNumber: "field503", Label: "Receiver Make", Required: false, FieldType: "text",
-			Help: "Manufacturer of receiver equipment",
-		},
-		"field504": {
-			FieldNumber: "field504", Label: "Receiver Model", Required: false, FieldType: "text",
-			Help: "Model number of receiver",
-		},
-		"field505": {
-			FieldNumber: "field505", Label: "Receiver S/N", Required: false, FieldType: "text",
-			Help: "Serial number of receiver",
-		},
-		"field506": {
-			FieldNumber: "field506", Label: "Antenna Make/Model", Required: false, FieldType: "text",
-			Help: "Antenna manufacturer and model number",
-		},
-		"field507": {
-			FieldNumber: "field507", Label: "Antenna Type", Required: false, FieldType: "select",
-			Options: []string{"Omnidirectional", "Directional", "Yagi", "Parabolic", "Helical", "Loop", "Whip", "Other"},
-			Help:    "Type of antenna used",
-		},
-		"field508": {
-			FieldNumber: "field508", Label: "Antenna Gain (dBi)", Required: false, FieldType: "number",
-			Help: "Antenna gain in decibels relative to isotropic",
-		},
-		"field509": {
-			FieldNumber: "field509", Label: "Antenna Pattern", Required: false, FieldType: "text",
-			Help: "Antenna radiation pattern description",
-		},
-		"field510": {
-			FieldNumber: "field510", Label: "Antenna Polarization", Required: false, FieldType: "select",
-			Options: []string{"Horizontal", "Vertical", "Circular", "Elliptical"},
-			Help:    "Antenna polarization type",
-		},
-		"field511": {
-			FieldNumber: "field511", Label: "Feeder Loss (dB)", Required: false, FieldType: "number",
-			Help: "Transmission line loss in decibels",
-		},
-
-		// 600 Series - Operational Information
-		"field600": {
-			FieldNumber: "field600", Label: "Hours of Operation", Required: false, FieldType: "text",
-			Help: "Operating schedule (e.g., 24/7, 0800-1700 EST)",
-		},
-		"field601": {
-			FieldNumber: "field601", Label: "Days of Operation", Required: false, FieldType: "text",
-			Help: "Days when system operates (e.g., Mon-Fri, Daily)",
-		},
-		"field602": {
-			FieldNumber: "field602", Label: "Months of Operation", Required: false, FieldType: "text",
-			Help: "Seasonal operation months",
-		},
-		"field603": {
-			FieldNumber: "field603", Label: "Number of Transmitters", Required: false, FieldType: "number",
-			Help: "Total number of transmitters in system",
-		},
-		"field604": {
-			FieldNumber: "field604", Label: "Number of Receivers", Required: false, FieldType: "number",
-			Help: "Total number of receivers in system",
-		},
-		"field605": {
-			FieldNumber: "field605", Label: "Traffic Volume", Required: false, FieldType: "text",
-			Help: "Expected traffic volume or duty cycle",
-		},
-		"field606": {
-			FieldNumber: "field606", Label: "Critical Infrastructure", Required: false, FieldType: "select",
-			Options: []string{"Yes", "No"},
-			Help:    "Is this system critical infrastructure?",
-		},
-		"field607": {
-			FieldNumber: "field607", Label: "Emergency Communications", Required: false, FieldType: "select",
-			Options: []string{"Yes", "No"},
-			Help:    "Used for emergency communications?",
-		},
-
-		// 700 Series - Coordination Information
-		"field700": {
-			FieldNumber: "field700", Label: "Coordination Required", Required: false, FieldType: "select",
-			Options: []string{"Yes", "No", "Unknown"},
-			Help:    "Is frequency coordination required?",
-		},
-		"field701": {
-			FieldNumber: "field701", Label: "Coordination Agency", Required: false, FieldType: "text",
-			Help: "Agency responsible for coordination",
-		},
-		"field702": {
-			FieldNumber: "field702", Label: "International Coordination", Required: false, FieldType: "select",
-			Options: []string{"Yes", "No"},
-			Help:    "International coordination required?",
-		},
-		"field703": {
-			FieldNumber: "field703", Label: "Border Distance (km)", Required: false, FieldType: "number",
-			Help: "Distance to nearest international border",
-		},
-		"field704": {
-			FieldNumber: "field704", Label: "Satellite Coordination", Required: false, FieldType: "select",
-			Options: []string{"Yes", "No"},
-			Help:    "Satellite coordination required?",
-		},
-
-		// 800 Series - Administrative Information
-		"field800": {
-			FieldNumber: "field800", Label: "POC Name", Required: false, FieldType: "text",
-			Help: "Primary point of contact name",
-		},
-		"field801": {
-			FieldNumber: "field801", Label: "POC Title", Required: false, FieldType: "text",
-			Help: "Point of contact title/position",
-		},
-		"field802": {
-			FieldNumber: "field802", Label: "POC Phone", Required: false, FieldType: "text",
-			Help: "Point of contact phone number",
-		},
-		"field803": {
-			FieldNumber: "field803", Label: "POC Email", Required: false, FieldType: "email",
-			Help: "Point of contact email address",
-		},
-		"field804": {
-			FieldNumber: "field804", Label: "Organization", Required: false, FieldType: "text",
-			Help: "Requesting organization or unit",
-		},
-		"field805": {
-			FieldNumber: "field805", Label: "Address Line 1", Required: false, FieldType: "text",
-			Help: "Organization address",
-		},
-		"field806": {
-			FieldNumber: "field806", Label: "Address Line 2", Required: false, FieldType: "text",
-			Help: "Additional address information",
-		},
-		"field807": {
-			FieldNumber: "field807", Label: "City", Required: false, FieldType: "text",
-			Help: "City",
-		},
-		"field808": {
-			FieldNumber: "field808", Label: "State/Province", Required: false, FieldType: "text",
-			Help: "State or province",
-		},
-		"field809": {
-			FieldNumber: "field809", Label: "Postal Code", Required: false, FieldType: "text",
-			Help: "ZIP or postal code",
-		},
-
-		// 900 Series - Comments and Special Requirements
-		"field900": {
-			FieldNumber: "field900", Label: "IRAC Notes", Required: false, FieldType: "textarea",
-			Help: "Notes for IRAC review and coordination",
-		},
-		"field901": {
-			FieldNumber: "field901", Label: "Technical Comments", Required: false, FieldType: "textarea",
-			Help: "Technical notes and specifications",
-		},
-		"field902": {
-			FieldNumber: "field902", Label: "Operational Comments", Required: false, FieldType: "textarea",
-			Help: "Operational requirements and constraints",
-		},
-		"field903": {
-			FieldNumber: "field903", Label: "Regulatory Comments", Required: false, FieldType: "textarea",
-			Help: "Regulatory compliance notes",
-		},
-		"field904": {
-			FieldNumber: "field904", Label: "General Comments", Required: false, FieldType: "textarea",
-			Help: "Additional comments and information",
-		},
+	if err := ss.geometryService.RemoveAuthorizationArea(sfaf.MarkerID); err != nil {
+		log.Printf("❌ Authorization area not removed for marker %s: %v", sfaf.MarkerID, err)
 	}
+	return nil
 }
@@ -0,0 +1,116 @@
+// sfaf_writer.go
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+const (
+	// sfafValueColumn is where values start on a field line ("005.  UE")
+	sfafValueColumn = 6
+	// sfafLineWidth is the longest line written before wrapping a value
+	sfafLineWidth = 80
+)
+
+// WriteSFAFRecords writes records in MCEB Pub 7 text format, one blank line
+// between records. Entries are written in the order given, so records
+// imported through ParseSFAFRecords keep their original field order.
+func WriteSFAFRecords(w io.Writer, records [][]models.SFAFEntry) error {
+	bw := bufio.NewWriter(w)
+	for i, entries := range records {
+		if i > 0 {
+			if _, err := bw.WriteString("\n"); err != nil {
+				return err
+			}
+		}
+		if err := writeSFAFRecord(bw, entries); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// FormatSFAFRecord renders a single record in MCEB Pub 7 text format.
+func FormatSFAFRecord(entries []models.SFAFEntry) string {
+	var sb strings.Builder
+	WriteSFAFRecords(&sb, [][]models.SFAFEntry{entries})
+	return sb.String()
+}
+
+func writeSFAFRecord(w *bufio.Writer, entries []models.SFAFEntry) error {
+	for _, entry := range entries {
+		value := strings.TrimSpace(entry.Value)
+		if value == "" {
+			continue
+		}
+
+		tag := entry.FieldNumber + "."
+		if entry.Occurrence > 1 {
+			tag = fmt.Sprintf("%s/%02d.", entry.FieldNumber, entry.Occurrence)
+		}
+		prefix := tag + strings.Repeat(" ", max(sfafValueColumn-len(tag), 1))
+		indent := strings.Repeat(" ", len(prefix))
+
+		for i, line := range wrapSFAFValue(value, sfafLineWidth-len(prefix)) {
+			lead := indent
+			if i == 0 {
+				lead = prefix
+			}
+			if _, err := w.WriteString(lead + line + "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrapSFAFValue splits a value into lines of at most width characters so that
+// ParseSFAFRecords joins them back to the same value: breaks are only made at
+// single spaces, and never where the next line would read as a field line or
+// record separator. Words longer than width are left on their own line.
+func wrapSFAFValue(value string, width int) []string {
+	var lines []string
+	for len(value) > width {
+		split := -1
+		for i := min(width, len(value)-1); i > 0; i-- {
+			if value[i] != ' ' || value[i-1] == ' ' || value[i+1] == ' ' {
+				continue
+			}
+			if isSFAFStructuralLine(value[i+1:]) {
+				continue
+			}
+			split = i
+			break
+		}
+		if split < 0 {
+			// No safe break inside the width; take the first one after it
+			for i := width + 1; i < len(value)-1; i++ {
+				if value[i] == ' ' && value[i-1] != ' ' && value[i+1] != ' ' && !isSFAFStructuralLine(value[i+1:]) {
+					split = i
+					break
+				}
+			}
+		}
+		if split < 0 {
+			break
+		}
+		lines = append(lines, value[:split])
+		value = value[split+1:]
+	}
+	return append(lines, value)
+}
+
+// isSFAFStructuralLine reports whether text at the start of a line would be
+// read as something other than a continuation by ParseSFAFRecords.
+func isSFAFStructuralLine(text string) bool {
+	if sfafFieldLinePattern.MatchString(text) {
+		return true
+	}
+	word, _, _ := strings.Cut(text, " ")
+	return word == "$" || word == "---" || word == "END"
+}
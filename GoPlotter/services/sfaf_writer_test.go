@@ -0,0 +1,125 @@
+// sfaf_writer_test.go
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+// TestSFAFRoundTrip checks that text already in export layout is written
+// back byte for byte, and that any other layout is normalized to text that
+// parses to the same entries and then round trips itself.
+func TestSFAFRoundTrip(t *testing.T) {
+	long := strings.Repeat("TRANSMITTER USED FOR RANGE SAFETY COMMUNICATIONS ", 4) + "END OF REMARKS"
+
+	tests := []struct {
+		name       string
+		input      string
+		normalized string
+	}{
+		{
+			name:       "canonical record",
+			input:      "005.  UE\n102.  AF  014589\n110.  M225.5\n500.  S189\n500/02. C010\n",
+			normalized: "005.  UE\n102.  AF  014589\n110.  M225.5\n500.  S189\n500/02. C010\n",
+		},
+		{
+			name:       "canonical records",
+			input:      "005.  UE\n110.  M225.5\n\n005.  UA\n110.  K4551.5(4550)\n",
+			normalized: "005.  UE\n110.  M225.5\n\n005.  UA\n110.  K4551.5(4550)\n",
+		},
+		{
+			name:  "canonical wrapped value",
+			input: FormatSFAFRecord([]models.SFAFEntry{{FieldNumber: "520", Occurrence: 1, Value: long}}),
+			normalized: FormatSFAFRecord([]models.SFAFEntry{
+				{FieldNumber: "520", Occurrence: 1, Value: long},
+			}),
+		},
+		{
+			name:       "value spacing and separators",
+			input:      "HEADER\r\n005. UE\r\n110.     M225.5  \r\n---\r\n005.  UA\r\nEND\r\n",
+			normalized: "005.  UE\n110.  M225.5\n\n005.  UA\n",
+		},
+		{
+			name:       "repeated unsuffixed field lines",
+			input:      "500.  S189\n500.  C010\n500.  S362\n",
+			normalized: "500.  S189\n500/02. C010\n500/03. S362\n",
+		},
+		{
+			name:       "continuation lines",
+			input:      "520.  FIRST LINE\n   SECOND LINE\n005.  UE\n",
+			normalized: "520.  FIRST LINE SECOND LINE\n005.  UE\n",
+		},
+		{
+			name:       "deleted fields are dropped",
+			input:      "005.  UE\n144.  $\n",
+			normalized: "005.  UE\n",
+		},
+		{
+			name:       "no break before a field tag inside a value",
+			input:      "520.  " + strings.Repeat("X", 70) + " 005. UE\n",
+			normalized: "520.  " + strings.Repeat("X", 70) + " 005.\n      UE\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := parseEntries(t, tt.input)
+			written := writeEntries(t, entries)
+			if written != tt.normalized {
+				t.Fatalf("written text\n%q\nwant\n%q", written, tt.normalized)
+			}
+
+			if again := parseEntries(t, written); !reflect.DeepEqual(again, entries) {
+				t.Errorf("written text parses to %+v, want %+v", again, entries)
+			}
+			if again := writeEntries(t, parseEntries(t, written)); again != written {
+				t.Errorf("normalized text does not round trip:\n%q\n%q", written, again)
+			}
+		})
+	}
+}
+
+func TestFormatSFAFRecordWrapsLongValues(t *testing.T) {
+	value := strings.Repeat("WORD ", 60) + "LAST"
+	text := FormatSFAFRecord([]models.SFAFEntry{{FieldNumber: "520", Occurrence: 3, Value: value}})
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "520/03. ") {
+		t.Fatalf("unexpected layout:\n%s", text)
+	}
+	for _, line := range lines {
+		if len(line) > sfafLineWidth {
+			t.Errorf("line longer than %d characters: %q", sfafLineWidth, line)
+		}
+	}
+	for _, line := range lines[1:] {
+		if !strings.HasPrefix(line, strings.Repeat(" ", len("520/03. "))) {
+			t.Errorf("continuation line not indented to the value column: %q", line)
+		}
+	}
+}
+
+func parseEntries(t *testing.T, text string) [][]models.SFAFEntry {
+	t.Helper()
+	records, err := ParseSFAFRecords(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseSFAFRecords: %v", err)
+	}
+	var entries [][]models.SFAFEntry
+	for _, record := range records {
+		entries = append(entries, record.Entries)
+	}
+	return entries
+}
+
+func writeEntries(t *testing.T, entries [][]models.SFAFEntry) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := WriteSFAFRecords(&sb, entries); err != nil {
+		t.Fatalf("WriteSFAFRecords: %v", err)
+	}
+	return sb.String()
+}
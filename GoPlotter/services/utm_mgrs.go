@@ -0,0 +1,377 @@
+// utm_mgrs.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sfaf-plotter/models"
+)
+
+// WGS 84 ellipsoid and the UTM projection constants
+const (
+	wgs84A         = 6378137.0
+	wgs84F         = 1 / 298.257223563
+	utmScale       = 0.9996
+	utmFalseEast   = 500000.0
+	utmFalseNorth  = 10000000.0 // southern hemisphere
+	utmMinLatitude = -80.0
+	utmMaxLatitude = 84.0
+)
+
+// MGRS precision is the number of digits in each of the easting and
+// northing: 1 is a 10 km square, 5 a 1 m square
+const (
+	MGRSMinPrecision     = 1
+	MGRSMaxPrecision     = 5
+	MGRSDefaultPrecision = MGRSMaxPrecision
+)
+
+// utmBands are the 8° latitude bands from 80°S; X covers 72°N-84°N
+const utmBands = "CDEFGHJKLMNPQRSTUVWXX"
+
+// mgrsColumns and mgrsRows are the 100 km square letters. Column letters
+// repeat every three zones, row letters are offset by five in even zones.
+var (
+	mgrsColumns = [3]string{"ABCDEFGH", "JKLMNPQR", "STUVWXYZ"}
+	mgrsRows    = [2]string{"ABCDEFGHJKLMNPQRSTUV", "FGHJKLMNPQRSTUVABCDE"}
+)
+
+// Krüger series for the transverse Mercator projection to sixth order in
+// the third flattening (Karney 2011), good to a few nanometres inside a zone
+var utmE, utmA, utmAlpha, utmBeta = krugerSeries()
+
+func krugerSeries() (e, a float64, alpha, beta [6]float64) {
+	n := wgs84F / (2 - wgs84F)
+	n2, n3 := n*n, n*n*n
+	n4, n5, n6 := n3*n, n3*n2, n3*n3
+
+	e = math.Sqrt(wgs84F * (2 - wgs84F))
+	a = wgs84A / (1 + n) * (1 + n2/4 + n4/64 + n6/256)
+	alpha = [6]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+		13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+		61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+		49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+		34729*n5/80640 - 3418889*n6/1995840,
+		212378941 * n6 / 319334400,
+	}
+	beta = [6]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+		n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+		17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+		4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+		4583*n5/161280 - 108847*n6/3991680,
+		20648693 * n6 / 638668800,
+	}
+	return e, a, alpha, beta
+}
+
+// ErrOutsideUTM is returned for latitudes UTM and MGRS don't cover; the
+// polar regions use UPS, which we don't support
+var ErrOutsideUTM = fmt.Errorf("%w: UTM and MGRS cover %.0f°S to %.0f°N only", ErrInvalidCoordinates, -utmMinLatitude, utmMaxLatitude)
+
+// ToUTM projects a position into its UTM zone, including the Norway and
+// Svalbard zone exceptions
+func (cs *CoordinateService) ToUTM(lat, lng float64) (models.UTMCoordinate, error) {
+	if err := cs.CheckCoordinate(lat, lng); err != nil {
+		return models.UTMCoordinate{}, err
+	}
+	if lat < utmMinLatitude || lat > utmMaxLatitude {
+		return models.UTMCoordinate{}, ErrOutsideUTM
+	}
+
+	zone := utmZone(lat, lng)
+	band := utmBands[int(math.Floor((lat-utmMinLatitude)/8))]
+	easting, northing := transverseMercator(lat, lng-utmCentralMeridian(zone))
+
+	hemisphere := "N"
+	if lat < 0 {
+		hemisphere = "S"
+		northing += utmFalseNorth
+	}
+	return models.UTMCoordinate{
+		Zone:       zone,
+		Band:       string(band),
+		Hemisphere: hemisphere,
+		Easting:    easting + utmFalseEast,
+		Northing:   northing,
+	}, nil
+}
+
+// FromUTM converts a UTM position back to latitude and longitude
+func (cs *CoordinateService) FromUTM(utm models.UTMCoordinate) (models.Coordinate, error) {
+	if utm.Zone < 1 || utm.Zone > 60 {
+		return models.Coordinate{}, fmt.Errorf("%w: UTM zone %d is not between 1 and 60", ErrInvalidCoordinates, utm.Zone)
+	}
+	if utm.Easting < 100000 || utm.Easting > 900000 {
+		return models.Coordinate{}, fmt.Errorf("%w: UTM easting %.0f is outside 100000-900000", ErrInvalidCoordinates, utm.Easting)
+	}
+	if utm.Northing < 0 || utm.Northing > utmFalseNorth {
+		return models.Coordinate{}, fmt.Errorf("%w: UTM northing %.0f is outside 0-10000000", ErrInvalidCoordinates, utm.Northing)
+	}
+
+	northing := utm.Northing
+	if utm.Hemisphere == "S" {
+		northing -= utmFalseNorth
+	}
+	lat, dLng := inverseTransverseMercator(utm.Easting-utmFalseEast, northing)
+	lng := math.Mod(dLng+utmCentralMeridian(utm.Zone)+540, 360) - 180
+	return models.Coordinate{Lat: lat, Lng: lng}, nil
+}
+
+// FormatUTM writes a position as zone, band, easting and northing to the
+// metre, e.g. 16R 526715 3365620
+func (cs *CoordinateService) FormatUTM(lat, lng float64) (string, error) {
+	utm, err := cs.ToUTM(lat, lng)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d%s %.0f %.0f", utm.Zone, utm.Band, math.Floor(utm.Easting), math.Floor(utm.Northing)), nil
+}
+
+// FormatMGRS writes a position as an MGRS grid reference, e.g.
+// 16RDU 26715 65620. Digits are truncated, as MGRS names the square the
+// position lies in.
+func (cs *CoordinateService) FormatMGRS(lat, lng float64, precision int) (string, error) {
+	utm, err := cs.ToUTM(lat, lng)
+	if err != nil {
+		return "", err
+	}
+	precision = clampMGRSPrecision(precision)
+
+	column := int(math.Floor(utm.Easting / 100000))
+	row := int(math.Floor(utm.Northing/100000)) % 20
+	square := string(mgrsColumns[(utm.Zone-1)%3][column-1]) + string(mgrsRows[(utm.Zone-1)%2][row])
+
+	divisor := math.Pow10(MGRSMaxPrecision - precision)
+	easting := int(math.Floor(math.Mod(utm.Easting, 100000) / divisor))
+	northing := int(math.Floor(math.Mod(utm.Northing, 100000) / divisor))
+	return fmt.Sprintf("%d%s%s %0*d %0*d", utm.Zone, utm.Band, square, precision, easting, precision, northing), nil
+}
+
+// utmPosition matches 16R 526715 3365620, with optional m/E/N suffixes
+var utmPosition = regexp.MustCompile(`^(\d{1,2}) ?([C-HJ-NP-X]) (\d+(?:\.\d+)?) ?M? ?E? (\d+(?:\.\d+)?) ?M? ?N?$`)
+
+// mgrsPosition matches 16RDU2671565620 and 16R DU 26715 65620
+var mgrsPosition = regexp.MustCompile(`^(\d{1,2}) ?([C-HJ-NP-X]) ?([A-HJ-NP-Z])([A-HJ-NP-V]) ?(\d*) ?(\d*)$`)
+
+// ParseUTM reads a UTM position such as 16R 526715 3365620. The latitude
+// band gives the hemisphere.
+func (cs *CoordinateService) ParseUTM(input string) (models.Coordinate, error) {
+	text := strings.Join(strings.Fields(strings.ToUpper(input)), " ")
+	match := utmPosition.FindStringSubmatch(text)
+	if match == nil {
+		return models.Coordinate{}, fmt.Errorf("%w: %q is not a UTM position like 16R 526715 3365620", ErrInvalidCoordinates, input)
+	}
+
+	zone, _ := strconv.Atoi(match[1])
+	easting, _ := strconv.ParseFloat(match[3], 64)
+	northing, _ := strconv.ParseFloat(match[4], 64)
+	return cs.FromUTM(models.UTMCoordinate{
+		Zone:       zone,
+		Band:       match[2],
+		Hemisphere: bandHemisphere(match[2][0]),
+		Easting:    easting,
+		Northing:   northing,
+	})
+}
+
+// ParseMGRS reads an MGRS grid reference with 0 to 5 digits each of
+// easting and northing, spaced or not. It returns the centre of the square
+// the reference names.
+func (cs *CoordinateService) ParseMGRS(input string) (models.Coordinate, error) {
+	text := strings.Join(strings.Fields(strings.ToUpper(input)), " ")
+	match := mgrsPosition.FindStringSubmatch(text)
+	if match == nil {
+		return models.Coordinate{}, fmt.Errorf("%w: %q is not an MGRS grid reference like 16RDU2671565620", ErrInvalidCoordinates, input)
+	}
+
+	zone, _ := strconv.Atoi(match[1])
+	if zone < 1 || zone > 60 {
+		return models.Coordinate{}, fmt.Errorf("%w: MGRS zone %d is not between 1 and 60", ErrInvalidCoordinates, zone)
+	}
+	band := match[2][0]
+
+	digits := match[5] + match[6]
+	if match[6] != "" && len(match[5]) != len(match[6]) {
+		return models.Coordinate{}, fmt.Errorf("%w: MGRS easting %s and northing %s need the same number of digits", ErrInvalidCoordinates, match[5], match[6])
+	}
+	if len(digits)%2 != 0 || len(digits) > 2*MGRSMaxPrecision {
+		return models.Coordinate{}, fmt.Errorf("%w: MGRS %q needs an even number of digits, at most %d", ErrInvalidCoordinates, input, 2*MGRSMaxPrecision)
+	}
+	precision := len(digits) / 2
+
+	column := strings.IndexByte(mgrsColumns[(zone-1)%3], match[3][0])
+	if column < 0 {
+		return models.Coordinate{}, fmt.Errorf("%w: MGRS column letter %s is not used in zone %d", ErrInvalidCoordinates, match[3], zone)
+	}
+	row := strings.IndexByte(mgrsRows[(zone-1)%2], match[4][0])
+
+	// Centre of the named square
+	size := math.Pow10(MGRSMaxPrecision - precision)
+	easting, northing := size/2, size/2
+	if precision > 0 {
+		e, _ := strconv.Atoi(digits[:precision])
+		n, _ := strconv.Atoi(digits[precision:])
+		easting += float64(e) * size
+		northing += float64(n) * size
+	}
+	easting += float64(column+1) * 100000
+	northing += float64(row) * 100000
+
+	// Row letters repeat every 2000 km; the band says which cycle
+	hemisphere := bandHemisphere(band)
+	bandSouth := utmMinLatitude + 8*float64(strings.IndexByte(utmBands, band))
+	_, bandStart := transverseMercator(bandSouth, 0)
+	if hemisphere == "S" {
+		bandStart += utmFalseNorth
+	}
+	bandStart = math.Floor(bandStart/100000) * 100000
+	for northing < bandStart {
+		northing += 2000000
+	}
+
+	return cs.FromUTM(models.UTMCoordinate{
+		Zone:       zone,
+		Band:       string(band),
+		Hemisphere: hemisphere,
+		Easting:    easting,
+		Northing:   northing,
+	})
+}
+
+// ParsePosition reads a position given as MGRS, UTM or any latitude and
+// longitude form ParseCoordinates accepts
+func (cs *CoordinateService) ParsePosition(input string) (models.Coordinate, error) {
+	text := strings.Join(strings.Fields(strings.ToUpper(input)), " ")
+	switch {
+	case mgrsPosition.MatchString(text):
+		return cs.ParseMGRS(text)
+	case utmPosition.MatchString(text):
+		return cs.ParseUTM(text)
+	}
+	return cs.ParseCoordinates(input)
+}
+
+// ParseMGRSPrecision reads a requested MGRS precision in digits. Blank
+// means the default, 1 m.
+func (cs *CoordinateService) ParseMGRSPrecision(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return MGRSDefaultPrecision, nil
+	}
+	precision, err := strconv.Atoi(text)
+	if err != nil || precision < MGRSMinPrecision || precision > MGRSMaxPrecision {
+		return 0, fmt.Errorf("%w: MGRS precision must be %d (10 km) to %d (1 m) digits",
+			ErrInvalidCoordinates, MGRSMinPrecision, MGRSMaxPrecision)
+	}
+	return precision, nil
+}
+
+// clampMGRSPrecision treats 0 as the default, so a zero-value
+// CoordinateFormatOptions gives 1 m references
+func clampMGRSPrecision(precision int) int {
+	if precision == 0 {
+		return MGRSDefaultPrecision
+	}
+	return int(math.Max(MGRSMinPrecision, math.Min(MGRSMaxPrecision, float64(precision))))
+}
+
+func bandHemisphere(band byte) string {
+	if band < 'N' {
+		return "S"
+	}
+	return "N"
+}
+
+func utmZone(lat, lng float64) int {
+	zone := int(math.Floor((lng+180)/6)) + 1
+	if zone > 60 {
+		zone = 60 // 180°E
+	}
+
+	// Norway's southwest coast is in a widened zone 32
+	if lat >= 56 && lat < 64 && lng >= 3 && lng < 12 {
+		return 32
+	}
+	// Svalbard uses zones 31, 33, 35 and 37 only
+	if lat >= 72 && lng >= 0 && lng < 42 {
+		switch {
+		case lng < 9:
+			return 31
+		case lng < 21:
+			return 33
+		case lng < 33:
+			return 35
+		default:
+			return 37
+		}
+	}
+	return zone
+}
+
+func utmCentralMeridian(zone int) float64 {
+	return float64(zone-1)*6 - 180 + 3
+}
+
+// transverseMercator projects onto a transverse Mercator grid (scaled,
+// without false easting or northing); dLng is the offset from the central
+// meridian in degrees
+func transverseMercator(lat, dLng float64) (x, y float64) {
+	phi := lat * math.Pi / 180
+	lambda := dLng * math.Pi / 180
+
+	tau := math.Tan(phi)
+	sigma := math.Sinh(utmE * math.Atanh(utmE*tau/math.Sqrt(1+tau*tau)))
+	tauPrime := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+
+	xiPrime := math.Atan2(tauPrime, math.Cos(lambda))
+	etaPrime := math.Asinh(math.Sin(lambda) / math.Sqrt(tauPrime*tauPrime+math.Cos(lambda)*math.Cos(lambda)))
+
+	xi, eta := xiPrime, etaPrime
+	for j, alpha := range utmAlpha {
+		k := 2 * float64(j+1)
+		xi += alpha * math.Sin(k*xiPrime) * math.Cosh(k*etaPrime)
+		eta += alpha * math.Cos(k*xiPrime) * math.Sinh(k*etaPrime)
+	}
+	return utmScale * utmA * eta, utmScale * utmA * xi
+}
+
+// inverseTransverseMercator undoes transverseMercator, returning latitude
+// and the longitude offset from the central meridian in degrees
+func inverseTransverseMercator(x, y float64) (lat, dLng float64) {
+	eta := x / (utmScale * utmA)
+	xi := y / (utmScale * utmA)
+
+	xiPrime, etaPrime := xi, eta
+	for j, beta := range utmBeta {
+		k := 2 * float64(j+1)
+		xiPrime -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	sinhEta := math.Sinh(etaPrime)
+	tauPrime := math.Sin(xiPrime) / math.Sqrt(sinhEta*sinhEta+math.Cos(xiPrime)*math.Cos(xiPrime))
+
+	// Newton's method for tau from tau'; converges in a few steps
+	e2 := utmE * utmE
+	tau := tauPrime
+	for i := 0; i < 10; i++ {
+		sigma := math.Sinh(utmE * math.Atanh(utmE*tau/math.Sqrt(1+tau*tau)))
+		tauI := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		delta := (tauPrime - tauI) / math.Sqrt(1+tauI*tauI) *
+			(1 + (1-e2)*tau*tau) / ((1 - e2) * math.Sqrt(1+tau*tau))
+		tau += delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+
+	lat = math.Atan(tau) * 180 / math.Pi
+	dLng = math.Atan2(sinhEta, math.Cos(xiPrime)) * 180 / math.Pi
+	return lat, dLng
+}
@@ -0,0 +1,177 @@
+// utm_mgrs_test.go
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+// The Baghdad position is the GeoConvert (GeographicLib) example
+func TestToUTM(t *testing.T) {
+	tests := []struct {
+		name              string
+		lat, lng          float64
+		zone              int
+		band, hemisphere  string
+		easting, northing float64
+	}{
+		{"equator on a central meridian", 0, 3, 31, "N", "N", 500000, 0},
+		{"Baghdad", 33.3, 44.4, 38, "S", "N", 444140.54, 3684706.36},
+		{"Sydney", -33.8688, 151.2093, 56, "H", "S", 334368.63, 6250948.35},
+		{"Norway exception", 60, 5, 32, "V", "N", 276979.93, 6658157.20},
+		{"Svalbard exception", 78, 20, 33, "X", "N", 615914.52, 8663320.20},
+		{"antimeridian", 0, 180, 60, "N", "N", 833978.56, 0},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utm, err := cs.ToUTM(tt.lat, tt.lng)
+			if err != nil {
+				t.Fatalf("ToUTM(%v, %v): %v", tt.lat, tt.lng, err)
+			}
+			if utm.Zone != tt.zone || utm.Band != tt.band || utm.Hemisphere != tt.hemisphere ||
+				math.Abs(utm.Easting-tt.easting) > 0.01 || math.Abs(utm.Northing-tt.northing) > 0.01 {
+				t.Errorf("ToUTM(%v, %v) = %+v, want %d%s %s %.2f %.2f",
+					tt.lat, tt.lng, utm, tt.zone, tt.band, tt.hemisphere, tt.easting, tt.northing)
+			}
+		})
+	}
+}
+
+func TestToUTMOutsideCoverage(t *testing.T) {
+	cs := NewCoordinateService()
+	for _, lat := range []float64{84.1, -80.1, 90, -90} {
+		if _, err := cs.ToUTM(lat, 0); !errors.Is(err, ErrOutsideUTM) {
+			t.Errorf("ToUTM(%v, 0) error = %v, want ErrOutsideUTM", lat, err)
+		}
+	}
+	if _, err := cs.ToUTM(0, 181); !errors.Is(err, ErrInvalidCoordinates) {
+		t.Errorf("ToUTM(0, 181) error = %v, want ErrInvalidCoordinates", err)
+	}
+}
+
+func TestFormatMGRS(t *testing.T) {
+	tests := []struct {
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{33.3, 44.4, 5, "38SMB 44140 84706"},
+		{33.3, 44.4, 3, "38SMB 441 847"},
+		{33.3, 44.4, 1, "38SMB 4 8"},
+		{33.3, 44.4, 0, "38SMB 44140 84706"},
+		{-33.8688, 151.2093, 4, "56HLH 3436 5094"},
+		{0, 3, 5, "31NEA 00000 00000"},
+		{78, 20, 2, "33XXG 15 63"},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := cs.FormatMGRS(tt.lat, tt.lng, tt.precision)
+			if err != nil {
+				t.Fatalf("FormatMGRS(%v, %v, %d): %v", tt.lat, tt.lng, tt.precision, err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatMGRS(%v, %v, %d) = %q, want %q", tt.lat, tt.lng, tt.precision, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		lat, lng float64
+		meters   float64 // how far the result may be from lat, lng
+	}{
+		{"38SMB4414084706", 33.3, 44.4, 1},
+		{"38S MB 44140 84706", 33.3, 44.4, 1},
+		{"38smb 441 847", 33.3, 44.4, 100},
+		{"56HLH3436850948", -33.8688, 151.2093, 1},
+		{"38S 444140.54 3684706.36", 33.3, 44.4, 0.01},
+		{"38S 444140.54mE 3684706.36mN", 33.3, 44.4, 0.01},
+		{"56H 334368.63 6250948.35", -33.8688, 151.2093, 0.01},
+		{"33.3, 44.4", 33.3, 44.4, 0},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := cs.ParsePosition(tt.input)
+			if err != nil {
+				t.Fatalf("ParsePosition(%q): %v", tt.input, err)
+			}
+			if d := geodesicDistance(got, models.Coordinate{Lat: tt.lat, Lng: tt.lng}); d > tt.meters {
+				t.Errorf("ParsePosition(%q) = %+v, %.3f m from %v, %v", tt.input, got, d, tt.lat, tt.lng)
+			}
+		})
+	}
+}
+
+func TestParsePositionErrors(t *testing.T) {
+	tests := []struct {
+		name, input string
+	}{
+		{"MGRS zone 0", "0SMB4414084706"},
+		{"MGRS zone 61", "61SMB4414084706"},
+		{"MGRS odd digits", "38SMB441408470"},
+		{"MGRS uneven spaced digits", "38SMB 4414 84706"},
+		{"MGRS column letter unused in the zone", "38SAB4414084706"},
+		{"UTM easting out of range", "38S 44140 3684706"},
+		{"UTM northing out of range", "38S 444140 10000001"},
+		{"UTM zone 61", "61S 444140 3684706"},
+		{"neither", "somewhere"},
+	}
+
+	cs := NewCoordinateService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cs.ParsePosition(tt.input)
+			if !errors.Is(err, ErrInvalidCoordinates) {
+				t.Errorf("ParsePosition(%q) = %+v, %v, want ErrInvalidCoordinates", tt.input, got, err)
+			}
+		})
+	}
+}
+
+// TestUTMRoundTrip checks FromUTM(ToUTM(x)) and ParseMGRS(FormatMGRS(x))
+// over the whole UTM range
+func TestUTMRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cs := NewCoordinateService()
+
+	for i := 0; i < 5000; i++ {
+		position := models.Coordinate{Lat: rng.Float64()*164 - 80, Lng: rng.Float64()*360 - 180}
+
+		utm, err := cs.ToUTM(position.Lat, position.Lng)
+		if err != nil {
+			t.Fatalf("ToUTM(%+v): %v", position, err)
+		}
+		back, err := cs.FromUTM(utm)
+		if err != nil {
+			t.Fatalf("FromUTM(%+v): %v", utm, err)
+		}
+		if d := geodesicDistance(position, back); d > 1e-6 {
+			t.Fatalf("%+v -> %+v -> %+v: %g m apart", position, utm, back, d)
+		}
+
+		// A 1 m reference names a square whose centre is within 0.71 m
+		mgrs, err := cs.FormatMGRS(position.Lat, position.Lng, MGRSMaxPrecision)
+		if err != nil {
+			t.Fatalf("FormatMGRS(%+v): %v", position, err)
+		}
+		parsed, err := cs.ParseMGRS(mgrs)
+		if err != nil {
+			t.Fatalf("ParseMGRS(%q): %v", mgrs, err)
+		}
+		if d := geodesicDistance(position, parsed); d > 0.71 {
+			t.Fatalf("%+v -> %q -> %+v: %.3f m apart", position, mgrs, parsed, d)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// spatial/index.go
+package spatial
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"sfaf-plotter/models"
+)
+
+// Node fan-out of the R-tree. A node that drops below minEntries after a
+// removal is dissolved and its entries inserted again.
+const (
+	maxEntries = 16
+	minEntries = maxEntries * 2 / 5
+)
+
+// rect is a box that does not wrap the antimeridian
+type rect struct {
+	minLat, minLng, maxLat, maxLng float64
+}
+
+// item is a node entry: an indexed ID in a leaf, a child node otherwise
+type item struct {
+	box   rect
+	id    string
+	child *node
+}
+
+type node struct {
+	leaf  bool
+	items []item
+}
+
+// Index finds IDs by bounding box with an R-tree (Guttman, quadratic
+// split), so a search only visits the subtrees whose bounds overlap the
+// query. Points are boxes with no size; a box wrapping the antimeridian is
+// stored as its two halves. It is safe for concurrent use.
+type Index struct {
+	mutex sync.RWMutex
+	boxes map[string]models.BoundingBox
+	root  *node
+}
+
+func NewIndex() *Index {
+	return &Index{
+		boxes: make(map[string]models.BoundingBox),
+		root:  &node{leaf: true},
+	}
+}
+
+// PointBox is the box of a single position
+func PointBox(lat, lng float64) models.BoundingBox {
+	return models.BoundingBox{South: lat, West: lng, North: lat, East: lng}
+}
+
+// Insert adds id with box, replacing any box it had
+func (idx *Index) Insert(id string, box models.BoundingBox) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.remove(id)
+	idx.boxes[id] = box
+	for _, r := range rects(box) {
+		idx.insert(item{box: r, id: id})
+	}
+}
+
+// Remove drops id; unknown IDs are ignored
+func (idx *Index) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	box, exists := idx.boxes[id]
+	if !exists {
+		return
+	}
+	delete(idx.boxes, id)
+
+	for _, r := range rects(box) {
+		var orphans []item
+		if !idx.root.remove(r, id, &orphans) {
+			continue
+		}
+		for !idx.root.leaf && len(idx.root.items) == 1 {
+			idx.root = idx.root.items[0].child
+		}
+		if !idx.root.leaf && len(idx.root.items) == 0 {
+			idx.root = &node{leaf: true}
+		}
+		for _, orphan := range orphans {
+			idx.insert(orphan)
+		}
+	}
+}
+
+// Clear removes every entry
+func (idx *Index) Clear() {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.boxes = make(map[string]models.BoundingBox)
+	idx.root = &node{leaf: true}
+}
+
+// Len is the number of entries
+func (idx *Index) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.boxes)
+}
+
+// Box returns the box stored for id
+func (idx *Index) Box(id string) (models.BoundingBox, bool) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	box, exists := idx.boxes[id]
+	return box, exists
+}
+
+// Search returns the IDs whose boxes overlap box, sorted
+func (idx *Index) Search(box models.BoundingBox) []string {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	found := make(map[string]struct{})
+	for _, r := range rects(box) {
+		idx.root.search(r, found)
+	}
+
+	ids := make([]string, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Overlaps reports whether two boxes share any point, either of them
+// wrapping the antimeridian
+func Overlaps(a, b models.BoundingBox) bool {
+	for _, ra := range rects(a) {
+		for _, rb := range rects(b) {
+			if ra.overlaps(rb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rects splits a box into rects that do not wrap
+func rects(box models.BoundingBox) []rect {
+	if box.West <= box.East {
+		return []rect{{box.South, box.West, box.North, box.East}}
+	}
+	return []rect{
+		{box.South, box.West, box.North, 180},
+		{box.South, -180, box.North, box.East},
+	}
+}
+
+// insert adds a leaf entry, growing a new root when the old one splits
+func (idx *Index) insert(entry item) {
+	sibling := idx.root.insert(entry)
+	if sibling == nil {
+		return
+	}
+	idx.root = &node{items: []item{
+		{box: idx.root.bounds(), child: idx.root},
+		{box: sibling.bounds(), child: sibling},
+	}}
+}
+
+// insert places a leaf entry below n, descending into the child whose box
+// grows least. It returns the new sibling when n had to split.
+func (n *node) insert(entry item) *node {
+	if n.leaf {
+		n.items = append(n.items, entry)
+	} else {
+		best := chooseSubtree(n.items, entry.box)
+		child := n.items[best].child
+		if sibling := child.insert(entry); sibling != nil {
+			n.items = append(n.items, item{box: sibling.bounds(), child: sibling})
+		}
+		n.items[best].box = child.bounds()
+	}
+
+	if len(n.items) <= maxEntries {
+		return nil
+	}
+	return n.split()
+}
+
+// remove deletes the leaf entry (r, id) below n. Nodes left with fewer
+// than minEntries are dropped and their leaf entries added to orphans for
+// the caller to insert again. It reports whether the entry was found.
+func (n *node) remove(r rect, id string, orphans *[]item) bool {
+	if n.leaf {
+		for i, entry := range n.items {
+			if entry.id == id && entry.box == r {
+				n.items = append(n.items[:i], n.items[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range n.items {
+		if !n.items[i].box.contains(r) {
+			continue
+		}
+		child := n.items[i].child
+		if !child.remove(r, id, orphans) {
+			continue
+		}
+		if len(child.items) < minEntries {
+			child.collect(orphans)
+			n.items = append(n.items[:i], n.items[i+1:]...)
+		} else {
+			n.items[i].box = child.bounds()
+		}
+		return true
+	}
+	return false
+}
+
+// collect appends every leaf entry below n
+func (n *node) collect(entries *[]item) {
+	if n.leaf {
+		*entries = append(*entries, n.items...)
+		return
+	}
+	for _, child := range n.items {
+		child.child.collect(entries)
+	}
+}
+
+func (n *node) search(r rect, found map[string]struct{}) {
+	for _, entry := range n.items {
+		if !entry.box.overlaps(r) {
+			continue
+		}
+		if n.leaf {
+			found[entry.id] = struct{}{}
+		} else {
+			entry.child.search(r, found)
+		}
+	}
+}
+
+func (n *node) bounds() rect {
+	box := n.items[0].box
+	for _, entry := range n.items[1:] {
+		box = box.union(entry.box)
+	}
+	return box
+}
+
+// split moves part of an overfull node's entries to a new sibling with
+// Guttman's quadratic split: the two entries that would waste the most
+// space together seed the groups, then the entry with the strongest
+// preference is assigned next, until one group needs the rest to reach
+// minEntries.
+func (n *node) split() *node {
+	entries := n.items
+	seedA, seedB := pickSeeds(entries)
+
+	groupA := []item{entries[seedA]}
+	groupB := []item{entries[seedB]}
+	boxA, boxB := entries[seedA].box, entries[seedB].box
+
+	remaining := make([]item, 0, len(entries)-2)
+	for i, entry := range entries {
+		if i != seedA && i != seedB {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	for len(remaining) > 0 {
+		if len(groupA)+len(remaining) == minEntries {
+			groupA = append(groupA, remaining...)
+			break
+		}
+		if len(groupB)+len(remaining) == minEntries {
+			groupB = append(groupB, remaining...)
+			break
+		}
+
+		next := strongestPreference(remaining, boxA, boxB)
+		entry := remaining[next]
+		remaining = append(remaining[:next], remaining[next+1:]...)
+
+		takeA := better(boxA, boxB, entry.box)
+		if !takeA && !better(boxB, boxA, entry.box) {
+			takeA = len(groupA) <= len(groupB)
+		}
+		if takeA {
+			groupA = append(groupA, entry)
+			boxA = boxA.union(entry.box)
+		} else {
+			groupB = append(groupB, entry)
+			boxB = boxB.union(entry.box)
+		}
+	}
+
+	n.items = groupA
+	return &node{leaf: n.leaf, items: groupB}
+}
+
+func pickSeeds(entries []item) (int, int) {
+	seedA, seedB := 0, 1
+	worstArea, worstMargin := -1.0, -1.0
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			union := entries[i].box.union(entries[j].box)
+			area := union.area() - entries[i].box.area() - entries[j].box.area()
+			margin := union.margin() - entries[i].box.margin() - entries[j].box.margin()
+			if area > worstArea || (area == worstArea && margin > worstMargin) {
+				seedA, seedB, worstArea, worstMargin = i, j, area, margin
+			}
+		}
+	}
+	return seedA, seedB
+}
+
+// strongestPreference picks the entry whose growth differs most between
+// the two split groups, by area and then by margin
+func strongestPreference(entries []item, a, b rect) int {
+	next := 0
+	bestArea, bestMargin := -1.0, -1.0
+	for i, entry := range entries {
+		areaA, marginA := a.growth(entry.box)
+		areaB, marginB := b.growth(entry.box)
+		area, margin := math.Abs(areaA-areaB), math.Abs(marginA-marginB)
+		if area > bestArea || (area == bestArea && margin > bestMargin) {
+			next, bestArea, bestMargin = i, area, margin
+		}
+	}
+	return next
+}
+
+// better reports whether box a should take r rather than box b: it grows
+// less (by area, then by margin so points spread out), then it is smaller
+func better(a, b, r rect) bool {
+	areaA, marginA := a.growth(r)
+	areaB, marginB := b.growth(r)
+	switch {
+	case areaA != areaB:
+		return areaA < areaB
+	case marginA != marginB:
+		return marginA < marginB
+	case a.area() != b.area():
+		return a.area() < b.area()
+	}
+	return a.margin() < b.margin()
+}
+
+// chooseSubtree picks the child that should take r
+func chooseSubtree(children []item, r rect) int {
+	best := 0
+	for i := 1; i < len(children); i++ {
+		if better(children[i].box, children[best].box, r) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (r rect) overlaps(o rect) bool {
+	return r.minLat <= o.maxLat && o.minLat <= r.maxLat && r.minLng <= o.maxLng && o.minLng <= r.maxLng
+}
+
+func (r rect) contains(o rect) bool {
+	return r.minLat <= o.minLat && o.maxLat <= r.maxLat && r.minLng <= o.minLng && o.maxLng <= r.maxLng
+}
+
+func (r rect) union(o rect) rect {
+	return rect{min(r.minLat, o.minLat), min(r.minLng, o.minLng), max(r.maxLat, o.maxLat), max(r.maxLng, o.maxLng)}
+}
+
+func (r rect) area() float64 {
+	return (r.maxLat - r.minLat) * (r.maxLng - r.minLng)
+}
+
+// margin is half the perimeter, which separates boxes of no area such as
+// points
+func (r rect) margin() float64 {
+	return (r.maxLat - r.minLat) + (r.maxLng - r.minLng)
+}
+
+// growth is how much r must grow to cover o, in area and in margin
+func (r rect) growth(o rect) (float64, float64) {
+	union := r.union(o)
+	return union.area() - r.area(), union.margin() - r.margin()
+}
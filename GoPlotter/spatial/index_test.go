@@ -0,0 +1,117 @@
+// spatial/index_test.go
+package spatial
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"sfaf-plotter/models"
+)
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b models.BoundingBox
+		want bool
+	}{
+		{"disjoint", box(0, 0, 1, 1), box(2, 2, 3, 3), false},
+		{"overlapping", box(0, 0, 2, 2), box(1, 1, 3, 3), true},
+		{"touching edges", box(0, 0, 1, 1), box(1, 1, 2, 2), true},
+		{"point inside", PointBox(0.5, 0.5), box(0, 0, 1, 1), true},
+		{"same longitudes, other latitudes", box(0, 0, 1, 1), box(5, 0, 6, 1), false},
+		{"wrapping box and point east of the antimeridian", box(-10, 170, 10, -170), PointBox(0, 179.5), true},
+		{"wrapping box and point west of the antimeridian", box(-10, 170, 10, -170), PointBox(0, -179.5), true},
+		{"wrapping box and point outside", box(-10, 170, 10, -170), PointBox(0, 0), false},
+		{"two wrapping boxes", box(-10, 175, 10, -175), box(-5, 178, 5, -178), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlaps(tt.a, tt.b); got != tt.want {
+				t.Errorf("Overlaps(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := Overlaps(tt.b, tt.a); got != tt.want {
+				t.Errorf("Overlaps(%+v, %+v) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIndexSearch checks Search against a linear scan while entries are
+// inserted, moved and removed
+func TestIndexSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	idx := NewIndex()
+	boxes := make(map[string]models.BoundingBox)
+
+	for step := 0; step < 5000; step++ {
+		id := fmt.Sprintf("id%d", rng.Intn(1500))
+		switch rng.Intn(4) {
+		case 0:
+			idx.Remove(id)
+			delete(boxes, id)
+		default:
+			b := randomBox(rng)
+			idx.Insert(id, b)
+			boxes[id] = b
+		}
+
+		if step%250 == 0 {
+			checkSearches(t, rng, idx, boxes)
+		}
+	}
+	checkSearches(t, rng, idx, boxes)
+
+	for id := range boxes {
+		idx.Remove(id)
+	}
+	if idx.Len() != 0 || len(idx.Search(box(-90, -180, 90, 180))) != 0 {
+		t.Errorf("index not empty after removing every entry")
+	}
+}
+
+func checkSearches(t *testing.T, rng *rand.Rand, idx *Index, boxes map[string]models.BoundingBox) {
+	t.Helper()
+	if idx.Len() != len(boxes) {
+		t.Fatalf("Len() = %d, want %d", idx.Len(), len(boxes))
+	}
+
+	for i := 0; i < 50; i++ {
+		query := randomBox(rng)
+		want := []string{}
+		for id, b := range boxes {
+			if Overlaps(b, query) {
+				want = append(want, id)
+			}
+		}
+		sort.Strings(want)
+
+		if got := idx.Search(query); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Search(%+v) = %d IDs, want %d", query, len(got), len(want))
+		}
+	}
+}
+
+// randomBox is a point, a small box, a large box or one wrapping the
+// antimeridian
+func randomBox(rng *rand.Rand) models.BoundingBox {
+	lat := rng.Float64()*170 - 85
+	lng := rng.Float64()*360 - 180
+	switch rng.Intn(4) {
+	case 0:
+		return PointBox(lat, lng)
+	case 1:
+		return box(lat, lng, min(lat+rng.Float64(), 90), min(lng+rng.Float64(), 180))
+	case 2:
+		return box(lat, lng, min(lat+rng.Float64()*40, 90), min(lng+rng.Float64()*90, 180))
+	}
+	west := 180 - rng.Float64()*20
+	return box(lat, west, min(lat+rng.Float64()*10, 90), -180+rng.Float64()*20)
+}
+
+func box(south, west, north, east float64) models.BoundingBox {
+	return models.BoundingBox{South: south, West: west, North: north, East: east}
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"sfaf-plotter/models"
+	"sfaf-plotter/spatial"
 
 	"github.com/google/uuid"
 )
@@ -19,6 +21,10 @@ type JSONStorage struct {
 	sfafs      map[uuid.UUID]*models.SFAF     // ✅ UUID keys match model
 	geometries map[uuid.UUID]*models.Geometry // ADD GEOMETRY STORAGE
 
+	// Lookups kept in step with the maps above
+	markerIndex        *spatial.Index                       // marker positions
+	sfafByMarker       map[uuid.UUID]uuid.UUID              // marker ID to SFAF ID
+	geometriesByMarker map[uuid.UUID]map[uuid.UUID]struct{} // marker ID to geometry IDs
 }
 
 type JSONData struct {
@@ -34,9 +40,14 @@ func NewJSONStorage(dataDir string) (*JSONStorage, error) {
 	}
 
 	storage := &JSONStorage{
-		dataDir: dataDir,
-		markers: make(map[uuid.UUID]*models.Marker), // ✅ UUID maps
-		sfafs:   make(map[uuid.UUID]*models.SFAF),   // ✅ UUID maps
+		dataDir:    dataDir,
+		markers:    make(map[uuid.UUID]*models.Marker),   // ✅ UUID maps
+		sfafs:      make(map[uuid.UUID]*models.SFAF),     // ✅ UUID maps
+		geometries: make(map[uuid.UUID]*models.Geometry), // ✅ UUID maps
+
+		markerIndex:        spatial.NewIndex(),
+		sfafByMarker:       make(map[uuid.UUID]uuid.UUID),
+		geometriesByMarker: make(map[uuid.UUID]map[uuid.UUID]struct{}),
 	}
 
 	if err := storage.loadFromFile(); err != nil {
@@ -60,18 +71,30 @@ func (js *JSONStorage) loadFromFile() error {
 
 	// Convert string maps back to UUID maps
 	js.markers = make(map[uuid.UUID]*models.Marker)
+	js.markerIndex.Clear()
 	for idStr, marker := range jsonData.Markers {
 		if id, err := uuid.Parse(idStr); err == nil {
 			js.markers[id] = marker
+			js.markerIndex.Insert(id.String(), spatial.PointBox(marker.Latitude, marker.Longitude))
 		}
 	}
 
 	js.sfafs = make(map[uuid.UUID]*models.SFAF)
 	for idStr, sfaf := range jsonData.SFAFs {
 		if id, err := uuid.Parse(idStr); err == nil {
+			sfaf.EnsureEntries() // records saved before entries existed
 			js.sfafs[id] = sfaf
 		}
 	}
+	js.rebuildSFAFLookup()
+
+	js.geometries = make(map[uuid.UUID]*models.Geometry)
+	for idStr, geometry := range jsonData.Geometries {
+		if id, err := uuid.Parse(idStr); err == nil {
+			js.geometries[id] = geometry
+		}
+	}
+	js.rebuildGeometryLookup()
 
 	return nil
 }
@@ -90,10 +113,16 @@ func (js *JSONStorage) saveToFile() error {
 		stringSFAFs[id.String()] = sfaf
 	}
 
+	stringGeometries := make(map[string]*models.Geometry)
+	for id, geometry := range js.geometries {
+		stringGeometries[id.String()] = geometry
+	}
+
 	jsonData := JSONData{
-		Markers: stringMarkers,
-		SFAFs:   stringSFAFs,
-		Version: "1.0",
+		Markers:    stringMarkers,
+		SFAFs:      stringSFAFs,
+		Version:    "1.0",
+		Geometries: stringGeometries,
 	}
 
 	data, err := json.MarshalIndent(jsonData, "", "  ")
@@ -114,6 +143,7 @@ func (js *JSONStorage) SaveMarker(marker *models.Marker) error {
 	defer js.mutex.Unlock()
 
 	js.markers[marker.ID] = marker
+	js.markerIndex.Insert(marker.ID.String(), spatial.PointBox(marker.Latitude, marker.Longitude))
 	return js.saveToFile()
 }
 
@@ -144,6 +174,54 @@ func (js *JSONStorage) GetAllMarkers() ([]*models.Marker, error) {
 	return markers, nil
 }
 
+// FindMarkers applies the attribute, Bounds, IDs and Limit parts of filter,
+// ordered like MarkerRepository.Find. Near and Within are left to
+// MarkerService. Bounds are looked up in the marker index.
+func (js *JSONStorage) FindMarkers(filter models.MarkerFilter) ([]*models.Marker, error) {
+	js.mutex.RLock()
+	defer js.mutex.RUnlock()
+
+	var candidates []*models.Marker
+	switch {
+	case filter.IDs != nil:
+		for _, id := range filter.IDs {
+			if marker, exists := js.markers[id]; exists {
+				candidates = append(candidates, marker)
+			}
+		}
+	case len(filter.Bounds) > 0:
+		for _, id := range js.markerIndex.Search(filter.Bounds[0]) {
+			candidates = append(candidates, js.markers[uuid.MustParse(id)])
+		}
+	default:
+		for _, marker := range js.markers {
+			candidates = append(candidates, marker)
+		}
+	}
+
+	var markers []*models.Marker
+	for _, marker := range candidates {
+		if filter.Matches(marker) {
+			markers = append(markers, marker)
+		}
+	}
+
+	switch filter.SortBy {
+	case "frequency":
+		sort.SliceStable(markers, func(i, j int) bool {
+			return markers[i].FrequencyBefore(markers[j])
+		})
+	default:
+		sort.SliceStable(markers, func(i, j int) bool {
+			return markers[i].CreatedAt.After(markers[j].CreatedAt)
+		})
+	}
+	if filter.Limit > 0 && len(markers) > filter.Limit {
+		markers = markers[:filter.Limit]
+	}
+	return markers, nil
+}
+
 func (js *JSONStorage) UpdateMarker(id string, marker *models.Marker) error {
 	// Convert string ID to UUID
 	markerUUID, err := uuid.Parse(id)
@@ -159,6 +237,7 @@ func (js *JSONStorage) UpdateMarker(id string, marker *models.Marker) error {
 	}
 
 	js.markers[markerUUID] = marker // ✅ UUID key assignment
+	js.markerIndex.Insert(markerUUID.String(), spatial.PointBox(marker.Latitude, marker.Longitude))
 	return js.saveToFile()
 }
 
@@ -171,6 +250,7 @@ func (js *JSONStorage) DeleteMarker(id string) error {
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
 	delete(js.markers, markerID)
+	js.markerIndex.Remove(markerID.String())
 	return js.saveToFile()
 }
 
@@ -178,10 +258,44 @@ func (js *JSONStorage) DeleteMarker(id string) error {
 func (js *JSONStorage) SaveSFAF(sfaf *models.SFAF) error {
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
+	sfaf.EnsureEntries()
+	js.unlinkSFAF(sfaf.ID)
 	js.sfafs[sfaf.ID] = sfaf // ✅ Now compatible: uuid.UUID to uuid.UUID
+	js.sfafByMarker[sfaf.MarkerID] = sfaf.ID
 	return js.saveToFile()
 }
 
+// SaveSFAFs stores a batch of SFAF records with a single file write. If the
+// write fails the in-memory maps are restored so nothing from the batch stays.
+func (js *JSONStorage) SaveSFAFs(sfafs []*models.SFAF) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	previous := make(map[uuid.UUID]*models.SFAF, len(sfafs))
+	for _, sfaf := range sfafs {
+		sfaf.EnsureEntries()
+		if existing, exists := js.sfafs[sfaf.ID]; exists {
+			previous[sfaf.ID] = existing
+		}
+		js.unlinkSFAF(sfaf.ID)
+		js.sfafs[sfaf.ID] = sfaf
+		js.sfafByMarker[sfaf.MarkerID] = sfaf.ID
+	}
+
+	if err := js.saveToFile(); err != nil {
+		for _, sfaf := range sfafs {
+			if existing, exists := previous[sfaf.ID]; exists {
+				js.sfafs[sfaf.ID] = existing
+			} else {
+				delete(js.sfafs, sfaf.ID)
+			}
+		}
+		js.rebuildSFAFLookup()
+		return err
+	}
+	return nil
+}
+
 func (js *JSONStorage) GetSFAF(id string) (*models.SFAF, error) {
 	// Convert string API input to UUID for internal map lookup
 	sfafID, err := uuid.Parse(id)
@@ -199,6 +313,17 @@ func (js *JSONStorage) GetSFAF(id string) (*models.SFAF, error) {
 	return sfaf, nil
 }
 
+func (js *JSONStorage) GetAllSFAFs() ([]*models.SFAF, error) {
+	js.mutex.RLock()
+	defer js.mutex.RUnlock()
+
+	sfafs := make([]*models.SFAF, 0, len(js.sfafs))
+	for _, sfaf := range js.sfafs {
+		sfafs = append(sfafs, sfaf)
+	}
+	return sfafs, nil
+}
+
 func (js *JSONStorage) GetSFAFByMarkerID(markerID string) (*models.SFAF, error) {
 	// Convert string input to UUID for comparison
 	markerUUID, err := uuid.Parse(markerID)
@@ -209,19 +334,31 @@ func (js *JSONStorage) GetSFAFByMarkerID(markerID string) (*models.SFAF, error)
 	js.mutex.RLock()
 	defer js.mutex.RUnlock()
 
-	fmt.Printf("🔍 Searching for SFAF with MarkerID: %s\n", markerID)
-	fmt.Printf("🔍 Total SFAF records in storage: %d\n", len(js.sfafs))
+	if sfafID, exists := js.sfafByMarker[markerUUID]; exists {
+		return js.sfafs[sfafID], nil
+	}
+	return nil, fmt.Errorf("SFAF not found for marker")
+}
 
-	for sfafID, sfaf := range js.sfafs {
-		fmt.Printf("🔍 SFAF ID: %s, MarkerID: %s\n", sfafID, sfaf.MarkerID)
-		if sfaf.MarkerID == markerUUID { // ✅ Now comparing UUID to UUID
-			fmt.Printf("✅ MATCH FOUND!\n")
-			return sfaf, nil
-		}
+// unlinkSFAF drops a stored record from sfafByMarker before it is replaced
+// or deleted. Callers hold the write lock.
+func (js *JSONStorage) unlinkSFAF(sfafID uuid.UUID) {
+	existing, exists := js.sfafs[sfafID]
+	if !exists {
+		return
+	}
+	if js.sfafByMarker[existing.MarkerID] == sfafID {
+		delete(js.sfafByMarker, existing.MarkerID)
 	}
+}
 
-	fmt.Printf("❌ No match found for MarkerID: %s\n", markerID)
-	return nil, fmt.Errorf("SFAF not found for marker")
+// rebuildSFAFLookup fills sfafByMarker from the SFAF map. Callers hold the
+// write lock.
+func (js *JSONStorage) rebuildSFAFLookup() {
+	js.sfafByMarker = make(map[uuid.UUID]uuid.UUID, len(js.sfafs))
+	for id, sfaf := range js.sfafs {
+		js.sfafByMarker[sfaf.MarkerID] = id
+	}
 }
 
 // Backup functionality for later SQLite migration
@@ -261,18 +398,129 @@ func (js *JSONStorage) DeleteSFAF(id string) error {
 
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
+	js.unlinkSFAF(sfafID)
 	delete(js.sfafs, sfafID)
 	return js.saveToFile()
 }
 
+func (js *JSONStorage) DeleteSFAFs(ids []string) error {
+	sfafIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		sfafID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid SFAF ID: %v", err)
+		}
+		sfafIDs = append(sfafIDs, sfafID)
+	}
+
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	for _, sfafID := range sfafIDs {
+		js.unlinkSFAF(sfafID)
+		delete(js.sfafs, sfafID)
+	}
+	return js.saveToFile()
+}
+
 // geometry operations for JSONStorage
 func (js *JSONStorage) SaveGeometry(geometry *models.Geometry) error {
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
+	js.unlinkGeometry(geometry.ID)
 	js.geometries[geometry.ID] = geometry
+	js.linkGeometry(geometry)
 	return js.saveToFile()
 }
 
+// SaveGeometries stores a batch of geometries with a single file write. If
+// the write fails the in-memory maps are restored so nothing from the batch
+// stays.
+func (js *JSONStorage) SaveGeometries(geometries []*models.Geometry) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	previous := make(map[uuid.UUID]*models.Geometry, len(geometries))
+	for _, geometry := range geometries {
+		if existing, exists := js.geometries[geometry.ID]; exists {
+			previous[geometry.ID] = existing
+		}
+		js.unlinkGeometry(geometry.ID)
+		js.geometries[geometry.ID] = geometry
+		js.linkGeometry(geometry)
+	}
+
+	if err := js.saveToFile(); err != nil {
+		for _, geometry := range geometries {
+			if existing, exists := previous[geometry.ID]; exists {
+				js.geometries[geometry.ID] = existing
+			} else {
+				delete(js.geometries, geometry.ID)
+			}
+		}
+		js.rebuildGeometryLookup()
+		return err
+	}
+	return nil
+}
+
+// GetGeometriesByMarkerID returns the geometries that belong to a marker:
+// the shape it is the center of and its field306 area, oldest first
+func (js *JSONStorage) GetGeometriesByMarkerID(markerID string) ([]*models.Geometry, error) {
+	markerUUID, err := uuid.Parse(markerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marker ID format: %v", err)
+	}
+
+	js.mutex.RLock()
+	defer js.mutex.RUnlock()
+
+	var geometries []*models.Geometry
+	for id := range js.geometriesByMarker[markerUUID] {
+		geometries = append(geometries, js.geometries[id])
+	}
+	sort.Slice(geometries, func(i, j int) bool {
+		return geometries[i].CreatedAt.Before(geometries[j].CreatedAt)
+	})
+	return geometries, nil
+}
+
+// linkGeometry adds a stored geometry to geometriesByMarker. Callers hold
+// the write lock.
+func (js *JSONStorage) linkGeometry(geometry *models.Geometry) {
+	if geometry.MarkerID == nil {
+		return
+	}
+	ids, exists := js.geometriesByMarker[*geometry.MarkerID]
+	if !exists {
+		ids = make(map[uuid.UUID]struct{})
+		js.geometriesByMarker[*geometry.MarkerID] = ids
+	}
+	ids[geometry.ID] = struct{}{}
+}
+
+// unlinkGeometry drops a stored geometry from geometriesByMarker before it
+// is replaced or deleted. Callers hold the write lock.
+func (js *JSONStorage) unlinkGeometry(geometryID uuid.UUID) {
+	existing, exists := js.geometries[geometryID]
+	if !exists || existing.MarkerID == nil {
+		return
+	}
+	ids := js.geometriesByMarker[*existing.MarkerID]
+	delete(ids, geometryID)
+	if len(ids) == 0 {
+		delete(js.geometriesByMarker, *existing.MarkerID)
+	}
+}
+
+// rebuildGeometryLookup fills geometriesByMarker from the geometry map.
+// Callers hold the write lock.
+func (js *JSONStorage) rebuildGeometryLookup() {
+	js.geometriesByMarker = make(map[uuid.UUID]map[uuid.UUID]struct{})
+	for _, geometry := range js.geometries {
+		js.linkGeometry(geometry)
+	}
+}
+
 func (js *JSONStorage) GetGeometry(id string) (*models.Geometry, error) {
 	geometryID, err := uuid.Parse(id)
 	if err != nil {
@@ -307,6 +555,26 @@ func (js *JSONStorage) DeleteGeometry(id string) error { // THE MISSING METHOD
 
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
+	js.unlinkGeometry(geometryID)
 	delete(js.geometries, geometryID)
 	return js.saveToFile()
 }
+
+func (js *JSONStorage) DeleteGeometries(ids []string) error {
+	geometryIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		geometryID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid geometry ID format: %v", err)
+		}
+		geometryIDs = append(geometryIDs, geometryID)
+	}
+
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+	for _, geometryID := range geometryIDs {
+		js.unlinkGeometry(geometryID)
+		delete(js.geometries, geometryID)
+	}
+	return js.saveToFile()
+}
@@ -0,0 +1,177 @@
+// json_test.go
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sfaf-plotter/models"
+
+	"github.com/google/uuid"
+)
+
+func newTestGeometry(markerID *uuid.UUID, created int64) *models.Geometry {
+	return &models.Geometry{
+		ID:        uuid.New(),
+		Type:      models.GeometryTypeCircle,
+		MarkerID:  markerID,
+		CreatedAt: time.Unix(created, 0),
+	}
+}
+
+// markerGeometryIDs lists the IDs GetGeometriesByMarkerID returns, in order
+func markerGeometryIDs(t *testing.T, js *JSONStorage, markerID uuid.UUID) []uuid.UUID {
+	t.Helper()
+	geometries, err := js.GetGeometriesByMarkerID(markerID.String())
+	if err != nil {
+		t.Fatalf("GetGeometriesByMarkerID: %v", err)
+	}
+	var ids []uuid.UUID
+	for _, geometry := range geometries {
+		ids = append(ids, geometry.ID)
+	}
+	return ids
+}
+
+func TestJSONStorageGeometriesByMarker(t *testing.T) {
+	js, err := NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	first, second := uuid.New(), uuid.New()
+	area := newTestGeometry(&first, 2)
+	center := newTestGeometry(&first, 1)
+	other := newTestGeometry(&second, 3)
+	drawn := newTestGeometry(nil, 4)
+
+	if err := js.SaveGeometry(area); err != nil {
+		t.Fatalf("SaveGeometry: %v", err)
+	}
+	if err := js.SaveGeometries([]*models.Geometry{center, other, drawn}); err != nil {
+		t.Fatalf("SaveGeometries: %v", err)
+	}
+	if got, want := markerGeometryIDs(t, js, first), []uuid.UUID{center.ID, area.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("geometries of the first marker %v, want %v (oldest first)", got, want)
+	}
+	if got := markerGeometryIDs(t, js, uuid.New()); len(got) != 0 {
+		t.Errorf("marker without geometries has %v", got)
+	}
+	if _, err := js.GetGeometriesByMarkerID("not-a-uuid"); err == nil {
+		t.Errorf("malformed marker ID accepted")
+	}
+
+	// Saving a geometry under another marker moves it
+	moved := *area
+	moved.MarkerID = &second
+	if err := js.SaveGeometries([]*models.Geometry{&moved}); err != nil {
+		t.Fatalf("SaveGeometries: %v", err)
+	}
+	if got, want := markerGeometryIDs(t, js, first), []uuid.UUID{center.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("geometries of the first marker after the move %v, want %v", got, want)
+	}
+	if got, want := markerGeometryIDs(t, js, second), []uuid.UUID{area.ID, other.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("geometries of the second marker after the move %v, want %v", got, want)
+	}
+
+	if err := js.DeleteGeometries([]string{center.ID.String(), other.ID.String()}); err != nil {
+		t.Fatalf("DeleteGeometries: %v", err)
+	}
+	if err := js.DeleteGeometry(area.ID.String()); err != nil {
+		t.Fatalf("DeleteGeometry: %v", err)
+	}
+	if len(js.geometriesByMarker) != 0 {
+		t.Errorf("lookup keeps %d markers after their geometries are deleted", len(js.geometriesByMarker))
+	}
+	remaining, _ := js.GetAllGeometries()
+	if len(remaining) != 1 || remaining[0].ID != drawn.ID {
+		t.Errorf("remaining geometries %v", remaining)
+	}
+}
+
+func TestJSONStorageDeleteGeometriesMalformedID(t *testing.T) {
+	js, err := NewJSONStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	markerID := uuid.New()
+	geometry := newTestGeometry(&markerID, 1)
+	if err := js.SaveGeometry(geometry); err != nil {
+		t.Fatalf("SaveGeometry: %v", err)
+	}
+
+	if err := js.DeleteGeometries([]string{geometry.ID.String(), "not-a-uuid"}); err == nil {
+		t.Fatalf("malformed geometry ID accepted")
+	}
+	if _, err := js.GetGeometry(geometry.ID.String()); err != nil {
+		t.Errorf("geometry deleted by a rejected batch: %v", err)
+	}
+	if got := markerGeometryIDs(t, js, markerID); len(got) != 1 {
+		t.Errorf("lookup changed by a rejected batch: %v", got)
+	}
+}
+
+func TestJSONStorageSaveGeometriesWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	js, err := NewJSONStorage(dir)
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	first, second := uuid.New(), uuid.New()
+	stored := newTestGeometry(&first, 1)
+	if err := js.SaveGeometry(stored); err != nil {
+		t.Fatalf("SaveGeometry: %v", err)
+	}
+
+	// A directory where the temporary file goes makes the write fail
+	if err := os.Mkdir(filepath.Join(dir, "data.json.tmp"), 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	moved := *stored
+	moved.MarkerID = &second
+	added := newTestGeometry(&second, 2)
+	if err := js.SaveGeometries([]*models.Geometry{&moved, added}); err == nil {
+		t.Fatalf("SaveGeometries succeeded without a data file")
+	}
+
+	if current, err := js.GetGeometry(stored.ID.String()); err != nil || current != stored {
+		t.Errorf("replaced geometry not restored: %v", err)
+	}
+	if _, err := js.GetGeometry(added.ID.String()); err == nil {
+		t.Errorf("new geometry kept after the failed write")
+	}
+	if got, want := markerGeometryIDs(t, js, first), []uuid.UUID{stored.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("geometries of the first marker %v, want %v", got, want)
+	}
+	if got := markerGeometryIDs(t, js, second); len(got) != 0 {
+		t.Errorf("geometries of the second marker %v, want none", got)
+	}
+}
+
+func TestJSONStorageGeometryLookupOnLoad(t *testing.T) {
+	dir := t.TempDir()
+	js, err := NewJSONStorage(dir)
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	markerID := uuid.New()
+	geometries := []*models.Geometry{newTestGeometry(&markerID, 1), newTestGeometry(&markerID, 2), newTestGeometry(nil, 3)}
+	if err := js.SaveGeometries(geometries); err != nil {
+		t.Fatalf("SaveGeometries: %v", err)
+	}
+
+	reloaded, err := NewJSONStorage(dir)
+	if err != nil {
+		t.Fatalf("NewJSONStorage: %v", err)
+	}
+	got := markerGeometryIDs(t, reloaded, markerID)
+	want := []uuid.UUID{geometries[0].ID, geometries[1].ID}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("geometries of the marker after reloading %v, want %v", got, want)
+	}
+	if len(reloaded.geometriesByMarker) != 1 {
+		t.Errorf("lookup has %d markers, want 1", len(reloaded.geometriesByMarker))
+	}
+}
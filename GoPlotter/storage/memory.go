@@ -32,10 +32,22 @@ func (ms *MemoryStorage) SaveSFAF(sfaf *models.SFAF) error {
 	ms.mutex.Lock()
 	defer ms.mutex.Unlock()
 
+	sfaf.EnsureEntries()
 	ms.sfafs[sfaf.ID] = sfaf
 	return nil
 }
 
+func (ms *MemoryStorage) SaveSFAFs(sfafs []*models.SFAF) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, sfaf := range sfafs {
+		sfaf.EnsureEntries()
+		ms.sfafs[sfaf.ID] = sfaf
+	}
+	return nil
+}
+
 func (ms *MemoryStorage) GetSFAF(id string) (*models.SFAF, error) {
 	sfafID, err := uuid.Parse(id)
 	if err != nil {
@@ -51,6 +63,17 @@ func (ms *MemoryStorage) GetSFAF(id string) (*models.SFAF, error) {
 	return nil, fmt.Errorf("SFAF not found")
 }
 
+func (ms *MemoryStorage) GetAllSFAFs() ([]*models.SFAF, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	sfafs := make([]*models.SFAF, 0, len(ms.sfafs))
+	for _, sfaf := range ms.sfafs {
+		sfafs = append(sfafs, sfaf)
+	}
+	return sfafs, nil
+}
+
 func (ms *MemoryStorage) GetSFAFByMarkerID(markerID string) (*models.SFAF, error) {
 	// Convert string input to UUID for comparison
 	markerUUID, err := uuid.Parse(markerID)
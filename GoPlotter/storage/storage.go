@@ -7,6 +7,7 @@ type Storage interface {
 	SaveMarker(marker *models.Marker) error
 	GetMarker(id string) (*models.Marker, error)
 	GetAllMarkers() ([]*models.Marker, error)
+	FindMarkers(filter models.MarkerFilter) ([]*models.Marker, error)
 	UpdateMarker(id string, marker *models.Marker) error
 	DeleteMarker(id string) error
 
@@ -14,13 +15,19 @@ type Storage interface {
 	SaveSFAF(sfaf *models.SFAF) error
 	GetSFAF(id string) (*models.SFAF, error)
 	GetSFAFByMarkerID(markerID string) (*models.SFAF, error)
+	GetAllSFAFs() ([]*models.SFAF, error)
 	DeleteSFAF(id string) error
+	SaveSFAFs(sfafs []*models.SFAF) error // all-or-nothing batch
+	DeleteSFAFs(ids []string) error
 
 	// Geometry operations (for GeometryService)
 	SaveGeometry(geometry *models.Geometry) error
 	GetGeometry(id string) (*models.Geometry, error)
+	GetGeometriesByMarkerID(markerID string) ([]*models.Geometry, error)
 	GetAllGeometries() ([]*models.Geometry, error)
 	DeleteGeometry(id string) error
+	SaveGeometries(geometries []*models.Geometry) error // all-or-nothing batch
+	DeleteGeometries(ids []string) error
 
 	// Export functionality
 	ExportBackup(backupPath string) error
@@ -673,6 +673,19 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 });
 
+document.addEventListener('DOMContentLoaded', () => {
+    // Connect Export All button (Overview tab) to the bulk SFAF export
+    const exportAllBtn = document.getElementById('exportAllData');
+    if (exportAllBtn) {
+        exportAllBtn.addEventListener('click', (e) => {
+            e.preventDefault();
+            console.log('📤 Export All button clicked');
+            window.location.href = '/api/sfaf/export?format=zip';
+        });
+        console.log('✅ Export All button connected');
+    }
+});
+
 document.addEventListener('DOMContentLoaded', () => {
     // Connect Object tab delete button
     const objectDeleteBtn = document.getElementById('deleteObjectBtn');
@@ -934,4 +947,4 @@ function addCommentsEntryManual() {
     addValidationListeners(newEntry);
     console.log(`✅ Added emission characteristics entry #${entryCount} (MCEB Pub 7 compliant)`);
     showNotification(`Emission characteristics #${entryCount} added`, 'success')
-};
\ No newline at end of file
+};
\ No newline at end of file
@@ -855,6 +855,15 @@ class DatabaseViewer {
                         <label>Compact (Military):</label>
                         <span class="coord-value">${data.coordinates.compact}</span>
                     </div>
+                    ${data.coordinates.mgrs ? `
+                    <div class="coord-item">
+                        <label>MGRS:</label>
+                        <span class="coord-value">${data.coordinates.mgrs}</span>
+                    </div>
+                    <div class="coord-item">
+                        <label>UTM:</label>
+                        <span class="coord-value">${data.coordinates.utm}</span>
+                    </div>` : ''}
                 </div>
             </div>
             
@@ -939,7 +948,10 @@ class DatabaseViewer {
 
     // Render individual SFAF field input (Source: services.txt field types)
     renderSFAFFieldInput(fieldId, value, fieldType, definition) {
+        const maxLength = definition && definition.max_length ? `maxlength="${definition.max_length}"` : '';
+
         switch (fieldType) {
+            case 'code':
             case 'select':
                 const options = definition.options || [];
                 return `
@@ -955,8 +967,10 @@ class DatabaseViewer {
                 return `<textarea name="sfaf_${fieldId}" class="sfaf-field-input" rows="3">${value || ''}</textarea>`;
 
             case 'date':
-                return `<input type="date" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}">`;
+                // SFAF dates are YYYYMMDD, not the browser's YYYY-MM-DD
+                return `<input type="text" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}" placeholder="YYYYMMDD" pattern="\\d{8}" ${maxLength}>`;
 
+            case 'numeric':
             case 'number':
                 return `<input type="number" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}">`;
 
@@ -964,7 +978,7 @@ class DatabaseViewer {
                 return `<input type="email" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}">`;
 
             default:
-                return `<input type="text" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}">`;
+                return `<input type="text" name="sfaf_${fieldId}" class="sfaf-field-input" value="${value || ''}" ${maxLength}>`;
         }
     }
 
@@ -35,6 +35,9 @@ map.addLayer(drawnItems);
 
 // Storage for markers
 const markers = new Map();
+
+// Drawn geometries by ID; each layer carries geometryId and centerMarkerId
+const geometryLayers = new Map();
 let currentSelectedMarker = null;
 
 // Marker icons (same as your original)
@@ -85,7 +88,7 @@ map.on('mousemove', async (e) => {
 
             cursorTooltip
                 .setLatLng(e.latlng)
-                .setContent(`<b>Cursor</b><br>DecDeg: ${coords.decimal}<br>DMS: ${coords.dms}`);
+                .setContent(`<b>Cursor</b><br>DecDeg: ${coords.decimal}<br>DMS: ${coords.dms}${coords.mgrs ? `<br>MGRS: ${coords.mgrs}` : ''}`);
             if (!cursorTooltip._map) {
                 cursorTooltip.addTo(map);
             }
@@ -182,6 +185,8 @@ function createMarkerOnMap(markerData) {
                 });
                 if (response.ok) {
                     console.log('✅ Marker position saved to server');
+                    const markerResp = await response.json();
+                    showCoordinationAreas(markerResp.coordination);
                 }
             } catch (error) {
                 console.error('❌ Failed to update marker coordinates:', error);
@@ -322,6 +327,7 @@ map.on(L.Draw.Event.CREATED, async function (event) {
                     // Don't add the drawn layer to drawnItems since we're replacing it
                     map.removeLayer(layer);
                     createMarkerOnMap(markerResp.marker); // This will now add to drawnItems
+                    showCoordinationAreas(markerResp.coordination);
                 }
                 break;
 
@@ -341,11 +347,11 @@ map.on(L.Draw.Event.CREATED, async function (event) {
 
                 if (response.ok) {
                     const geometryResp = await response.json();
-                    drawnItems.addLayer(layer);
+                    registerGeometryLayer(layer, geometryResp.geometry);
 
-                    // Add center marker if geometry service returns one
-                    if (geometryResp.geometry && geometryResp.geometry.center_marker) {
-                        createMarkerOnMap(geometryResp.geometry.center_marker);
+                    // Add the center marker the geometry service created
+                    if (geometryResp.center_marker) {
+                        createMarkerOnMap(geometryResp.center_marker);
                     }
                 }
                 break;
@@ -364,10 +370,10 @@ map.on(L.Draw.Event.CREATED, async function (event) {
 
                 if (response.ok) {
                     const geometryResp = await response.json();
-                    drawnItems.addLayer(layer);
+                    registerGeometryLayer(layer, geometryResp.geometry);
 
-                    if (geometryResp.geometry && geometryResp.geometry.center_marker) {
-                        createMarkerOnMap(geometryResp.geometry.center_marker);
+                    if (geometryResp.center_marker) {
+                        createMarkerOnMap(geometryResp.center_marker);
                     }
                 }
                 break;
@@ -391,10 +397,10 @@ map.on(L.Draw.Event.CREATED, async function (event) {
 
                 if (response.ok) {
                     const geometryResp = await response.json();
-                    drawnItems.addLayer(layer);
+                    registerGeometryLayer(layer, geometryResp.geometry);
 
-                    if (geometryResp.geometry && geometryResp.geometry.center_marker) {
-                        createMarkerOnMap(geometryResp.geometry.center_marker);
+                    if (geometryResp.center_marker) {
+                        createMarkerOnMap(geometryResp.center_marker);
                     }
                 }
                 break;
@@ -406,6 +412,135 @@ map.on(L.Draw.Event.CREATED, async function (event) {
     }
 });
 
+// Send edited shapes to the server; the center marker follows the shape
+map.on(L.Draw.Event.EDITED, async function (event) {
+    const updates = [];
+    event.layers.eachLayer(layer => {
+        if (layer.geometryId) {
+            updates.push(saveGeometryLayer(layer));
+        }
+    });
+    await Promise.all(updates);
+});
+
+// Deleting a shape also deletes its center marker on the server
+map.on(L.Draw.Event.DELETED, async function (event) {
+    const deletions = [];
+    event.layers.eachLayer(layer => {
+        if (layer.geometryId) {
+            deletions.push(deleteGeometryLayer(layer));
+        }
+    });
+    await Promise.all(deletions);
+});
+
+// Load the shapes drawn in earlier sessions. Field306 and coordination
+// areas are generated, so they are left to their own displays.
+async function loadExistingGeometries() {
+    try {
+        const response = await fetch('/api/geometry');
+        const data = await response.json();
+
+        (data.geometries || [])
+            .filter(geometry => !geometry.source)
+            .forEach(geometry => {
+                const layer = geometryToLayer(geometry);
+                if (layer) {
+                    registerGeometryLayer(layer, geometry);
+                }
+            });
+    } catch (error) {
+        console.error('Failed to load existing geometries:', error);
+    }
+}
+
+function geometryToLayer(geometry) {
+    const style = { color: geometry.color };
+    switch (geometry.type) {
+        case 'circle':
+            return geometry.circle_properties &&
+                L.circle([geometry.lat, geometry.lng], { ...style, radius: geometry.circle_properties.radius });
+        case 'polygon':
+            return geometry.polygon_properties &&
+                L.polygon(geometry.polygon_properties.points.map(point => [point.lat, point.lng]), style);
+        case 'rectangle':
+            return geometry.rectangle_properties &&
+                L.rectangle(geometry.rectangle_properties.bounds.map(point => [point.lat, point.lng]), style);
+    }
+    return null;
+}
+
+function registerGeometryLayer(layer, geometry) {
+    layer.geometryId = geometry.id;
+    layer.centerMarkerId = geometry.marker_id;
+    drawnItems.addLayer(layer);
+    geometryLayers.set(geometry.id, layer);
+}
+
+async function saveGeometryLayer(layer) {
+    let body;
+    if (layer instanceof L.Circle) {
+        body = { radius: layer.getRadius() / 1000, unit: 'km' };
+    } else if (layer instanceof L.Rectangle) {
+        const bounds = layer.getBounds();
+        body = {
+            south_west: { lat: bounds.getSouth(), lng: bounds.getWest() },
+            north_east: { lat: bounds.getNorth(), lng: bounds.getEast() }
+        };
+    } else {
+        body = { points: layer.getLatLngs()[0].map(point => ({ lat: point.lat, lng: point.lng })) };
+    }
+
+    try {
+        const response = await fetch(`/api/geometry/${layer.geometryId}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}: ${response.statusText}`);
+        }
+
+        const geometryResp = await response.json();
+        const centerMarker = markers.get(layer.centerMarkerId);
+        if (centerMarker && geometryResp.center_marker) {
+            centerMarker.setLatLng([geometryResp.center_marker.lat, geometryResp.center_marker.lng]);
+        }
+    } catch (error) {
+        console.error(`❌ Failed to save geometry ${layer.geometryId}:`, error);
+    }
+}
+
+async function deleteGeometryLayer(layer) {
+    try {
+        const response = await fetch(`/api/geometry/${layer.geometryId}`, { method: 'DELETE' });
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}: ${response.statusText}`);
+        }
+        geometryLayers.delete(layer.geometryId);
+        removeMarkerFromMap(layer.centerMarkerId);
+    } catch (error) {
+        console.error(`❌ Failed to delete geometry ${layer.geometryId}:`, error);
+    }
+}
+
+// Take a marker and any shape it is the center of off the map
+function removeMarkerFromMap(markerId) {
+    const marker = markers.get(markerId);
+    if (marker) {
+        drawnItems.removeLayer(marker);
+        map.removeLayer(marker);
+        markers.delete(markerId);
+    }
+
+    geometryLayers.forEach((layer, geometryId) => {
+        if (layer.centerMarkerId === markerId) {
+            drawnItems.removeLayer(layer);
+            geometryLayers.delete(geometryId);
+        }
+    });
+}
+
 // Helper functions for sidebar integration
 function openPersistentSidebar() {
     const sidebar = document.getElementById('persistentSidebar');
@@ -680,6 +815,25 @@ function populateExistingSFAFForm(data) {
     }
 }
 
+// Tell the user which IRAC coordination areas a created or moved marker lies in
+function showCoordinationAreas(coordination) {
+    if (!Array.isArray(coordination) || coordination.length === 0) return;
+
+    const missing = coordination.filter(match => !match.present);
+    if (missing.length === 0) return;
+
+    const required = missing.filter(match => match.required);
+    const suggested = missing.filter(match => !match.required);
+    const describe = match => `${match.note} (${match.name || match.area})`;
+
+    if (required.length > 0) {
+        showNotification(`⚠️ Coordination required: ${required.map(describe).join(', ')}`, 'warning');
+    }
+    if (suggested.length > 0) {
+        showNotification(`ℹ️ Coordination suggested: ${suggested.map(describe).join(', ')}`, 'info');
+    }
+}
+
 // Show MCEB Pub 7 compliance notification
 function showComplianceNotification(successCount, totalSkipped) {
     const notification = document.createElement('div');
@@ -854,11 +1008,17 @@ function wireUpActionButtons() {
 async function validateSFAFWithGo() {
     const formData = collectSFAFFormData();
 
+    // The server keys repeated fields "field500/02"; the form uses "field500_2"
+    const fields = {};
+    Object.entries(formData).forEach(([key, value]) => {
+        fields[window.toSFAFRecordKey ? window.toSFAFRecordKey(key) : key] = value;
+    });
+
     try {
         const response = await fetch('/api/sfaf/validate', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ fields: formData })
+            body: JSON.stringify({ fields: fields })
         });
 
         const result = await response.json();
@@ -950,12 +1110,8 @@ async function deleteSFAFWithGo() {
             });
 
             if (response.ok) {
-                // Remove marker from map
-                const marker = markers.get(window.currentSFAFMarker.id);
-                if (marker) {
-                    map.removeLayer(marker);
-                    markers.delete(window.currentSFAFMarker.id);
-                }
+                // Remove marker, and the shape it is the center of, from the map
+                removeMarkerFromMap(window.currentSFAFMarker.id);
 
                 // Remove authorization circle
                 removeAuthorizationCircle();
@@ -1076,6 +1232,25 @@ function applySFAFValidationResults(validation) {
             }
         });
     }
+
+    // Errors on repeated occurrences ("field500/02") have no field definition of their own
+    Object.entries(validation.errors || {}).forEach(([fieldId, message]) => {
+        if (validation.fields && validation.fields[fieldId]) return;
+
+        const field = window.findSFAFFormField ? window.findSFAFFormField(fieldId) : findFieldByAnyMeans(fieldId);
+        if (field) {
+            field.style.borderColor = '#f44336';
+            field.classList.add('validation-error');
+
+            const errorMsg = document.createElement('div');
+            errorMsg.className = 'validation-message';
+            errorMsg.style.cssText = 'color: #f44336; font-size: 12px; margin-top: 2px;';
+            errorMsg.textContent = message;
+            if (field.parentNode) {
+                field.parentNode.appendChild(errorMsg);
+            }
+        }
+    });
 }
 
 function manageObjectTabVisibility(hasSelectedMarker = false) {
@@ -1157,6 +1332,10 @@ async function clearAllMarkers() {
                 console.log('✅ DrawnItems layers cleared');
             }
 
+            // Drawn shapes are deleted with their center markers
+            geometryLayers.forEach(layer => drawnItems.removeLayer(layer));
+            geometryLayers.clear();
+
             // 3. Remove ALL marker layers directly from map
             map.eachLayer(function (layer) {
                 // Remove all marker instances (manual and imported)
@@ -1272,6 +1451,7 @@ async function openSidebar(markerId) {
 // Load existing markers when page loads
 document.addEventListener('DOMContentLoaded', function () {
     loadExistingMarkers();
+    loadExistingGeometries();
 });
 
 // Make functions globally available